@@ -1,9 +1,12 @@
 package test
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
 
@@ -63,16 +66,25 @@ func (i *InputPart) UnmarshalYAML(value *yaml.Node) error {
 
 // Case contains a definition of a single Benthos config test case.
 type Case struct {
-	Name             string               `yaml:"name"`
-	Environment      map[string]string    `yaml:"environment"`
-	TargetProcessors string               `yaml:"target_processors"`
-	TargetMapping    string               `yaml:"target_mapping"`
-	Mocks            map[string]yaml.Node `yaml:"mocks"`
-	InputBatch       []InputPart          `yaml:"input_batch"`
-	InputBatches     [][]InputPart        `yaml:"input_batches"`
-	OutputBatches    [][]ConditionsMap    `yaml:"output_batches"`
+	Name               string                  `yaml:"name"`
+	Environment        map[string]string       `yaml:"environment"`
+	TargetProcessors   string                  `yaml:"target_processors"`
+	TargetMapping      string                  `yaml:"target_mapping"`
+	TargetStream       bool                    `yaml:"target_stream"`
+	Mocks              map[string]yaml.Node    `yaml:"mocks"`
+	CacheResources     []CacheFixture          `yaml:"cache_resources"`
+	RateLimitResources []RateLimitFixture      `yaml:"rate_limit_resources"`
+	InputBatch         []InputPart             `yaml:"input_batch"`
+	InputBatches       [][]InputPart           `yaml:"input_batches"`
+	OutputBatches      [][]ConditionsMap       `yaml:"output_batches"`
+	OutputSnapshot     string                  `yaml:"output_snapshot"`
+	StreamOutputs      map[string]StreamOutput `yaml:"stream_outputs"`
+	RejectedBatches    [][]ConditionsMap       `yaml:"rejected_batches"`
+	OutputMetrics      []MetricCondition       `yaml:"output_metrics"`
+	OutputLogs         []LogCondition          `yaml:"output_logs"`
 
-	line int
+	line            int
+	updateSnapshots bool
 }
 
 // AtLine returns a test case at a given line.
@@ -81,17 +93,31 @@ func (c Case) AtLine(l int) Case {
 	return c
 }
 
+// WithSnapshotUpdates returns a test case that writes its output batches to its
+// output snapshot file rather than checking them against it.
+func (c Case) WithSnapshotUpdates() Case {
+	c.updateSnapshots = true
+	return c
+}
+
 // NewCase returns a default test case.
 func NewCase() Case {
 	return Case{
-		Name:             "Example test case",
-		Environment:      map[string]string{},
-		TargetProcessors: "/pipeline/processors",
-		TargetMapping:    "",
-		Mocks:            map[string]yaml.Node{},
-		InputBatch:       []InputPart{},
-		InputBatches:     [][]InputPart{},
-		OutputBatches:    [][]ConditionsMap{},
+		Name:               "Example test case",
+		Environment:        map[string]string{},
+		TargetProcessors:   "/pipeline/processors",
+		TargetMapping:      "",
+		Mocks:              map[string]yaml.Node{},
+		CacheResources:     []CacheFixture{},
+		RateLimitResources: []RateLimitFixture{},
+		InputBatch:         []InputPart{},
+		InputBatches:       [][]InputPart{},
+		OutputBatches:      [][]ConditionsMap{},
+		OutputSnapshot:     "",
+		StreamOutputs:      map[string]StreamOutput{},
+		RejectedBatches:    [][]ConditionsMap{},
+		OutputMetrics:      []MetricCondition{},
+		OutputLogs:         []LogCondition{},
 	}
 }
 
@@ -116,6 +142,12 @@ type CaseFailure struct {
 	Name     string
 	TestLine int
 	Reason   string
+
+	// Condition is the type of the condition that failed, and Diff describes
+	// the difference between the expected and actual values. Both are empty
+	// when the failure was not caused by a condition.
+	Condition string
+	Diff      string
 }
 
 // String returns a string representation of the case failure.
@@ -130,42 +162,190 @@ type ProcProvider interface {
 	ProvideBloblang(path string) ([]iprocessor.V1, error)
 }
 
+// ObservedProcProvider is an optional extension of ProcProvider that is able to
+// replace resources with fixtures and capture the metrics and logs emitted by
+// the provided processors.
+type ObservedProcProvider interface {
+	ProvideObserved(jsonPtr string, environment map[string]string, mocks map[string]yaml.Node, fixtures Fixtures) ([]iprocessor.V1, *Observed, error)
+}
+
+func (c *Case) fixtures() Fixtures {
+	return Fixtures{
+		Caches:     c.CacheResources,
+		RateLimits: c.RateLimitResources,
+	}
+}
+
+func (c *Case) isObserved() bool {
+	return !c.fixtures().Empty() || len(c.OutputMetrics) > 0 || len(c.OutputLogs) > 0
+}
+
+// failureReporter records a failed test case, where the cause of the failure
+// is optional.
+type failureReporter func(reason string, cause error)
+
+// StreamProvider is an optional extension of ProcProvider that is able to
+// construct the entire stream of a Benthos config with its input and target
+// outputs replaced by in-memory stand-ins.
+type StreamProvider interface {
+	ProvideStream(environment map[string]string, mocks map[string]yaml.Node, fixtures Fixtures, outputs []string) (*TestStream, error)
+}
+
+// streamTestTimeout is the maximum period of time to wait for each input batch
+// of a stream test to be acknowledged, and for the stream to shut down.
+const streamTestTimeout = time.Second * 10
+
 // ExecuteFrom executes a test case from the perspective of a given directory,
 // which is used for obtaining relative condition file imports.
 func (c *Case) ExecuteFrom(dir string, provider ProcProvider) (failures []CaseFailure, err error) {
+	reportFailure := func(reason string, cause error) {
+		failure := CaseFailure{
+			Name:     c.Name,
+			TestLine: c.line,
+			Reason:   reason,
+		}
+		var condErr *ConditionError
+		if errors.As(cause, &condErr) {
+			failure.Condition = condErr.Type
+			failure.Diff = condErr.Err.Error()
+		}
+		failures = append(failures, failure)
+	}
+
+	if c.TargetStream {
+		err = c.executeStream(dir, provider, reportFailure)
+		return
+	}
+	if len(c.StreamOutputs) > 0 || len(c.RejectedBatches) > 0 {
+		return nil, errors.New("stream_outputs and rejected_batches can only be used with target_stream")
+	}
+
 	var procSet []iprocessor.V1
+	var observed *Observed
 	if c.TargetMapping != "" {
+		if c.isObserved() {
+			return nil, errors.New("resource fixtures, output_metrics and output_logs cannot be used with target_mapping")
+		}
 		if procSet, err = provider.ProvideBloblang(c.TargetMapping); err != nil {
 			return nil, fmt.Errorf("failed to initialise Bloblang mapping '%v': %v", c.TargetMapping, err)
 		}
+	} else if c.isObserved() {
+		oProvider, ok := provider.(ObservedProcProvider)
+		if !ok {
+			return nil, errors.New("resource fixtures, output_metrics and output_logs are not supported by this processors provider")
+		}
+		if procSet, observed, err = oProvider.ProvideObserved(c.TargetProcessors, c.Environment, c.Mocks, c.fixtures()); err != nil {
+			return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
+		}
+		defer func() {
+			_ = observed.Close(streamTestTimeout)
+		}()
 	} else {
 		if procSet, err = provider.Provide(c.TargetProcessors, c.Environment, c.Mocks); err != nil {
 			return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
 		}
 	}
 
-	reportFailure := func(reason string) {
-		failures = append(failures, CaseFailure{
-			Name:     c.Name,
-			TestLine: c.line,
-			Reason:   reason,
+	var inputMsg []*message.Batch
+	if inputMsg, err = c.inputBatches(dir); err != nil {
+		return
+	}
+
+	outputBatches, result := iprocessor.ExecuteAll(procSet, inputMsg...)
+	if result != nil {
+		reportFailure(fmt.Sprintf("processors resulted in error: %v", result), nil)
+	}
+
+	// A snapshot covers every output batch, in which case output_batches are
+	// only checked when they're specified.
+	if c.OutputSnapshot == "" || len(c.OutputBatches) > 0 {
+		checkOutputBatches(dir, c.OutputBatches, outputBatches, reportFailure)
+	}
+	if c.OutputSnapshot != "" {
+		if err = c.checkOutputSnapshot(dir, outputBatches, reportFailure); err != nil {
+			return
+		}
+	}
+	if observed != nil {
+		c.checkObserved(dir, observed, reportFailure)
+	}
+	return
+}
+
+func (c *Case) executeStream(dir string, provider ProcProvider, reportFailure failureReporter) error {
+	if c.TargetMapping != "" {
+		return errors.New("target_stream cannot be used with target_mapping")
+	}
+	if c.OutputSnapshot != "" {
+		return errors.New("output_snapshot cannot be used with target_stream")
+	}
+
+	streamOutputs := c.StreamOutputs
+	if len(streamOutputs) == 0 {
+		streamOutputs = map[string]StreamOutput{
+			"/output": {OutputBatches: c.OutputBatches},
+		}
+	} else if len(c.OutputBatches) > 0 {
+		return errors.New("output_batches cannot be used alongside stream_outputs, specify the output_batches of each stream output instead")
+	}
+
+	sProvider, ok := provider.(StreamProvider)
+	if !ok {
+		return errors.New("target_stream is not supported by this processors provider")
+	}
+
+	targets := make([]string, 0, len(streamOutputs))
+	failAttempts := make(map[string]int, len(streamOutputs))
+	for k, v := range streamOutputs {
+		targets = append(targets, k)
+		failAttempts[k] = v.FailAttempts
+	}
+	sort.Strings(targets)
+
+	inputMsg, err := c.inputBatches(dir)
+	if err != nil {
+		return err
+	}
+
+	strm, err := sProvider.ProvideStream(c.Environment, c.Mocks, c.fixtures(), targets)
+	if err != nil {
+		return fmt.Errorf("failed to initialise stream: %v", err)
+	}
+	defer func() {
+		_ = strm.Observed().Close(streamTestTimeout)
+	}()
+
+	res, err := strm.Run(inputMsg, failAttempts, streamTestTimeout)
+	if err != nil {
+		reportFailure(fmt.Sprintf("stream resulted in error: %v", err), nil)
+	}
+
+	for _, target := range targets {
+		target := target
+		checkOutputBatches(dir, streamOutputs[target].OutputBatches, res.Outputs[target], func(reason string, cause error) {
+			reportFailure(fmt.Sprintf("output '%v': %v", target, reason), cause)
 		})
 	}
+	checkOutputBatches(dir, c.RejectedBatches, res.Rejected, func(reason string, cause error) {
+		reportFailure(fmt.Sprintf("rejected batches: %v", reason), cause)
+	})
+	c.checkObserved(dir, strm.Observed(), reportFailure)
+	return nil
+}
 
+func (c *Case) inputBatches(dir string) ([]*message.Batch, error) {
 	// append old batch to new batch array.
 	if len(c.InputBatch) > 0 {
 		c.InputBatches = append(c.InputBatches, c.InputBatch)
 	}
 
 	var inputMsg []*message.Batch
-
 	for _, inputBatch := range c.InputBatches {
 		parts := make([]*message.Part, len(inputBatch))
 		for i, v := range inputBatch {
-			var content string
-			if content, err = v.getContent(dir); err != nil {
-				err = fmt.Errorf("failed to create mock input %v: %w", i, err)
-				return
+			content, err := v.getContent(dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create mock input %v: %w", i, err)
 			}
 			part := message.NewPart([]byte(content))
 			for k, v := range v.Metadata {
@@ -178,39 +358,82 @@ func (c *Case) ExecuteFrom(dir string, provider ProcProvider) (failures []CaseFa
 		currentBatch.SetAll(parts)
 		inputMsg = append(inputMsg, currentBatch)
 	}
+	return inputMsg, nil
+}
 
-	outputBatches, result := iprocessor.ExecuteAll(procSet, inputMsg...)
-	if result != nil {
-		reportFailure(fmt.Sprintf("processors resulted in error: %v", result))
-	}
-
-	if lExp, lAct := len(c.OutputBatches), len(outputBatches); lAct < lExp {
-		reportFailure(fmt.Sprintf("wrong batch count, expected %v, got %v", lExp, lAct))
+func checkOutputBatches(dir string, expected [][]ConditionsMap, actual []*message.Batch, reportFailure failureReporter) {
+	if lExp, lAct := len(expected), len(actual); lAct < lExp {
+		reportFailure(fmt.Sprintf("wrong batch count, expected %v, got %v", lExp, lAct), nil)
 	}
 
-	for i, v := range outputBatches {
-		if len(c.OutputBatches) <= i {
-			reportFailure(fmt.Sprintf("unexpected batch: %s", message.GetAllBytes(v)))
+	for i, v := range actual {
+		if len(expected) <= i {
+			reportFailure(fmt.Sprintf("unexpected batch: %s", message.GetAllBytes(v)), nil)
 			continue
 		}
-		expectedBatch := c.OutputBatches[i]
+		expectedBatch := expected[i]
 		if lExp, lAct := len(expectedBatch), v.Len(); lExp != lAct {
-			reportFailure(fmt.Sprintf("mismatch of output batch %v message counts, expected %v, got %v", i, lExp, lAct))
+			reportFailure(fmt.Sprintf("mismatch of output batch %v message counts, expected %v, got %v", i, lExp, lAct), nil)
 		}
 		_ = v.Iter(func(i2 int, part *message.Part) error {
 			if len(expectedBatch) <= i2 {
-				reportFailure(fmt.Sprintf("unexpected message from batch %v: %s", i, part.Get()))
+				reportFailure(fmt.Sprintf("unexpected message from batch %v: %s", i, part.Get()), nil)
 				return nil
 			}
 			condErrs := expectedBatch[i2].CheckAll(dir, part)
 			for _, condErr := range condErrs {
-				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, condErr))
+				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, condErr), condErr)
 			}
 			if procErr := part.ErrorGet(); procErr != nil && len(condErrs) > 0 {
-				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, red(procErr)))
+				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, red(procErr)), nil)
 			}
 			return nil
 		})
 	}
-	return
+}
+
+func (c *Case) checkOutputSnapshot(dir string, outputBatches []*message.Batch, reportFailure failureReporter) error {
+	snapshotPath := c.OutputSnapshot
+	if !filepath.IsAbs(snapshotPath) {
+		snapshotPath = filepath.Join(dir, snapshotPath)
+	}
+
+	if c.updateSnapshots {
+		if err := writeSnapshot(snapshotPath, outputBatches); err != nil {
+			return fmt.Errorf("failed to write snapshot file '%v': %v", c.OutputSnapshot, err)
+		}
+		return nil
+	}
+
+	expected, err := readSnapshot(snapshotPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			reportFailure(fmt.Sprintf("output_snapshot: snapshot file '%v' does not exist, run the tests with --update-snapshots in order to create it", c.OutputSnapshot), nil)
+			return nil
+		}
+		return err
+	}
+	for _, snapErr := range checkSnapshot(expected, newSnapshot(outputBatches)) {
+		condErr := &ConditionError{Type: "output_snapshot", Err: snapErr}
+		reportFailure(condErr.Error(), condErr)
+	}
+	return nil
+}
+
+func (c *Case) checkObserved(dir string, observed *Observed, reportFailure failureReporter) {
+	metricValues := observed.Metrics()
+	for i, m := range c.OutputMetrics {
+		if mErr := m.Check(metricValues); mErr != nil {
+			reportFailure(fmt.Sprintf("output_metrics %v: %v", i, mErr), &ConditionError{Type: "output_metrics", Err: mErr})
+		}
+	}
+	logEntries := observed.Logs()
+	for i, l := range c.OutputLogs {
+		if lErr := l.Check(logEntries); lErr != nil {
+			reportFailure(fmt.Sprintf("output_logs %v: %v", i, lErr), &ConditionError{Type: "output_logs", Err: lErr})
+		}
+	}
+	for _, fErr := range observed.CheckFixtures(dir) {
+		reportFailure(fErr.Error(), fErr)
+	}
 }
@@ -171,9 +171,11 @@ output_batches:
 `,
 			expected: []test.CaseFailure{
 				{
-					Name:     "negative 1",
-					TestLine: 2,
-					Reason:   "batch 0 message 0: content_equals: content mismatch\n  expected: foo baz\n  received: foo bar",
+					Name:      "negative 1",
+					TestLine:  2,
+					Reason:    "batch 0 message 0: content_equals: content mismatch\n  expected: foo baz\n  received: foo bar",
+					Condition: "content_equals",
+					Diff:      "content mismatch\n  expected: foo baz\n  received: foo bar",
 				},
 			},
 		},
@@ -195,14 +197,18 @@ output_batches:
 `,
 			expected: []test.CaseFailure{
 				{
-					Name:     "negative 2",
-					TestLine: 2,
-					Reason:   "batch 0 message 1: content_equals: content mismatch\n  expected: bar baz\n  received: foo baz",
+					Name:      "negative 2",
+					TestLine:  2,
+					Reason:    "batch 0 message 1: content_equals: content mismatch\n  expected: bar baz\n  received: foo baz",
+					Condition: "content_equals",
+					Diff:      "content mismatch\n  expected: bar baz\n  received: foo baz",
 				},
 				{
-					Name:     "negative 2",
-					TestLine: 2,
-					Reason:   "batch 0 message 1: metadata_equals: metadata key 'foo' mismatch\n  expected: bar\n  received: baz",
+					Name:      "negative 2",
+					TestLine:  2,
+					Reason:    "batch 0 message 1: metadata_equals: metadata key 'foo' mismatch\n  expected: bar\n  received: baz",
+					Condition: "metadata_equals",
+					Diff:      "metadata key 'foo' mismatch\n  expected: bar\n  received: baz",
 				},
 			},
 		},
@@ -322,9 +328,11 @@ output_batches:
 
 	assert.Equal(t, []test.CaseFailure{
 		{
-			Name:     "not uppercased",
-			TestLine: 2,
-			Reason:   "batch 0 message 0: content_equals: content mismatch\n  expected: hello world FOO BAR BAZ\n  received: hello world foo bar baz",
+			Name:      "not uppercased",
+			TestLine:  2,
+			Reason:    "batch 0 message 0: content_equals: content mismatch\n  expected: hello world FOO BAR BAZ\n  received: hello world foo bar baz",
+			Condition: "content_equals",
+			Diff:      "content mismatch\n  expected: hello world FOO BAR BAZ\n  received: hello world foo bar baz",
 		},
 	}, fails)
 }
@@ -381,9 +389,102 @@ output_batches:
 
 	assert.Equal(t, []test.CaseFailure{
 		{
-			Name:     "not uppercased",
+			Name:      "not uppercased",
+			TestLine:  2,
+			Reason:    "batch 0 message 0: file_equals: content mismatch\n  expected: foo bar baz\n  received: FOO BAR BAZ",
+			Condition: "file_equals",
+			Diff:      "content mismatch\n  expected: foo bar baz\n  received: FOO BAR BAZ",
+		},
+	}, fails)
+}
+
+func TestCaseOutputSnapshot(t *testing.T) {
+	color.NoColor = true
+
+	provider := mockProvider{}
+	procConf := processor.NewConfig()
+
+	procConf.Type = "bloblang"
+	procConf.Bloblang = `
+meta foo = "bar"
+root = content().uppercase()
+`
+	proc, err := mock.NewManager().NewProcessor(procConf)
+	require.NoError(t, err)
+
+	provider["/pipeline/processors"] = []processor.V1{proc}
+
+	tmpDir := t.TempDir()
+
+	newCase := func(content string) test.Case {
+		t.Helper()
+		c := test.NewCase()
+		require.NoError(t, yaml.Unmarshal([]byte(`
+name: snapshot
+output_snapshot: ./snapshots/upper.yaml
+input_batches:
+  - - content: `+content+`
+    - content: bar
+  - - content: baz
+`), &c))
+		return c
+	}
+
+	c := newCase("foo")
+	fails, err := c.ExecuteFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Equal(t, []test.CaseFailure{
+		{
+			Name:     "snapshot",
 			TestLine: 2,
-			Reason:   "batch 0 message 0: file_equals: content mismatch\n  expected: foo bar baz\n  received: FOO BAR BAZ",
+			Reason:   "output_snapshot: snapshot file './snapshots/upper.yaml' does not exist, run the tests with --update-snapshots in order to create it",
 		},
 	}, fails)
+
+	c = newCase("foo").WithSnapshotUpdates()
+	fails, err = c.ExecuteFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+
+	snapBytes, err := os.ReadFile(filepath.Join(tmpDir, "snapshots", "upper.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(snapBytes), "content: FOO")
+
+	c = newCase("foo")
+	fails, err = c.ExecuteFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Empty(t, fails)
+
+	c = newCase("nope")
+	fails, err = c.ExecuteFrom(tmpDir, provider)
+	require.NoError(t, err)
+	assert.Equal(t, []test.CaseFailure{
+		{
+			Name:      "snapshot",
+			TestLine:  2,
+			Reason:    "output_snapshot: batch 0 message 0: content mismatch\n  expected: FOO\n  received: NOPE",
+			Condition: "output_snapshot",
+			Diff:      "batch 0 message 0: content mismatch\n  expected: FOO\n  received: NOPE",
+		},
+	}, fails)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "snapshots", "upper.yaml"), []byte(`
+- - content: FOO
+    metadata:
+      foo: baz
+`), 0o644))
+
+	c = newCase("foo")
+	fails, err = c.ExecuteFrom(tmpDir, provider)
+	require.NoError(t, err)
+
+	var reasons []string
+	for _, f := range fails {
+		reasons = append(reasons, f.Reason)
+	}
+	assert.Equal(t, []string{
+		"output_snapshot: wrong batch count, expected 1, got 2",
+		"output_snapshot: mismatch of batch 0 message counts, expected 1, got 2",
+		"output_snapshot: batch 0 message 0: metadata key 'foo' mismatch\n  expected: baz\n  received: bar",
+	}, reasons)
 }
@@ -3,6 +3,7 @@ package test
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 
@@ -22,6 +23,7 @@ fail the process will report the errors and exit with a status code 1.
   benthos test ./path/to/configs/...
   benthos test ./foo_configs/*.yaml ./bar_configs/*.yaml
   benthos test ./foo.yaml
+  benthos test --format junit ./path/to/configs/... > report.xml
 
 For more information check out the docs at:
 https://benthos.dev/docs/configuration/unit_testing`[1:],
@@ -31,6 +33,16 @@ https://benthos.dev/docs/configuration/unit_testing`[1:],
 				Value: "",
 				Usage: "allow components to write logs at a provided level to stdout.",
 			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: FormatDefault,
+				Usage: "the format of test results printed to stdout, one of: " + strings.Join(Formats(), ", ") + ".",
+			},
+			&cli.BoolFlag{
+				Name:  "update-snapshots",
+				Value: false,
+				Usage: "write the output batches of test cases to their output_snapshot files rather than checking them.",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if len(c.StringSlice("set")) > 0 {
@@ -46,15 +58,21 @@ https://benthos.dev/docs/configuration/unit_testing`[1:],
 			if logLevel := c.String("log"); len(logLevel) > 0 {
 				logConf := log.NewConfig()
 				logConf.LogLevel = logLevel
-				logger, err := log.NewV2(os.Stdout, logConf)
+				// Reports are written to stdout and must not be interleaved
+				// with logs.
+				logStream := os.Stdout
+				if c.String("format") != FormatDefault {
+					logStream = os.Stderr
+				}
+				logger, err := log.NewV2(logStream, logConf)
 				if err != nil {
 					fmt.Printf("Failed to init logger: %v\n", err)
 					os.Exit(1)
 				}
-				if RunAll(c.Args().Slice(), testSuffix, true, logger, resourcesPaths) {
+				if RunAll(c.Args().Slice(), testSuffix, true, logger, resourcesPaths, c.String("format"), c.Bool("update-snapshots")) {
 					os.Exit(0)
 				}
-			} else if RunAll(c.Args().Slice(), testSuffix, true, log.Noop(), resourcesPaths) {
+			} else if RunAll(c.Args().Slice(), testSuffix, true, log.Noop(), resourcesPaths, c.String("format"), c.Bool("update-snapshots")) {
 				os.Exit(0)
 			}
 			os.Exit(1)
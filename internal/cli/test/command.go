@@ -101,71 +101,96 @@ func lintTarget(path, testSuffix string) ([]string, error) {
 
 // RunAll executes the test command for a slice of paths. The path can either be
 // a config file, a config files test definition file, a directory, or the
-// wildcard pattern './...'.
-func RunAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string) bool {
+// wildcard pattern './...'. Results are printed to stdout in the given format.
+// When updateSnapshots is true the output snapshot files of test cases are
+// written rather than checked.
+func RunAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string, format string, updateSnapshots bool) bool {
+	switch format {
+	case FormatDefault:
+	case FormatJUnit, FormatTAP, FormatJSON:
+		// Reports are consumed by other tools and must not contain colours.
+		color.NoColor = true
+	default:
+		fmt.Fprintf(os.Stderr, "Unrecognised report format '%v', expected one of: %v\n", format, strings.Join(Formats(), ", "))
+		return false
+	}
+
 	targets, err := GetTestTargets(paths, testSuffix)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to obtain test targets: %v\n", err)
 		return false
 	}
 	if len(targets) == 0 {
-		fmt.Printf("%v\n", yellow("No tests were found"))
+		if format == FormatDefault {
+			fmt.Printf("%v\n", yellow("No tests were found"))
+		} else {
+			fmt.Fprintln(os.Stderr, "No tests were found")
+		}
 		return false
 	}
 
-	type failedTarget struct {
-		target string
-		lints  []string
-		cases  []CaseFailure
-	}
-	fails := []failedTarget{}
-
 	targetPaths := make([]string, 0, len(targets))
 	for k := range targets {
 		targetPaths = append(targetPaths, k)
 	}
 	sort.Strings(targetPaths)
 
+	results := make([]TargetResult, 0, len(targetPaths))
+	fails := []TargetResult{}
 	for _, target := range targetPaths {
-		var lints []string
-		var failCases []CaseFailure
+		result := TargetResult{Path: target}
 		if lint {
-			if lints, err = lintTarget(target, testSuffix); err != nil {
+			if result.Lints, err = lintTarget(target, testSuffix); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 				return false
 			}
 		}
-		if failCases, err = targets[target].Execute(target, resourcesPaths, logger); err != nil {
+		if result.Cases, err = targets[target].Run(target, resourcesPaths, logger, updateSnapshots); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 			return false
 		}
-		if len(lints) > 0 || len(failCases) > 0 {
-			fails = append(fails, failedTarget{
-				target: target,
-				lints:  lints,
-				cases:  failCases,
-			})
+		results = append(results, result)
+		if result.Failed() {
+			fails = append(fails, result)
+		}
+		if format != FormatDefault {
+			continue
+		}
+		if result.Failed() {
 			fmt.Printf("Test '%v' %v\n", target, red("failed"))
 		} else {
 			fmt.Printf("Test '%v' %v\n", target, green("succeeded"))
 		}
 	}
+
+	if format != FormatDefault {
+		if err := WriteReport(os.Stdout, format, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write test report: %v\n", err)
+			return false
+		}
+		return len(fails) == 0
+	}
+
 	if len(fails) > 0 {
 		fmt.Printf("\nFailures:\n\n")
 		for i, fail := range fails {
 			if i > 0 {
 				fmt.Println("")
 			}
-			fmt.Printf("--- %v ---\n\n", fail.target)
-			for _, lint := range fail.lints {
+			fmt.Printf("--- %v ---\n\n", fail.Path)
+			for _, lint := range fail.Lints {
 				fmt.Printf("Lint: %v\n", lint)
 			}
-			if len(fail.cases) > 0 {
-				if len(fail.lints) > 0 {
+			var failCases []CaseFailure
+			for _, c := range fail.Cases {
+				failCases = append(failCases, c.Failures...)
+			}
+			if len(failCases) > 0 {
+				if len(fail.Lints) > 0 {
 					fmt.Println("")
 				}
 				var namePrev string
-				for i, fail := range fail.cases {
+				for i, fail := range failCases {
 					if namePrev != fail.Name {
 						if i > 0 {
 							fmt.Println("")
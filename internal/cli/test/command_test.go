@@ -244,15 +244,15 @@ tests:
 	}
 	defer os.RemoveAll(testDir)
 
-	if !test.RunAll([]string{filepath.Join(testDir, "foo.yaml")}, "_benthos_test", false, log.Noop(), nil) {
+	if !test.RunAll([]string{filepath.Join(testDir, "foo.yaml")}, "_benthos_test", false, log.Noop(), nil, test.FormatDefault, false) {
 		t.Error("Unexpected result")
 	}
 
-	if test.RunAll([]string{filepath.Join(testDir, "foo.yaml")}, "_benthos_test", true, log.Noop(), nil) {
+	if test.RunAll([]string{filepath.Join(testDir, "foo.yaml")}, "_benthos_test", true, log.Noop(), nil, test.FormatDefault, false) {
 		t.Error("Unexpected result")
 	}
 
-	if test.RunAll([]string{testDir}, "_benthos_test", true, log.Noop(), nil) {
+	if test.RunAll([]string{testDir}, "_benthos_test", true, log.Noop(), nil, test.FormatDefault, false) {
 		t.Error("Unexpected result")
 	}
 }
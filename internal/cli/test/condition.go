@@ -94,8 +94,26 @@ func (c *ConditionsMap) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// ConditionError is returned when a message part fails a condition, and
+// contains the type of the condition that failed.
+type ConditionError struct {
+	Type string
+	Err  error
+}
+
+// Error returns the type of the failed condition followed by the reason.
+func (c *ConditionError) Error() string {
+	return fmt.Sprintf("%v: %v", c.Type, c.Err)
+}
+
+// Unwrap returns the reason that the condition failed.
+func (c *ConditionError) Unwrap() error {
+	return c.Err
+}
+
 // CheckAll checks all conditions against a message part. Conditions are
-// executed in alphabetical order.
+// executed in alphabetical order, and each failed condition is returned as a
+// *ConditionError.
 func (c ConditionsMap) CheckAll(dir string, part *message.Part) (errs []error) {
 	condTypes := []string{}
 	for k := range c {
@@ -107,10 +125,10 @@ func (c ConditionsMap) CheckAll(dir string, part *message.Part) (errs []error) {
 			checkFrom(string, *message.Part) error
 		}); ok {
 			if err := relCheck.checkFrom(dir, part); err != nil {
-				errs = append(errs, fmt.Errorf("%v: %v", k, err))
+				errs = append(errs, &ConditionError{Type: k, Err: err})
 			}
 		} else if err := c[k].Check(part); err != nil {
-			errs = append(errs, fmt.Errorf("%v: %v", k, err))
+			errs = append(errs, &ConditionError{Type: k, Err: err})
 		}
 	}
 	return
@@ -3,6 +3,7 @@ package test
 import (
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/log"
 )
@@ -12,8 +13,32 @@ type Definition struct {
 	Cases []Case `yaml:"tests"`
 }
 
+// CaseResult contains the outcome of a single executed test case.
+type CaseResult struct {
+	Name     string
+	TestLine int
+	Duration time.Duration
+	Failures []CaseFailure
+}
+
 // Execute the test definition.
 func (d Definition) Execute(testFilePath string, resourcesPaths []string, logger log.Modular) ([]CaseFailure, error) {
+	results, err := d.Run(testFilePath, resourcesPaths, logger, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalFailures []CaseFailure
+	for _, r := range results {
+		totalFailures = append(totalFailures, r.Failures...)
+	}
+	return totalFailures, nil
+}
+
+// Run executes the test definition and returns the result of each test case.
+// When updateSnapshots is true the output snapshot files of test cases are
+// written rather than checked.
+func (d Definition) Run(testFilePath string, resourcesPaths []string, logger log.Modular, updateSnapshots bool) ([]CaseResult, error) {
 	procsProvider := NewProcessorsProvider(
 		testFilePath,
 		OptAddResourcesPaths(resourcesPaths),
@@ -22,17 +47,26 @@ func (d Definition) Execute(testFilePath string, resourcesPaths []string, logger
 
 	dir := filepath.Dir(testFilePath)
 
-	var totalFailures []CaseFailure
+	results := make([]CaseResult, 0, len(d.Cases))
 	for i, c := range d.Cases {
+		if updateSnapshots {
+			c = c.WithSnapshotUpdates()
+		}
 		cleanupEnv := setEnvironment(c.Environment)
+		started := time.Now()
 		failures, err := c.ExecuteFrom(dir, procsProvider)
 		if err != nil {
 			cleanupEnv()
 			return nil, fmt.Errorf("test case %v failed: %v", i, err)
 		}
-		totalFailures = append(totalFailures, failures...)
+		results = append(results, CaseResult{
+			Name:     c.Name,
+			TestLine: c.line,
+			Duration: time.Since(started),
+			Failures: failures,
+		})
 		cleanupEnv()
 	}
 
-	return totalFailures, nil
+	return results, nil
 }
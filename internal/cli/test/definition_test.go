@@ -6,6 +6,9 @@ import (
 	"testing"
 
 	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
 
 	"github.com/benthosdev/benthos/v4/internal/cli/test"
 	"github.com/benthosdev/benthos/v4/internal/log"
@@ -163,3 +166,241 @@ pipeline:
 		t.Errorf("Mismatched fail message: %v != %v", act, exp)
 	}
 }
+
+func TestDefinitionObserved(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(t, map[string]string{
+		"config1.yaml": `
+cache_resources:
+  - label: foocache
+    memory: {}
+
+rate_limit_resources:
+  - label: foolimit
+    local:
+      count: 1
+      interval: 1h
+
+pipeline:
+  processors:
+    - rate_limit:
+        resource: foolimit
+    - cache:
+        resource: foocache
+        operator: get
+        key: ${! json("id") }
+    - cache:
+        resource: foocache
+        operator: set
+        key: ${! json("id") }_seen
+        value: "true"
+    - label: count_docs
+      metric:
+        type: counter
+        name: docs_total
+        labels:
+          kind: ${! json("kind") }
+    - log:
+        level: INFO
+        message: 'received ${! json("kind") } doc'
+        fields_mapping: 'root.id = this.id'
+`,
+	})
+	require.NoError(t, err)
+
+	var def test.Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: passing test
+    cache_resources:
+      - label: foocache
+        seed:
+          a: '{"id":"a","kind":"foo"}'
+          b: '{"id":"b","kind":"foo"}'
+        expect:
+          a_seen:
+            content_equals: "true"
+        expect_missing: [ c_seen ]
+    rate_limit_resources:
+      - label: foolimit
+        expect_accesses: 2
+    input_batch:
+      - content: '{"id":"a"}'
+      - content: '{"id":"b"}'
+    output_batches:
+      - - json_equals: { "id": "a", "kind": "foo" }
+        - json_equals: { "id": "b", "kind": "foo" }
+    output_metrics:
+      - name: docs_total
+        labels:
+          kind: foo
+        equals: 2
+      - name: rate_limit_checked
+        greater_than: 1
+    output_logs:
+      - level: info
+        message_equals: received foo doc
+        fields:
+          id: b
+
+  - name: failing test
+    cache_resources:
+      - label: foocache
+        seed:
+          a: '{"id":"a","kind":"foo"}'
+        expect:
+          a_seen:
+            content_equals: "false"
+        expect_missing: [ a ]
+    rate_limit_resources:
+      - label: foolimit
+        expect_accesses: 2
+    input_batch:
+      - content: '{"id":"a"}'
+    output_batches:
+      - - content_equals: '{"id":"a","kind":"foo"}'
+    output_metrics:
+      - name: docs_total
+        labels:
+          kind: bar
+        equals: 1
+    output_logs:
+      - level: ERROR
+`), &def))
+
+	failures, err := def.Execute(filepath.Join(testDir, "config1.yaml"), nil, log.Noop())
+	require.NoError(t, err)
+
+	var reasons []string
+	for _, f := range failures {
+		assert.Equal(t, "failing test", f.Name)
+		reasons = append(reasons, f.Reason)
+	}
+	require.Len(t, reasons, 5)
+	assert.Equal(t, "output_metrics 0: metric docs_total{kind=\"bar\"} mismatch\n  expected: 1\n  received: 0", reasons[0])
+	assert.Contains(t, reasons[1], "output_logs 0: no matching log line\n  expected: level: ERROR\n  received:\n")
+	assert.Contains(t, reasons[1], "INFO: received foo doc")
+	assert.Equal(t, "cache 'foocache' key 'a_seen': content_equals: content mismatch\n  expected: false\n  received: true", reasons[2])
+	assert.Equal(t, "cache 'foocache' key 'a': expected key to be missing, found: {\"id\":\"a\",\"kind\":\"foo\"}", reasons[3])
+	assert.Equal(t, "rate limit 'foolimit' accesses mismatch\n  expected: 2\n  received: 1", reasons[4])
+}
+
+func TestDefinitionStream(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(t, map[string]string{
+		"config1.yaml": `
+input:
+  generate:
+    mapping: 'root = {}'
+
+pipeline:
+  processors:
+    - bloblang: 'root = this.merge({"seen": true})'
+
+output:
+  switch:
+    cases:
+      - check: this.type == "event"
+        output:
+          fallback:
+            - label: primary_out
+              drop: {}
+            - label: backup_out
+              drop: {}
+      - check: this.type == "audit"
+        output:
+          retry:
+            backoff:
+              initial_interval: 1ms
+              max_interval: 1ms
+            output:
+              label: audit_out
+              drop: {}
+      - output:
+          reject: 'unknown type: ${! json("type") }'
+`,
+		"config2.yaml": `
+input:
+  generate:
+    mapping: 'root = {}'
+
+pipeline:
+  processors:
+    - bloblang: 'root = content().uppercase()'
+
+output:
+  drop: {}
+`,
+	})
+	require.NoError(t, err)
+
+	var def test.Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: passing test
+    target_stream: true
+    input_batches:
+      - - content: '{"id":"a","type":"event"}'
+      - - content: '{"id":"b","type":"audit"}'
+      - - content: '{"id":"c","type":"unknown"}'
+    stream_outputs:
+      primary_out:
+        fail_attempts: 1
+      backup_out:
+        output_batches:
+          - - json_equals: { "id": "a", "type": "event", "seen": true }
+      audit_out:
+        fail_attempts: 2
+        output_batches:
+          - - json_equals: { "id": "b", "type": "audit", "seen": true }
+    rejected_batches:
+      - - content_equals: '{"id":"c","type":"unknown"}'
+    output_metrics:
+      - name: processor_received
+        equals: 3
+
+  - name: failing test
+    target_stream: true
+    input_batches:
+      - - content: '{"id":"a","type":"event"}'
+      - - content: '{"id":"c","type":"unknown"}'
+    stream_outputs:
+      primary_out:
+        output_batches:
+          - - json_contains: { "id": "b" }
+      backup_out:
+        output_batches:
+          - - json_contains: { "id": "a" }
+`), &def))
+
+	failures, err := def.Execute(filepath.Join(testDir, "config1.yaml"), nil, log.Noop())
+	require.NoError(t, err)
+
+	var reasons []string
+	for _, f := range failures {
+		assert.Equal(t, "failing test", f.Name)
+		reasons = append(reasons, f.Reason)
+	}
+	require.Len(t, reasons, 3)
+	assert.Equal(t, "output 'backup_out': wrong batch count, expected 1, got 0", reasons[0])
+	assert.Contains(t, reasons[1], "output 'primary_out': batch 0 message 0: json_contains: JSON superset mismatch")
+	assert.Equal(t, "rejected batches: unexpected batch: [{\"id\":\"c\",\"type\":\"unknown\"}]", reasons[2])
+
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: root output test
+    target_stream: true
+    input_batch:
+      - content: 'foo'
+      - content: 'bar'
+    output_batches:
+      - - content_equals: FOO
+        - content_equals: BAR
+`), &def))
+
+	failures, err = def.Execute(filepath.Join(testDir, "config2.yaml"), nil, log.Noop())
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
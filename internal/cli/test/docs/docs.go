@@ -51,6 +51,10 @@ It is also possible to target processors in a separate file by prefixing the tar
 			"target_mapping",
 			"A file path relative to the test definition path of a Bloblang file to execute as an alternative to testing processors with the `target_processors` field. This allows you to define unit tests for Bloblang mappings directly.",
 		).HasDefault(""),
+		docs.FieldBool(
+			"target_stream",
+			"Execute the entire stream of the config file as an alternative to testing processors with the `target_processors` field. The input of the stream is replaced with the messages of the test, and the target outputs of the stream are replaced with in-memory outputs that can be checked with `stream_outputs`.",
+		).HasDefault(false),
 		docs.FieldAnything(
 			"mocks",
 			"An optional map of processors to mock. Keys should contain either a label or a JSON pointer of a processor that should be mocked. Values should contain a processor definition, which will replace the mocked processor. Most of the time you'll want to use a `bloblang` processor here, and use it to create a result that emulates the target processor.",
@@ -65,6 +69,20 @@ It is also possible to target processors in a separate file by prefixing the tar
 				},
 			},
 		).Map().Optional(),
+		docs.FieldObject(
+			"cache_resources", "An optional list of cache resources to replace with in-memory caches for the duration of the test. Each cache can be seeded with key/value pairs before the test is executed and checked after the test has finished.",
+		).Array().Optional().WithChildren(
+			docs.FieldString("label", "The label of the cache resource to replace. If a cache resource with this label does not exist in the config then it is added.", "foo_cache"),
+			docs.FieldString("seed", "A map of key/value pairs to add to the cache before the test is executed.", map[string]interface{}{"foo": "bar"}).Map().Optional(),
+			docs.FieldObject("expect", "A map of keys to conditions that are checked against the value of each key after the test has finished. The test fails if a key does not exist.").Map().Optional().WithChildren(outputConditionFields()...),
+			docs.FieldString("expect_missing", "A list of keys that are expected to not exist in the cache after the test has finished.", []string{"baz"}).Array().Optional(),
+		),
+		docs.FieldObject(
+			"rate_limit_resources", "An optional list of rate limit resources to replace with in-memory rate limits that never block for the duration of the test. Accesses of a rate limit can be checked with the `rate_limit_checked` metric using `output_metrics`.",
+		).Array().Optional().WithChildren(
+			docs.FieldString("label", "The label of the rate limit resource to replace. If a rate limit resource with this label does not exist in the config then it is added.", "foo_rate_limit"),
+			docs.FieldInt("expect_accesses", "The number of times the rate limit is expected to be accessed during the test.", 2).Optional(),
+		),
 		docs.FieldObject(
 			"input_batch", "Define a batch of messages to feed into your test, specify either an `input_batch` or a series of `input_batches`.",
 		).Array().Optional().WithChildren(
@@ -99,43 +117,78 @@ It is also possible to target processors in a separate file by prefixing the tar
 		),
 		docs.FieldObject(
 			"output_batches", "",
-		).ArrayOfArrays().Optional().WithChildren(
-			docs.FieldString("content", "The raw content of the input message.").HasDefault(""),
-			docs.FieldString("metadata", "A map of metadata key/values to add to the input message.").Map().Optional(),
-			docs.FieldString(
-				`bloblang`,
-				"Executes a Bloblang mapping on the output message, if the result is anything other than a boolean equalling `true` the test fails.",
-				"this.age > 10 && meta(\"foo\").length() > 0",
-			).Optional(),
-			docs.FieldString(`content_equals`, "Checks the full raw contents of a message against a value.").Optional(),
-			docs.FieldString(`content_matches`, "Checks whether the full raw contents of a message matches a regular expression (re2).", "^foo [a-z]+ bar$").Optional(),
-			docs.FieldString(
-				`metadata_equals`,
-				"Checks a map of metadata keys to values against the metadata stored in the message. If there is a value mismatch between a key of the condition versus the message metadata this condition will fail.",
-				map[string]interface{}{
-					"example_key": "example metadata value",
-				},
-			).Map().Optional(),
-			docs.FieldString(
-				`file_equals`,
-				"Checks that the contents of a message matches the contents of a file. The path of the file should be relative to the path of the test file.",
-				"./foo/bar.txt",
-			).Optional(),
-			docs.FieldString(
-				`file_json_equals`,
-				"Checks that both the message and the file contents are valid JSON documents, and that they are structurally equivalent. Will ignore formatting and ordering differences. The path of the file should be relative to the path of the test file.",
-				"./foo/bar.json",
-			).Optional(),
-			docs.FieldAnything(
-				`json_equals`,
-				"Checks that both the message and the condition are valid JSON documents, and that they are structurally equivalent. Will ignore formatting and ordering differences.",
-				map[string]interface{}{"key": "value"},
-			).Optional(),
-			docs.FieldString(
-				`json_contains`,
-				"Checks that both the message and the condition are valid JSON documents, and that the message is a superset of the condition.",
-				map[string]interface{}{"key": "value"},
-			).Optional(),
+		).ArrayOfArrays().Optional().WithChildren(outputConditionFields()...),
+		docs.FieldString(
+			"output_snapshot",
+			"A file path relative to the test definition path of a snapshot file containing the expected content and metadata of each output message. Snapshot files are written when tests are run with the flag `--update-snapshots`, and are checked against the output of the test otherwise.",
+			"./snapshots/foo.yaml",
+		).HasDefault(""),
+		docs.FieldObject(
+			"stream_outputs", "A map of outputs to replace with in-memory outputs when `target_stream` is enabled, and the batches expected to reach each of them. Keys should contain either a label or a JSON pointer of an output. When omitted the root output of the stream is replaced and checked against `output_batches`.",
+		).Map().Optional().WithChildren(
+			docs.FieldInt("fail_attempts", "The number of delivery attempts to this output that should fail before messages are accepted, which can be used to test retry and fallback behaviour.").HasDefault(0),
+			docs.FieldObject("output_batches", "The batches expected to be successfully delivered to this output, in order.").ArrayOfArrays().Optional().WithChildren(outputConditionFields()...),
+		),
+		docs.FieldObject(
+			"rejected_batches", "When `target_stream` is enabled, a series of batches of conditions checked against each input batch that was rejected by the stream, in order.",
+		).ArrayOfArrays().Optional().WithChildren(outputConditionFields()...),
+		docs.FieldObject(
+			"output_metrics", "A list of conditions to check against the metrics emitted by the processors during the test. The value checked is the sum of all counters and gauges with a matching name that contain each of the specified labels, where metrics that were not emitted have a value of zero.",
+		).Array().Optional().WithChildren(
+			docs.FieldString("name", "The name of the metric.", "processor_received"),
+			docs.FieldString("labels", "An optional map of labels that a metric must contain in order to be included.", map[string]interface{}{"label": "foo_processor"}).Map().Optional(),
+			docs.FieldInt("equals", "Checks that the value of the metric is equal to a number.").Optional(),
+			docs.FieldInt("greater_than", "Checks that the value of the metric is greater than a number.").Optional(),
+			docs.FieldInt("less_than", "Checks that the value of the metric is less than a number.").Optional(),
+		),
+		docs.FieldObject(
+			"output_logs", "A list of conditions to check against the log lines written during the test. Each condition passes when at least one log line matches all of its specified fields.",
+		).Array().Optional().WithChildren(
+			docs.FieldString("level", "The level of the log line, case insensitive. One of `FATAL`, `ERROR`, `WARN`, `INFO`, `DEBUG` or `TRACE`.", "INFO").Optional(),
+			docs.FieldString("message_equals", "Checks the full message of a log line against a value.").Optional(),
+			docs.FieldString("message_matches", "Checks whether the message of a log line matches a regular expression (re2).", "^failed to .*$").Optional(),
+			docs.FieldString("fields", "A map of fields that the log line must contain.", map[string]interface{}{"label": "foo_processor"}).Map().Optional(),
 		),
 	)
 }
+
+func outputConditionFields() []docs.FieldSpec {
+	return []docs.FieldSpec{
+		docs.FieldString("content", "The raw content of the input message.").HasDefault(""),
+		docs.FieldString("metadata", "A map of metadata key/values to add to the input message.").Map().Optional(),
+		docs.FieldString(
+			`bloblang`,
+			"Executes a Bloblang mapping on the output message, if the result is anything other than a boolean equalling `true` the test fails.",
+			"this.age > 10 && meta(\"foo\").length() > 0",
+		).Optional(),
+		docs.FieldString(`content_equals`, "Checks the full raw contents of a message against a value.").Optional(),
+		docs.FieldString(`content_matches`, "Checks whether the full raw contents of a message matches a regular expression (re2).", "^foo [a-z]+ bar$").Optional(),
+		docs.FieldString(
+			`metadata_equals`,
+			"Checks a map of metadata keys to values against the metadata stored in the message. If there is a value mismatch between a key of the condition versus the message metadata this condition will fail.",
+			map[string]interface{}{
+				"example_key": "example metadata value",
+			},
+		).Map().Optional(),
+		docs.FieldString(
+			`file_equals`,
+			"Checks that the contents of a message matches the contents of a file. The path of the file should be relative to the path of the test file.",
+			"./foo/bar.txt",
+		).Optional(),
+		docs.FieldString(
+			`file_json_equals`,
+			"Checks that both the message and the file contents are valid JSON documents, and that they are structurally equivalent. Will ignore formatting and ordering differences. The path of the file should be relative to the path of the test file.",
+			"./foo/bar.json",
+		).Optional(),
+		docs.FieldAnything(
+			`json_equals`,
+			"Checks that both the message and the condition are valid JSON documents, and that they are structurally equivalent. Will ignore formatting and ordering differences.",
+			map[string]interface{}{"key": "value"},
+		).Optional(),
+		docs.FieldString(
+			`json_contains`,
+			"Checks that both the message and the condition are valid JSON documents, and that the message is a superset of the condition.",
+			map[string]interface{}{"key": "value"},
+		).Optional(),
+	}
+}
@@ -0,0 +1,194 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// CacheFixture replaces a cache resource of a config with an in-memory cache
+// for the duration of a test case, which can be seeded with key/value pairs
+// before the test and checked afterwards.
+type CacheFixture struct {
+	Label         string                   `yaml:"label"`
+	Seed          map[string]string        `yaml:"seed"`
+	Expect        map[string]ConditionsMap `yaml:"expect"`
+	ExpectMissing []string                 `yaml:"expect_missing"`
+}
+
+// RateLimitFixture replaces a rate limit resource of a config with an
+// in-memory rate limit that never blocks for the duration of a test case, the
+// number of accesses of which can be checked afterwards.
+type RateLimitFixture struct {
+	Label          string `yaml:"label"`
+	ExpectAccesses *int64 `yaml:"expect_accesses"`
+}
+
+// Fixtures contains all resource fixtures of a test case.
+type Fixtures struct {
+	Caches     []CacheFixture
+	RateLimits []RateLimitFixture
+}
+
+// Empty returns true if there are no fixtures.
+func (f Fixtures) Empty() bool {
+	return len(f.Caches) == 0 && len(f.RateLimits) == 0
+}
+
+//------------------------------------------------------------------------------
+
+func fixturePluginNode(v interface{}) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// applyTo returns a copy of a resources config where each resource targeted by
+// a fixture is replaced, or added when it does not already exist.
+func (f Fixtures) applyTo(conf manager.ResourceConfig) (manager.ResourceConfig, error) {
+	caches := make([]cache.Config, 0, len(conf.ResourceCaches)+len(f.Caches))
+	for _, c := range conf.ResourceCaches {
+		if !f.hasCache(c.Label) {
+			caches = append(caches, c)
+		}
+	}
+	for _, fc := range f.Caches {
+		if fc.Label == "" {
+			return conf, errors.New("cache fixtures must specify a label")
+		}
+		initValues := map[string]interface{}{}
+		for k, v := range fc.Seed {
+			initValues[k] = v
+		}
+		node, err := fixturePluginNode(map[string]interface{}{
+			"init_values": initValues,
+		})
+		if err != nil {
+			return conf, fmt.Errorf("cache fixture '%v': %w", fc.Label, err)
+		}
+		cConf := cache.NewConfig()
+		cConf.Label = fc.Label
+		cConf.Type = "memory"
+		cConf.Plugin = node
+		caches = append(caches, cConf)
+	}
+
+	rateLimits := make([]ratelimit.Config, 0, len(conf.ResourceRateLimits)+len(f.RateLimits))
+	for _, r := range conf.ResourceRateLimits {
+		if !f.hasRateLimit(r.Label) {
+			rateLimits = append(rateLimits, r)
+		}
+	}
+	for _, fr := range f.RateLimits {
+		if fr.Label == "" {
+			return conf, errors.New("rate limit fixtures must specify a label")
+		}
+		node, err := fixturePluginNode(map[string]interface{}{
+			"count":    math.MaxInt32,
+			"interval": "1s",
+		})
+		if err != nil {
+			return conf, fmt.Errorf("rate limit fixture '%v': %w", fr.Label, err)
+		}
+		rConf := ratelimit.NewConfig()
+		rConf.Label = fr.Label
+		rConf.Type = "local"
+		rConf.Plugin = node
+		rateLimits = append(rateLimits, rConf)
+	}
+
+	conf.ResourceCaches = caches
+	conf.ResourceRateLimits = rateLimits
+	return conf, nil
+}
+
+func (f Fixtures) hasCache(label string) bool {
+	for _, c := range f.Caches {
+		if c.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Fixtures) hasRateLimit(label string) bool {
+	for _, r := range f.RateLimits {
+		if r.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRateLimits verifies the expected number of accesses of each rate limit
+// fixture from the metrics emitted during a test case.
+func (f Fixtures) checkRateLimits(metricValues map[string]int64) (errs []error) {
+	for _, fr := range f.RateLimits {
+		if fr.ExpectAccesses == nil {
+			continue
+		}
+		cond := MetricCondition{
+			Name:   "rate_limit_checked",
+			Labels: map[string]string{"label": fr.Label},
+		}
+		var accesses int64
+		for k, v := range metricValues {
+			if cond.matches(k) {
+				accesses += v
+			}
+		}
+		if accesses != *fr.ExpectAccesses {
+			errs = append(errs, fmt.Errorf("rate limit '%v' accesses mismatch\n  expected: %v\n  received: %v", fr.Label, *fr.ExpectAccesses, accesses))
+		}
+	}
+	return
+}
+
+// checkCaches verifies the expected state of each cache fixture after a test
+// case has been executed.
+func (f Fixtures) checkCaches(dir string, mgr bundle.NewManagement) (errs []error) {
+	ctx := context.Background()
+	for _, fc := range f.Caches {
+		keys := make([]string, 0, len(fc.Expect))
+		for k := range fc.Expect {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		if err := mgr.AccessCache(ctx, fc.Label, func(c cache.V1) {
+			for _, k := range keys {
+				v, err := c.Get(ctx, k)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("cache '%v' key '%v': %v", fc.Label, k, err))
+					continue
+				}
+				for _, condErr := range fc.Expect[k].CheckAll(dir, message.NewPart(v)) {
+					errs = append(errs, fmt.Errorf("cache '%v' key '%v': %w", fc.Label, k, condErr))
+				}
+			}
+			for _, k := range fc.ExpectMissing {
+				if v, err := c.Get(ctx, k); err == nil {
+					errs = append(errs, fmt.Errorf("cache '%v' key '%v': expected key to be missing, found: %s", fc.Label, k, v))
+				} else if !errors.Is(err, component.ErrKeyNotFound) {
+					errs = append(errs, fmt.Errorf("cache '%v' key '%v': %v", fc.Label, k, err))
+				}
+			}
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("cache '%v': %v", fc.Label, err))
+		}
+	}
+	return
+}
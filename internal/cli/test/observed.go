@@ -0,0 +1,359 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+)
+
+// MetricCondition is a test case against the value of a metric emitted during
+// the execution of a test. The value is the sum of all counters and gauges of
+// the given name that contain each of the specified labels.
+type MetricCondition struct {
+	Name        string            `yaml:"name"`
+	Labels      map[string]string `yaml:"labels"`
+	Equals      *int64            `yaml:"equals"`
+	GreaterThan *int64            `yaml:"greater_than"`
+	LessThan    *int64            `yaml:"less_than"`
+}
+
+func (m MetricCondition) matches(path string) bool {
+	name, tagNames, tagValues := metrics.ReverseLabelledPath(path)
+	if name != m.Name {
+		return false
+	}
+	for k, v := range m.Labels {
+		found := false
+		for i, tk := range tagNames {
+			if tk == k && tagValues[i] == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MetricCondition) describe() string {
+	if len(m.Labels) == 0 {
+		return m.Name
+	}
+	keys := make([]string, 0, len(m.Labels))
+	for k := range m.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labels := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labels = append(labels, fmt.Sprintf("%v=%q", k, m.Labels[k]))
+	}
+	return m.Name + "{" + strings.Join(labels, ",") + "}"
+}
+
+// Check this condition against a map of metric paths to values.
+func (m MetricCondition) Check(values map[string]int64) error {
+	var total int64
+	for k, v := range values {
+		if m.matches(k) {
+			total += v
+		}
+	}
+	if m.Equals != nil && total != *m.Equals {
+		return fmt.Errorf("metric %v mismatch\n  expected: %v\n  received: %v", m.describe(), *m.Equals, total)
+	}
+	if m.GreaterThan != nil && total <= *m.GreaterThan {
+		return fmt.Errorf("metric %v mismatch\n  expected: greater than %v\n  received: %v", m.describe(), *m.GreaterThan, total)
+	}
+	if m.LessThan != nil && total >= *m.LessThan {
+		return fmt.Errorf("metric %v mismatch\n  expected: less than %v\n  received: %v", m.describe(), *m.LessThan, total)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// LogCondition is a test case that passes when at least one log line written
+// during the execution of a test matches all of the specified fields.
+type LogCondition struct {
+	Level          string
+	MessageEquals  string
+	MessageMatches *regexp.Regexp
+	Fields         map[string]string
+}
+
+// UnmarshalYAML extracts a LogCondition from a YAML node.
+func (l *LogCondition) UnmarshalYAML(value *yaml.Node) error {
+	rawMap := map[string]yaml.Node{}
+	if err := value.Decode(&rawMap); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+	for k, v := range rawMap {
+		switch k {
+		case "level":
+			if err := v.Decode(&l.Level); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			l.Level = strings.ToUpper(l.Level)
+		case "message_equals":
+			if err := v.Decode(&l.MessageEquals); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+		case "message_matches":
+			var expr string
+			if err := v.Decode(&expr); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			l.MessageMatches = re
+		case "fields":
+			if err := v.Decode(&l.Fields); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+		default:
+			return fmt.Errorf("line %v: log condition field not recognised: %v", v.Line, k)
+		}
+	}
+	return nil
+}
+
+func (l LogCondition) matches(e LogEntry) bool {
+	if l.Level != "" && l.Level != e.Level {
+		return false
+	}
+	if l.MessageEquals != "" && l.MessageEquals != e.Message {
+		return false
+	}
+	if l.MessageMatches != nil && !l.MessageMatches.MatchString(e.Message) {
+		return false
+	}
+	for k, v := range l.Fields {
+		if ev, exists := e.Fields[k]; !exists || ev != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Check this condition against the log lines written during a test.
+func (l LogCondition) Check(entries []LogEntry) error {
+	for _, e := range entries {
+		if l.matches(e) {
+			return nil
+		}
+	}
+
+	var conds []string
+	if l.Level != "" {
+		conds = append(conds, fmt.Sprintf("level: %v", l.Level))
+	}
+	if l.MessageEquals != "" {
+		conds = append(conds, fmt.Sprintf("message_equals: %v", l.MessageEquals))
+	}
+	if l.MessageMatches != nil {
+		conds = append(conds, fmt.Sprintf("message_matches: %v", l.MessageMatches.String()))
+	}
+	if len(l.Fields) > 0 {
+		conds = append(conds, fmt.Sprintf("fields: %v", l.Fields))
+	}
+
+	received := make([]string, len(entries))
+	for i, e := range entries {
+		received[i] = e.String()
+	}
+	return fmt.Errorf("no matching log line\n  expected: %v\n  received:\n    %v", strings.Join(conds, ", "), strings.Join(received, "\n    "))
+}
+
+//------------------------------------------------------------------------------
+
+// LogEntry is a single log line written during the execution of a test.
+type LogEntry struct {
+	Level   string
+	Message string
+	Fields  map[string]string
+}
+
+// String returns a string representation of the log entry.
+func (e LogEntry) String() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("%v: %v", e.Level, e.Message)
+	}
+	return fmt.Sprintf("%v: %v %v", e.Level, e.Message, e.Fields)
+}
+
+type logRecorder struct {
+	mut     sync.Mutex
+	entries []LogEntry
+}
+
+func (r *logRecorder) add(e LogEntry) {
+	r.mut.Lock()
+	r.entries = append(r.entries, e)
+	r.mut.Unlock()
+}
+
+// Entries returns all log lines recorded so far.
+func (r *logRecorder) Entries() []LogEntry {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return append([]LogEntry(nil), r.entries...)
+}
+
+// capturingLogger records all log lines written to it at every level whilst
+// also forwarding them to a child logger.
+type capturingLogger struct {
+	rec    *logRecorder
+	fields map[string]string
+	child  log.Modular
+}
+
+func newCapturingLogger(child log.Modular) *capturingLogger {
+	return &capturingLogger{
+		rec:    &logRecorder{},
+		fields: map[string]string{},
+		child:  child,
+	}
+}
+
+func (c *capturingLogger) withFields(fields map[string]string, child log.Modular) log.Modular {
+	newFields := make(map[string]string, len(c.fields)+len(fields))
+	for k, v := range c.fields {
+		newFields[k] = v
+	}
+	for k, v := range fields {
+		newFields[k] = v
+	}
+	return &capturingLogger{
+		rec:    c.rec,
+		fields: newFields,
+		child:  child,
+	}
+}
+
+func (c *capturingLogger) WithFields(fields map[string]string) log.Modular {
+	return c.withFields(fields, c.child.WithFields(fields))
+}
+
+func (c *capturingLogger) With(keyValues ...interface{}) log.Modular {
+	fields := map[string]string{}
+	for i := 0; i < len(keyValues)-1; i += 2 {
+		fields[fmt.Sprintf("%v", keyValues[i])] = fmt.Sprintf("%v", keyValues[i+1])
+	}
+	return c.withFields(fields, c.child.With(keyValues...))
+}
+
+func (c *capturingLogger) record(level, message string) {
+	c.rec.add(LogEntry{
+		Level:   level,
+		Message: message,
+		Fields:  c.fields,
+	})
+}
+
+func (c *capturingLogger) Fatalf(format string, v ...interface{}) {
+	c.record("FATAL", fmt.Sprintf(format, v...))
+	c.child.Fatalf(format, v...)
+}
+
+func (c *capturingLogger) Errorf(format string, v ...interface{}) {
+	c.record("ERROR", fmt.Sprintf(format, v...))
+	c.child.Errorf(format, v...)
+}
+
+func (c *capturingLogger) Warnf(format string, v ...interface{}) {
+	c.record("WARN", fmt.Sprintf(format, v...))
+	c.child.Warnf(format, v...)
+}
+
+func (c *capturingLogger) Infof(format string, v ...interface{}) {
+	c.record("INFO", fmt.Sprintf(format, v...))
+	c.child.Infof(format, v...)
+}
+
+func (c *capturingLogger) Debugf(format string, v ...interface{}) {
+	c.record("DEBUG", fmt.Sprintf(format, v...))
+	c.child.Debugf(format, v...)
+}
+
+func (c *capturingLogger) Tracef(format string, v ...interface{}) {
+	c.record("TRACE", fmt.Sprintf(format, v...))
+	c.child.Tracef(format, v...)
+}
+
+func (c *capturingLogger) Fatalln(message string) {
+	c.record("FATAL", message)
+	c.child.Fatalln(message)
+}
+
+func (c *capturingLogger) Errorln(message string) {
+	c.record("ERROR", message)
+	c.child.Errorln(message)
+}
+
+func (c *capturingLogger) Warnln(message string) {
+	c.record("WARN", message)
+	c.child.Warnln(message)
+}
+
+func (c *capturingLogger) Infoln(message string) {
+	c.record("INFO", message)
+	c.child.Infoln(message)
+}
+
+func (c *capturingLogger) Debugln(message string) {
+	c.record("DEBUG", message)
+	c.child.Debugln(message)
+}
+
+func (c *capturingLogger) Traceln(message string) {
+	c.record("TRACE", message)
+	c.child.Traceln(message)
+}
+
+//------------------------------------------------------------------------------
+
+// Observed contains the state observed from the components of a test case
+// after its execution, used for checking metric, log and resource conditions.
+type Observed struct {
+	metrics  *metrics.Local
+	logs     *logRecorder
+	mgr      *manager.Type
+	fixtures Fixtures
+}
+
+// Metrics returns a map of metric paths to the values of all counters and
+// gauges emitted during the test.
+func (o *Observed) Metrics() map[string]int64 {
+	return o.metrics.GetCounters()
+}
+
+// Logs returns all log lines written during the test.
+func (o *Observed) Logs() []LogEntry {
+	return o.logs.Entries()
+}
+
+// CheckFixtures verifies the expected state of all resource fixtures.
+func (o *Observed) CheckFixtures(dir string) []error {
+	errs := o.fixtures.checkCaches(dir, o.mgr)
+	return append(errs, o.fixtures.checkRateLimits(o.metrics.GetCounters())...)
+}
+
+// Close shuts down the resources of the test case.
+func (o *Observed) Close(timeout time.Duration) error {
+	o.mgr.CloseAsync()
+	return o.mgr.WaitForClose(timeout)
+}
@@ -0,0 +1,125 @@
+package test_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/cli/test"
+)
+
+func TestMetricConditions(t *testing.T) {
+	values := map[string]int64{
+		`foo`:                        3,
+		`bar{a="1",label="baz"}`:     5,
+		`bar{a="2",label="baz"}`:     7,
+		`bar{a="1",label="buz"}`:     11,
+		`barbar{a="1",label="baz"}`:  13,
+		`bar_total{a="1",label="x"}`: 17,
+	}
+
+	tests := []struct {
+		name   string
+		conf   string
+		errStr string
+	}{
+		{
+			name: "no labels",
+			conf: `{ name: foo, equals: 3 }`,
+		},
+		{
+			name: "sum of all labels",
+			conf: `{ name: bar, equals: 23 }`,
+		},
+		{
+			name: "subset of labels",
+			conf: `{ name: bar, labels: { a: "1" }, equals: 16 }`,
+		},
+		{
+			name: "all labels",
+			conf: `{ name: bar, labels: { a: "1", label: baz }, greater_than: 4, less_than: 6 }`,
+		},
+		{
+			name:   "missing metric",
+			conf:   `{ name: nope, equals: 1 }`,
+			errStr: "metric nope mismatch\n  expected: 1\n  received: 0",
+		},
+		{
+			name:   "not greater",
+			conf:   `{ name: bar, labels: { label: buz, a: "1" }, greater_than: 11 }`,
+			errStr: "metric bar{a=\"1\",label=\"buz\"} mismatch\n  expected: greater than 11\n  received: 11",
+		},
+		{
+			name:   "not less",
+			conf:   `{ name: foo, less_than: 2 }`,
+			errStr: "metric foo mismatch\n  expected: less than 2\n  received: 3",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var cond test.MetricCondition
+			require.NoError(t, yaml.Unmarshal([]byte(tc.conf), &cond))
+
+			err := cond.Check(values)
+			if tc.errStr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errStr)
+			}
+		})
+	}
+}
+
+func TestLogConditions(t *testing.T) {
+	entries := []test.LogEntry{
+		{Level: "INFO", Message: "hello world", Fields: map[string]string{"label": "foo"}},
+		{Level: "ERROR", Message: "failed to do a thing", Fields: map[string]string{"label": "bar", "id": "1"}},
+	}
+
+	tests := []struct {
+		name   string
+		conf   string
+		errStr string
+	}{
+		{
+			name: "level only",
+			conf: `{ level: error }`,
+		},
+		{
+			name: "message equals",
+			conf: `{ level: INFO, message_equals: hello world }`,
+		},
+		{
+			name: "message matches with fields",
+			conf: `{ message_matches: '^failed to', fields: { id: "1" } }`,
+		},
+		{
+			name:   "no match",
+			conf:   `{ level: INFO, fields: { label: bar } }`,
+			errStr: "no matching log line\n  expected: level: INFO, fields: map[label:bar]\n  received:\n    INFO: hello world map[label:foo]\n    ERROR: failed to do a thing map[id:1 label:bar]",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var cond test.LogCondition
+			require.NoError(t, yaml.Unmarshal([]byte(tc.conf), &cond))
+
+			err := cond.Check(entries)
+			if tc.errStr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.errStr)
+			}
+		})
+	}
+
+	var cond test.LogCondition
+	require.Error(t, yaml.Unmarshal([]byte(`{ nope: foo }`), &cond))
+	require.Error(t, yaml.Unmarshal([]byte(`{ message_matches: '(' }`), &cond))
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/parser"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/config"
 	"github.com/benthosdev/benthos/v4/internal/docs"
@@ -20,6 +21,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/manager"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/stream"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 )
 
@@ -76,7 +78,98 @@ func (p *ProcessorsProvider) Provide(jsonPtr string, environment map[string]stri
 	if err != nil {
 		return nil, err
 	}
-	return p.initProcs(confs)
+	procs, _, err := p.initProcs(confs)
+	return procs, err
+}
+
+// ProvideObserved attempts to extract an array of processors from a Benthos
+// config in the same way as Provide, but with resources replaced by fixtures,
+// and returns an Observed that captures the metrics and logs emitted by the
+// processors.
+func (p *ProcessorsProvider) ProvideObserved(jsonPtr string, environment map[string]string, mocks map[string]yaml.Node, fixtures Fixtures) ([]processor.V1, *Observed, error) {
+	confs, err := p.getConfs(jsonPtr, environment, mocks)
+	if err != nil {
+		return nil, nil, err
+	}
+	if confs.mgr, err = fixtures.applyTo(confs.mgr); err != nil {
+		return nil, nil, err
+	}
+
+	stats := metrics.NewLocal()
+	logger := newCapturingLogger(p.logger)
+
+	procs, mgr, err := p.initProcs(confs, manager.OptSetLogger(logger), manager.OptSetMetrics(metrics.NewNamespaced(stats)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return procs, &Observed{
+		metrics:  stats,
+		logs:     logger.rec,
+		mgr:      mgr,
+		fixtures: fixtures,
+	}, nil
+}
+
+// ProvideStream attempts to construct the entire stream of a Benthos config,
+// where the input and each target output are replaced with in-memory stand-ins
+// and resources are replaced by fixtures. Outputs are targeted either by a
+// label or a JSON Pointer.
+func (p *ProcessorsProvider) ProvideStream(environment map[string]string, mocks map[string]yaml.Node, fixtures Fixtures, outputs []string) (*TestStream, error) {
+	cleanupEnv := setEnvironment(environment)
+	defer cleanupEnv()
+
+	mgrConf, root, err := p.readConfig(p.targetPath, mocks)
+	if err != nil {
+		return nil, err
+	}
+	if mgrConf, err = fixtures.applyTo(mgrConf); err != nil {
+		return nil, err
+	}
+
+	labelsToPaths := map[string][]string{}
+	config.Spec().YAMLLabelsToPaths(docs.DeprecatedProvider, root, labelsToPaths, nil)
+
+	outputPipes := make(map[string]string, len(outputs))
+	for i, target := range outputs {
+		var pathSlice []string
+		if strings.HasPrefix(target, "/") {
+			if pathSlice, err = gabs.JSONPointerToSlice(target); err != nil {
+				return nil, fmt.Errorf("failed to parse stream output path '%v': %w", target, err)
+			}
+		} else {
+			var exists bool
+			if pathSlice, exists = labelsToPaths[target]; !exists {
+				return nil, fmt.Errorf("target for label '%v' failed as the label was not found in the test target file", target)
+			}
+		}
+		pipe := fmt.Sprintf("benthos_test_output_%v", i)
+		if err = replaceWithInproc(root, pipe, pathSlice...); err != nil {
+			return nil, fmt.Errorf("failed to replace stream output '%v': %w", target, err)
+		}
+		outputPipes[target] = pipe
+	}
+	if err = replaceWithInproc(root, testStreamInputPipe, "input"); err != nil {
+		return nil, fmt.Errorf("failed to replace stream input: %w", err)
+	}
+
+	streamConf := stream.NewConfig()
+	if err = root.Decode(&streamConf); err != nil {
+		return nil, fmt.Errorf("failed to parse stream from '%v': %v", p.targetPath, err)
+	}
+
+	stats := metrics.NewLocal()
+	logger := newCapturingLogger(p.logger)
+
+	mgr, err := manager.New(mgrConf, manager.OptSetLogger(logger), manager.OptSetMetrics(metrics.NewNamespaced(stats)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+	}
+	return newTestStream(streamConf, mgr, outputPipes, &Observed{
+		metrics:  stats,
+		logs:     logger.rec,
+		mgr:      mgr,
+		fixtures: fixtures,
+	})
 }
 
 // ProvideBloblang attempts to parse a Bloblang mapping and returns a processor
@@ -143,19 +236,19 @@ func (b *bloblangProc) Close(context.Context) error {
 
 //------------------------------------------------------------------------------
 
-func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]processor.V1, error) {
-	mgr, err := manager.New(confs.mgr, manager.OptSetLogger(p.logger))
+func (p *ProcessorsProvider) initProcs(confs cachedConfig, opts ...manager.OptFunc) ([]processor.V1, *manager.Type, error) {
+	mgr, err := manager.New(confs.mgr, append([]manager.OptFunc{manager.OptSetLogger(p.logger)}, opts...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+		return nil, nil, fmt.Errorf("failed to initialise resources: %v", err)
 	}
 
 	procs := make([]processor.V1, len(confs.procs))
 	for i, conf := range confs.procs {
 		if procs[i], err = mgr.NewProcessor(conf); err != nil {
-			return nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
+			return nil, nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
 		}
 	}
-	return procs, nil
+	return procs, mgr, nil
 }
 
 func confTargetID(jsonPtr string, environment map[string]string, mocks map[string]yaml.Node) string {
@@ -238,6 +331,50 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 	cleanupEnv := setEnvironment(environment)
 	defer cleanupEnv()
 
+	var root *yaml.Node
+	if confs.mgr, root, err = p.readConfig(targetPath, mocks); err != nil {
+		return confs, err
+	}
+
+	confSpec := config.Spec()
+	labelsToPaths := map[string][]string{}
+
+	var pathSlice []string
+	if strings.HasPrefix(procPath, "/") {
+		if pathSlice, err = gabs.JSONPointerToSlice(procPath); err != nil {
+			return confs, fmt.Errorf("failed to parse case processors path '%v': %w", procPath, err)
+		}
+	} else {
+		confSpec.YAMLLabelsToPaths(docs.DeprecatedProvider, root, labelsToPaths, nil)
+		if pathSlice, exists = labelsToPaths[procPath]; !exists {
+			return confs, fmt.Errorf("target for label '%v' failed as the label was not found in the test target file, it is not currently possible to target resources imported separate to the test file", procPath)
+		}
+	}
+
+	if root, err = docs.GetYAMLPath(root, pathSlice...); err != nil {
+		return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
+	}
+
+	if root.Kind == yaml.SequenceNode {
+		if err = root.Decode(&confs.procs); err != nil {
+			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
+		}
+	} else {
+		var procConf processor.Config
+		if err = root.Decode(&procConf); err != nil {
+			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
+		}
+		confs.procs = append(confs.procs, procConf)
+	}
+
+	p.cachedConfigs[cacheKey] = confs
+	return confs, nil
+}
+
+// readConfig parses a config file and any additional resource files, and then
+// replaces mocked components within the config. Returns the resources of the
+// config and the root node of the mocked config.
+func (p *ProcessorsProvider) readConfig(targetPath string, mocks map[string]yaml.Node) (mgrConf manager.ResourceConfig, root *yaml.Node, err error) {
 	remainingMocks := map[string]yaml.Node{}
 	for k, v := range mocks {
 		remainingMocks[k] = v
@@ -245,33 +382,31 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 
 	configBytes, _, err := config.ReadFileEnvSwap(targetPath)
 	if err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		return mgrConf, nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
-	mgrWrapper := manager.NewResourceConfig()
-	if err = yaml.Unmarshal(configBytes, &mgrWrapper); err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	mgrConf = manager.NewResourceConfig()
+	if err = yaml.Unmarshal(configBytes, &mgrConf); err != nil {
+		return mgrConf, nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
 	for _, path := range p.resourcesPaths {
 		resourceBytes, _, err := config.ReadFileEnvSwap(path)
 		if err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+			return mgrConf, nil, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
 		}
 		extraMgrWrapper := manager.NewResourceConfig()
 		if err = yaml.Unmarshal(resourceBytes, &extraMgrWrapper); err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+			return mgrConf, nil, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
 		}
-		if err = mgrWrapper.AddFrom(&extraMgrWrapper); err != nil {
-			return confs, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
+		if err = mgrConf.AddFrom(&extraMgrWrapper); err != nil {
+			return mgrConf, nil, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
 		}
 	}
 
-	confs.mgr = mgrWrapper
-
-	root := &yaml.Node{}
+	root = &yaml.Node{}
 	if err = yaml.Unmarshal(configBytes, root); err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		return mgrConf, nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
 	// Replace mock components, starting with all absolute paths in JSON pointer
@@ -283,10 +418,10 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		}
 		mockPathSlice, err := gabs.JSONPointerToSlice(k)
 		if err != nil {
-			return confs, fmt.Errorf("failed to parse mock path '%v': %w", k, err)
+			return mgrConf, nil, fmt.Errorf("failed to parse mock path '%v': %w", k, err)
 		}
 		if err = confSpec.SetYAMLPath(docs.DeprecatedProvider, root, &v, mockPathSlice...); err != nil {
-			return confs, fmt.Errorf("failed to set mock '%v': %w", k, err)
+			return mgrConf, nil, fmt.Errorf("failed to set mock '%v': %w", k, err)
 		}
 		delete(remainingMocks, k)
 	}
@@ -297,45 +432,14 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		for k, v := range remainingMocks {
 			mockPathSlice, exists := labelsToPaths[k]
 			if !exists {
-				return confs, fmt.Errorf("mock for label '%v' could not be applied as the label was not found in the test target file, it is not currently possible to mock resources imported separate to the test file", k)
+				return mgrConf, nil, fmt.Errorf("mock for label '%v' could not be applied as the label was not found in the test target file, it is not currently possible to mock resources imported separate to the test file", k)
 			}
 			if err = confSpec.SetYAMLPath(docs.DeprecatedProvider, root, &v, mockPathSlice...); err != nil {
-				return confs, fmt.Errorf("failed to set mock '%v': %w", k, err)
+				return mgrConf, nil, fmt.Errorf("failed to set mock '%v': %w", k, err)
 			}
 			delete(remainingMocks, k)
 		}
 	}
 
-	var pathSlice []string
-	if strings.HasPrefix(procPath, "/") {
-		if pathSlice, err = gabs.JSONPointerToSlice(procPath); err != nil {
-			return confs, fmt.Errorf("failed to parse case processors path '%v': %w", procPath, err)
-		}
-	} else {
-		if len(labelsToPaths) == 0 {
-			confSpec.YAMLLabelsToPaths(docs.DeprecatedProvider, root, labelsToPaths, nil)
-		}
-		if pathSlice, exists = labelsToPaths[procPath]; !exists {
-			return confs, fmt.Errorf("target for label '%v' failed as the label was not found in the test target file, it is not currently possible to target resources imported separate to the test file", procPath)
-		}
-	}
-
-	if root, err = docs.GetYAMLPath(root, pathSlice...); err != nil {
-		return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
-	}
-
-	if root.Kind == yaml.SequenceNode {
-		if err = root.Decode(&confs.procs); err != nil {
-			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
-		}
-	} else {
-		var procConf processor.Config
-		if err = root.Decode(&procConf); err != nil {
-			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
-		}
-		confs.procs = append(confs.procs, procConf)
-	}
-
-	p.cachedConfigs[cacheKey] = confs
-	return confs, nil
+	return mgrConf, root, nil
 }
@@ -0,0 +1,333 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Formats supported for reporting the results of tests.
+const (
+	FormatDefault = "default"
+	FormatJUnit   = "junit"
+	FormatTAP     = "tap"
+	FormatJSON    = "json"
+)
+
+// Formats returns the names of all supported report formats.
+func Formats() []string {
+	return []string{FormatDefault, FormatJUnit, FormatTAP, FormatJSON}
+}
+
+// TargetResult contains the results of executing the test definition of a
+// single config file.
+type TargetResult struct {
+	Path  string
+	Lints []string
+	Cases []CaseResult
+}
+
+// Failed returns true if the target has lint errors or failed test cases.
+func (t TargetResult) Failed() bool {
+	if len(t.Lints) > 0 {
+		return true
+	}
+	for _, c := range t.Cases {
+		if len(c.Failures) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport writes the results of tests to a writer in a given format, which
+// must be either junit, tap or json.
+func WriteReport(w io.Writer, format string, results []TargetResult) error {
+	switch format {
+	case FormatJUnit:
+		return writeJUnitReport(w, results)
+	case FormatTAP:
+		return writeTAPReport(w, results)
+	case FormatJSON:
+		return writeJSONReport(w, results)
+	}
+	return fmt.Errorf("report format not recognised: %v", format)
+}
+
+//------------------------------------------------------------------------------
+
+var ansiEscapeRegexp = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Conditions such as json_equals produce coloured diffs regardless of whether
+// colours are enabled, which would corrupt a report.
+func stripANSI(s string) string {
+	return ansiEscapeRegexp.ReplaceAllString(s, "")
+}
+
+type failureReport struct {
+	Reason    string `json:"reason" yaml:"reason"`
+	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Diff      string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+func newFailureReports(failures []CaseFailure) []failureReport {
+	reports := make([]failureReport, len(failures))
+	for i, f := range failures {
+		reports[i] = failureReport{
+			Reason:    stripANSI(f.Reason),
+			Condition: f.Condition,
+			Diff:      stripANSI(f.Diff),
+		}
+	}
+	return reports
+}
+
+func lintFailureReports(lints []string) []failureReport {
+	reports := make([]failureReport, len(lints))
+	for i, l := range lints {
+		reports[i] = failureReport{
+			Reason:    l,
+			Condition: "lint",
+		}
+	}
+	return reports
+}
+
+func durationSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+//------------------------------------------------------------------------------
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func newJUnitFailures(reports []failureReport) []junitFailure {
+	failures := make([]junitFailure, len(reports))
+	for i, r := range reports {
+		message := r.Reason
+		if idx := strings.Index(message, "\n"); idx >= 0 {
+			message = message[:idx]
+		}
+		failures[i] = junitFailure{
+			Message: message,
+			Type:    r.Condition,
+			Text:    r.Reason,
+		}
+	}
+	return failures
+}
+
+func writeJUnitReport(w io.Writer, results []TargetResult) error {
+	var totalCases, totalFailures int
+	var totalDuration time.Duration
+
+	suites := junitTestSuites{Name: "benthos"}
+	for _, t := range results {
+		suite := junitTestSuite{Name: t.Path}
+
+		var suiteDuration time.Duration
+		if len(t.Lints) > 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "lint",
+				ClassName: t.Path,
+				Time:      durationSeconds(0),
+				Failures:  newJUnitFailures(lintFailureReports(t.Lints)),
+			})
+			suite.Failures++
+		}
+		for _, c := range t.Cases {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      c.Name,
+				ClassName: t.Path,
+				Time:      durationSeconds(c.Duration),
+				Failures:  newJUnitFailures(newFailureReports(c.Failures)),
+			})
+			if len(c.Failures) > 0 {
+				suite.Failures++
+			}
+			suiteDuration += c.Duration
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Time = durationSeconds(suiteDuration)
+
+		totalCases += suite.Tests
+		totalFailures += suite.Failures
+		totalDuration += suiteDuration
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Tests = totalCases
+	suites.Failures = totalFailures
+	suites.Time = durationSeconds(totalDuration)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+type tapDiagnostic struct {
+	Line       int             `yaml:"line,omitempty"`
+	DurationMS float64         `yaml:"duration_ms"`
+	Failures   []failureReport `yaml:"failures,omitempty"`
+}
+
+func tapDescription(path, name string) string {
+	return strings.ReplaceAll(path+": "+name, "#", "\\#")
+}
+
+func writeTAPReport(w io.Writer, results []TargetResult) error {
+	var lines []string
+	var points int
+
+	addPoint := func(description string, diag tapDiagnostic) error {
+		points++
+		status := "ok"
+		if len(diag.Failures) > 0 {
+			status = "not ok"
+		}
+		lines = append(lines, fmt.Sprintf("%v %v - %v", status, points, description))
+
+		var diagBuf bytes.Buffer
+		enc := yaml.NewEncoder(&diagBuf)
+		enc.SetIndent(2)
+		if err := enc.Encode(diag); err != nil {
+			return err
+		}
+		lines = append(lines, "  ---")
+		for _, l := range strings.Split(strings.TrimSuffix(diagBuf.String(), "\n"), "\n") {
+			lines = append(lines, "  "+l)
+		}
+		lines = append(lines, "  ...")
+		return nil
+	}
+
+	for _, t := range results {
+		if len(t.Lints) > 0 {
+			if err := addPoint(tapDescription(t.Path, "lint"), tapDiagnostic{
+				Failures: lintFailureReports(t.Lints),
+			}); err != nil {
+				return err
+			}
+		}
+		for _, c := range t.Cases {
+			if err := addPoint(tapDescription(t.Path, c.Name), tapDiagnostic{
+				Line:       c.TestLine,
+				DurationMS: durationMillis(c.Duration),
+				Failures:   newFailureReports(c.Failures),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "TAP version 13\n1..%v\n", points); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type jsonReport struct {
+	Passed  bool               `json:"passed"`
+	Targets []jsonTargetReport `json:"targets"`
+}
+
+type jsonTargetReport struct {
+	Path   string           `json:"path"`
+	Passed bool             `json:"passed"`
+	Lints  []string         `json:"lints"`
+	Cases  []jsonCaseReport `json:"cases"`
+}
+
+type jsonCaseReport struct {
+	Name       string          `json:"name"`
+	Line       int             `json:"line"`
+	Passed     bool            `json:"passed"`
+	DurationMS float64         `json:"duration_ms"`
+	Failures   []failureReport `json:"failures"`
+}
+
+func writeJSONReport(w io.Writer, results []TargetResult) error {
+	report := jsonReport{
+		Passed:  true,
+		Targets: make([]jsonTargetReport, 0, len(results)),
+	}
+	for _, t := range results {
+		target := jsonTargetReport{
+			Path:   t.Path,
+			Passed: !t.Failed(),
+			Lints:  append([]string{}, t.Lints...),
+			Cases:  make([]jsonCaseReport, 0, len(t.Cases)),
+		}
+		for _, c := range t.Cases {
+			target.Cases = append(target.Cases, jsonCaseReport{
+				Name:       c.Name,
+				Line:       c.TestLine,
+				Passed:     len(c.Failures) == 0,
+				DurationMS: durationMillis(c.Duration),
+				Failures:   newFailureReports(c.Failures),
+			})
+		}
+		if !target.Passed {
+			report.Passed = false
+		}
+		report.Targets = append(report.Targets, target)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,206 @@
+package test_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/cli/test"
+)
+
+func testReportResults() []test.TargetResult {
+	return []test.TargetResult{
+		{
+			Path: "foo.yaml",
+			Cases: []test.CaseResult{
+				{
+					Name:     "passes",
+					TestLine: 2,
+					Duration: time.Millisecond * 5,
+				},
+				{
+					Name:     "fails",
+					TestLine: 10,
+					Duration: time.Millisecond * 10,
+					Failures: []test.CaseFailure{
+						{
+							Name:      "fails",
+							TestLine:  10,
+							Reason:    "batch 0 message 0: content_equals: content mismatch\n  expected: foo\n  received: bar",
+							Condition: "content_equals",
+							Diff:      "content mismatch\n  expected: foo\n  received: bar",
+						},
+						{
+							Name:     "fails",
+							TestLine: 10,
+							Reason:   "wrong batch count, expected 2, got 1",
+						},
+					},
+				},
+			},
+		},
+		{
+			Path:  "bar.yaml",
+			Lints: []string{"line 2: field meow not recognised"},
+			Cases: []test.CaseResult{
+				{
+					Name:     "passes # too",
+					TestLine: 2,
+					Duration: time.Millisecond,
+				},
+			},
+		},
+	}
+}
+
+func TestReportJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, test.WriteReport(&buf, test.FormatJUnit, testReportResults()))
+
+	var report struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name  string `xml:"name,attr"`
+			Cases []struct {
+				Name     string `xml:"name,attr"`
+				Time     string `xml:"time,attr"`
+				Failures []struct {
+					Message string `xml:"message,attr"`
+					Type    string `xml:"type,attr"`
+					Text    string `xml:",chardata"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+
+	assert.Equal(t, 4, report.Tests)
+	assert.Equal(t, 2, report.Failures)
+	require.Len(t, report.Suites, 2)
+
+	foo := report.Suites[0]
+	assert.Equal(t, "foo.yaml", foo.Name)
+	require.Len(t, foo.Cases, 2)
+	assert.Equal(t, "passes", foo.Cases[0].Name)
+	assert.Equal(t, "0.005", foo.Cases[0].Time)
+	assert.Empty(t, foo.Cases[0].Failures)
+
+	require.Len(t, foo.Cases[1].Failures, 2)
+	assert.Equal(t, "batch 0 message 0: content_equals: content mismatch", foo.Cases[1].Failures[0].Message)
+	assert.Equal(t, "content_equals", foo.Cases[1].Failures[0].Type)
+	assert.Equal(t, "batch 0 message 0: content_equals: content mismatch\n  expected: foo\n  received: bar", foo.Cases[1].Failures[0].Text)
+	assert.Equal(t, "", foo.Cases[1].Failures[1].Type)
+
+	bar := report.Suites[1]
+	require.Len(t, bar.Cases, 2)
+	assert.Equal(t, "lint", bar.Cases[0].Name)
+	require.Len(t, bar.Cases[0].Failures, 1)
+	assert.Equal(t, "lint", bar.Cases[0].Failures[0].Type)
+}
+
+func TestReportTAP(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, test.WriteReport(&buf, test.FormatTAP, testReportResults()))
+
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "TAP version 13", lines[0])
+	assert.Equal(t, "1..4", lines[1])
+	assert.Equal(t, "ok 1 - foo.yaml: passes", lines[2])
+
+	require.Greater(t, len(lines), 9)
+	assert.Equal(t, "  ---", lines[3])
+	assert.Equal(t, "  line: 2", lines[4])
+	assert.Equal(t, "  duration_ms: 5", lines[5])
+	assert.Equal(t, "  ...", lines[6])
+	assert.Equal(t, "not ok 2 - foo.yaml: fails", lines[7])
+	assert.Equal(t, "  ---", lines[8])
+
+	var diagLines []string
+	for _, l := range lines[9:] {
+		if l == "  ..." {
+			break
+		}
+		diagLines = append(diagLines, strings.TrimPrefix(l, "  "))
+	}
+
+	var diag struct {
+		Line       int     `yaml:"line"`
+		DurationMS float64 `yaml:"duration_ms"`
+		Failures   []struct {
+			Reason    string `yaml:"reason"`
+			Condition string `yaml:"condition"`
+			Diff      string `yaml:"diff"`
+		} `yaml:"failures"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(strings.Join(diagLines, "\n")), &diag))
+	assert.Equal(t, 10, diag.Line)
+	assert.Equal(t, float64(10), diag.DurationMS)
+	require.Len(t, diag.Failures, 2)
+	assert.Equal(t, "batch 0 message 0: content_equals: content mismatch\n  expected: foo\n  received: bar", diag.Failures[0].Reason)
+	assert.Equal(t, "content_equals", diag.Failures[0].Condition)
+	assert.Equal(t, "content mismatch\n  expected: foo\n  received: bar", diag.Failures[0].Diff)
+	assert.Equal(t, "wrong batch count, expected 2, got 1", diag.Failures[1].Reason)
+	assert.Equal(t, "", diag.Failures[1].Condition)
+
+	assert.Contains(t, buf.String(), "not ok 3 - bar.yaml: lint\n")
+	assert.Contains(t, buf.String(), "ok 4 - bar.yaml: passes \\# too\n")
+}
+
+func TestReportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, test.WriteReport(&buf, test.FormatJSON, testReportResults()))
+
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+
+	assert.Equal(t, false, report["passed"])
+
+	targets := report["targets"].([]interface{})
+	require.Len(t, targets, 2)
+
+	foo := targets[0].(map[string]interface{})
+	assert.Equal(t, "foo.yaml", foo["path"])
+	assert.Equal(t, false, foo["passed"])
+
+	cases := foo["cases"].([]interface{})
+	require.Len(t, cases, 2)
+	assert.Equal(t, map[string]interface{}{
+		"name":        "passes",
+		"line":        float64(2),
+		"passed":      true,
+		"duration_ms": float64(5),
+		"failures":    []interface{}{},
+	}, cases[0])
+	assert.Equal(t, map[string]interface{}{
+		"name":        "fails",
+		"line":        float64(10),
+		"passed":      false,
+		"duration_ms": float64(10),
+		"failures": []interface{}{
+			map[string]interface{}{
+				"reason":    "batch 0 message 0: content_equals: content mismatch\n  expected: foo\n  received: bar",
+				"condition": "content_equals",
+				"diff":      "content mismatch\n  expected: foo\n  received: bar",
+			},
+			map[string]interface{}{
+				"reason": "wrong batch count, expected 2, got 1",
+			},
+		},
+	}, cases[1])
+
+	bar := targets[1].(map[string]interface{})
+	assert.Equal(t, []interface{}{"line 2: field meow not recognised"}, bar["lints"])
+}
+
+func TestReportUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	require.Error(t, test.WriteReport(&buf, "nope", testReportResults()))
+}
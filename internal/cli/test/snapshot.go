@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+const snapshotHeader = "# This file is generated by `benthos test --update-snapshots` and should not be edited by hand.\n"
+
+// snapshotPart is the content and metadata of a message recorded within a
+// snapshot file.
+type snapshotPart struct {
+	Content  string            `yaml:"content"`
+	Metadata map[string]string `yaml:"metadata,omitempty"`
+}
+
+func newSnapshot(batches []*message.Batch) [][]snapshotPart {
+	snapshot := make([][]snapshotPart, len(batches))
+	for i, b := range batches {
+		parts := make([]snapshotPart, b.Len())
+		_ = b.Iter(func(j int, p *message.Part) error {
+			parts[j].Content = string(p.Get())
+			_ = p.MetaIter(func(k, v string) error {
+				if parts[j].Metadata == nil {
+					parts[j].Metadata = map[string]string{}
+				}
+				parts[j].Metadata[k] = v
+				return nil
+			})
+			return nil
+		})
+		snapshot[i] = parts
+	}
+	return snapshot
+}
+
+func writeSnapshot(path string, batches []*message.Batch) error {
+	var buf bytes.Buffer
+	buf.WriteString(snapshotHeader)
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(newSnapshot(batches)); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func readSnapshot(path string) ([][]snapshotPart, error) {
+	snapBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot [][]snapshotPart
+	if err := yaml.Unmarshal(snapBytes, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot file '%v': %v", path, err)
+	}
+	return snapshot, nil
+}
+
+// checkSnapshot compares the output batches of a test against a snapshot, and
+// returns an error for each difference found.
+func checkSnapshot(expected, actual [][]snapshotPart) (errs []error) {
+	if lExp, lAct := len(expected), len(actual); lExp != lAct {
+		errs = append(errs, fmt.Errorf("wrong batch count, expected %v, got %v", lExp, lAct))
+	}
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		expBatch, actBatch := expected[i], actual[i]
+		if lExp, lAct := len(expBatch), len(actBatch); lExp != lAct {
+			errs = append(errs, fmt.Errorf("mismatch of batch %v message counts, expected %v, got %v", i, lExp, lAct))
+		}
+		for j := 0; j < len(expBatch) && j < len(actBatch); j++ {
+			errs = append(errs, checkSnapshotPart(i, j, expBatch[j], actBatch[j])...)
+		}
+	}
+	return
+}
+
+func checkSnapshotPart(batchIndex, index int, expected, actual snapshotPart) (errs []error) {
+	if expected.Content != actual.Content {
+		errs = append(errs, fmt.Errorf("batch %v message %v: content mismatch\n  expected: %v\n  received: %v", batchIndex, index, blue(expected.Content), red(actual.Content)))
+	}
+
+	keys := make([]string, 0, len(expected.Metadata)+len(actual.Metadata))
+	for k := range expected.Metadata {
+		keys = append(keys, k)
+	}
+	for k := range actual.Metadata {
+		if _, exists := expected.Metadata[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		expV, expExists := expected.Metadata[k]
+		actV, actExists := actual.Metadata[k]
+		switch {
+		case !actExists:
+			errs = append(errs, fmt.Errorf("batch %v message %v: metadata key '%v' missing\n  expected: %v", batchIndex, index, k, blue(expV)))
+		case !expExists:
+			errs = append(errs, fmt.Errorf("batch %v message %v: unexpected metadata key '%v'\n  received: %v", batchIndex, index, k, red(actV)))
+		case expV != actV:
+			errs = append(errs, fmt.Errorf("batch %v message %v: metadata key '%v' mismatch\n  expected: %v\n  received: %v", batchIndex, index, k, blue(expV), red(actV)))
+		}
+	}
+	return
+}
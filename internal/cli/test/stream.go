@@ -0,0 +1,191 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+)
+
+const testStreamInputPipe = "benthos_test_input"
+
+// errStreamOutputFailure is the error returned to a stream when a stand-in
+// output is configured to fail delivery attempts.
+var errStreamOutputFailure = errors.New("delivery attempt failed by test")
+
+// StreamOutput defines the expected behaviour of an output of a stream under
+// test, which is replaced with an in-memory stand-in.
+type StreamOutput struct {
+	FailAttempts  int               `yaml:"fail_attempts"`
+	OutputBatches [][]ConditionsMap `yaml:"output_batches"`
+}
+
+// StreamResult contains the batches observed during the execution of a
+// TestStream.
+type StreamResult struct {
+	// Outputs contains the batches successfully delivered to each target
+	// output, in the order that they were delivered.
+	Outputs map[string][]*message.Batch
+
+	// Rejected contains each input batch that was acknowledged by the stream
+	// with an error.
+	Rejected []*message.Batch
+}
+
+// TestStream is a stream constructed from a Benthos config for the purpose of
+// a test, where the input and any target outputs have been replaced with
+// in-memory stand-ins.
+type TestStream struct {
+	strm        *stream.Type
+	mgr         bundle.NewManagement
+	input       chan message.Transaction
+	outputPipes map[string]string
+	observed    *Observed
+}
+
+func newTestStream(conf stream.Config, mgr bundle.NewManagement, outputPipes map[string]string, observed *Observed) (*TestStream, error) {
+	// The inproc input attempts to connect to its pipe as soon as it is
+	// created, and therefore the pipe needs to exist before the stream does.
+	input := make(chan message.Transaction)
+	mgr.SetPipe(testStreamInputPipe, input)
+
+	strm, err := stream.New(conf, mgr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise stream: %v", err)
+	}
+	return &TestStream{
+		strm:        strm,
+		mgr:         mgr,
+		input:       input,
+		outputPipes: outputPipes,
+		observed:    observed,
+	}, nil
+}
+
+// Observed returns the metrics, logs and fixtures of the stream.
+func (s *TestStream) Observed() *Observed {
+	return s.observed
+}
+
+// Run feeds each batch into the stream in turn, waiting for each to be
+// acknowledged before the next is sent, and then shuts the stream down. The
+// first N delivery attempts of each target output are failed according to the
+// failAttempts map. The timeout applies to the delivery of each batch as well
+// as the shutdown of the stream.
+func (s *TestStream) Run(batches []*message.Batch, failAttempts map[string]int, timeout time.Duration) (res StreamResult, err error) {
+	// Collectors append to their own map as they may still be running after a
+	// timeout, in which case the result holds a copy of what was collected.
+	outputs := make(map[string][]*message.Batch, len(s.outputPipes))
+
+	var resMut sync.Mutex
+	var wg sync.WaitGroup
+	for target, pipe := range s.outputPipes {
+		tChan, err := s.mgr.GetPipe(pipe)
+		if err != nil {
+			_ = s.strm.Stop(timeout)
+			return res, fmt.Errorf("failed to connect to output '%v': %v", target, err)
+		}
+		outputs[target] = nil
+
+		wg.Add(1)
+		go func(target string, tChan <-chan message.Transaction, remainingFails int) {
+			defer wg.Done()
+			for tran := range tChan {
+				if remainingFails > 0 {
+					remainingFails--
+					_ = tran.Ack(context.Background(), errStreamOutputFailure)
+					continue
+				}
+				resMut.Lock()
+				outputs[target] = append(outputs[target], tran.Payload.Copy())
+				resMut.Unlock()
+				_ = tran.Ack(context.Background(), nil)
+			}
+		}(target, tChan, failAttempts[target])
+	}
+
+	for i, b := range batches {
+		resChan := make(chan error)
+		select {
+		case s.input <- message.NewTransaction(b.DeepCopy(), resChan):
+		case <-time.After(timeout):
+			err = fmt.Errorf("timed out sending input batch %v to the stream", i)
+		}
+		if err != nil {
+			break
+		}
+		select {
+		case ackErr := <-resChan:
+			if ackErr != nil {
+				res.Rejected = append(res.Rejected, b)
+			}
+		case <-time.After(timeout):
+			err = fmt.Errorf("timed out waiting for input batch %v to be acknowledged", i)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if stopErr := s.strm.Stop(timeout); stopErr != nil && err == nil {
+		err = fmt.Errorf("failed to stop stream: %v", stopErr)
+	}
+
+	collectorsDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(collectorsDone)
+	}()
+	select {
+	case <-collectorsDone:
+	case <-time.After(timeout):
+		if err == nil {
+			err = errors.New("timed out waiting for stream outputs to close")
+		}
+	}
+
+	resMut.Lock()
+	res.Outputs = make(map[string][]*message.Batch, len(outputs))
+	for target, batches := range outputs {
+		res.Outputs[target] = append([]*message.Batch(nil), batches...)
+	}
+	resMut.Unlock()
+	return res, err
+}
+
+//------------------------------------------------------------------------------
+
+// replaceWithInproc replaces an input or output config within a YAML node with
+// an inproc config of the same label and processors.
+func replaceWithInproc(root *yaml.Node, pipe string, path ...string) error {
+	target, err := docs.GetYAMLPath(root, path...)
+	if err != nil {
+		return err
+	}
+	if target.Kind != yaml.MappingNode {
+		return fmt.Errorf("expected object value, got %v", target.Tag)
+	}
+
+	standIn := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < len(target.Content)-1; i += 2 {
+		switch target.Content[i].Value {
+		case "label", "processors":
+			standIn.Content = append(standIn.Content, target.Content[i], target.Content[i+1])
+		}
+	}
+	standIn.Content = append(standIn.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "inproc"},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: pipe},
+	)
+
+	*target = *standIn
+	return nil
+}
@@ -8,8 +8,12 @@ import (
 // queue needs to be declared and bound to an exchange, as well as any fields
 // specifying how to accomplish that.
 type AMQP09QueueDeclareConfig struct {
-	Enabled bool `json:"enabled" yaml:"enabled"`
-	Durable bool `json:"durable" yaml:"durable"`
+	Enabled              bool   `json:"enabled" yaml:"enabled"`
+	Durable              bool   `json:"durable" yaml:"durable"`
+	Type                 string `json:"type" yaml:"type"`
+	DeadLetterExchange   string `json:"dead_letter_exchange" yaml:"dead_letter_exchange"`
+	DeadLetterRoutingKey string `json:"dead_letter_routing_key" yaml:"dead_letter_routing_key"`
+	DeliveryLimit        int    `json:"delivery_limit" yaml:"delivery_limit"`
 }
 
 // AMQP09BindingConfig contains fields describing a queue binding to be
@@ -39,8 +43,12 @@ func NewAMQP09Config() AMQP09Config {
 		URLs:  []string{},
 		Queue: "",
 		QueueDeclare: AMQP09QueueDeclareConfig{
-			Enabled: false,
-			Durable: true,
+			Enabled:              false,
+			Durable:              true,
+			Type:                 "classic",
+			DeadLetterExchange:   "",
+			DeadLetterRoutingKey: "",
+			DeliveryLimit:        0,
 		},
 		ConsumerTag:        "",
 		AutoAck:            false,
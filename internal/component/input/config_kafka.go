@@ -23,6 +23,28 @@ func NewKafkaBalancedGroupConfig() KafkaBalancedGroupConfig {
 	}
 }
 
+// KafkaStartOffsetConfig contains config fields for the offset that a Kafka
+// consumer group starts consuming from.
+type KafkaStartOffsetConfig struct {
+	Type             string           `json:"type" yaml:"type"`
+	Relative         int64            `json:"relative" yaml:"relative"`
+	Timestamp        string           `json:"timestamp" yaml:"timestamp"`
+	PartitionOffsets map[string]int64 `json:"partition_offsets" yaml:"partition_offsets"`
+	Force            bool             `json:"force" yaml:"force"`
+}
+
+// NewKafkaStartOffsetConfig returns a KafkaStartOffsetConfig with default
+// values.
+func NewKafkaStartOffsetConfig() KafkaStartOffsetConfig {
+	return KafkaStartOffsetConfig{
+		Type:             "none",
+		Relative:         0,
+		Timestamp:        "",
+		PartitionOffsets: map[string]int64{},
+		Force:            false,
+	}
+}
+
 // KafkaConfig contains configuration fields for the Kafka input type.
 type KafkaConfig struct {
 	Addresses           []string                 `json:"addresses" yaml:"addresses"`
@@ -37,6 +59,7 @@ type KafkaConfig struct {
 	MaxProcessingPeriod string                   `json:"max_processing_period" yaml:"max_processing_period"`
 	FetchBufferCap      int                      `json:"fetch_buffer_cap" yaml:"fetch_buffer_cap"`
 	StartFromOldest     bool                     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	StartOffset         KafkaStartOffsetConfig   `json:"start_offset" yaml:"start_offset"`
 	TargetVersion       string                   `json:"target_version" yaml:"target_version"`
 	TLS                 btls.Config              `json:"tls" yaml:"tls"`
 	SASL                sasl.Config              `json:"sasl" yaml:"sasl"`
@@ -57,6 +80,7 @@ func NewKafkaConfig() KafkaConfig {
 		MaxProcessingPeriod: "100ms",
 		FetchBufferCap:      256,
 		StartFromOldest:     true,
+		StartOffset:         NewKafkaStartOffsetConfig(),
 		TargetVersion:       "2.0.0",
 		TLS:                 btls.NewConfig(),
 		SASL:                sasl.NewConfig(),
@@ -139,10 +139,14 @@ func createLabelledPath(name string, tagNames, tagValues []string) string {
 		for k, v := range tagNames {
 			tags[v] = tagValues[k]
 		}
-		sort.Strings(tagNames)
+
+		// Sort a copy as the names are shared by each call to a vec.
+		sortedNames := make([]string, len(tagNames))
+		copy(sortedNames, tagNames)
+		sort.Strings(sortedNames)
 
 		b.WriteByte('{')
-		for i, v := range tagNames {
+		for i, v := range sortedNames {
 			if i > 0 {
 				b.WriteString(tagEncodingSeparator)
 			}
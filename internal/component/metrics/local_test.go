@@ -90,6 +90,20 @@ func TestCounter(t *testing.T) {
 	assert.Equal(t, expTimingAvgs, actTimingAvgs)
 }
 
+func TestCounterVecUnsortedLabels(t *testing.T) {
+	nm := NewLocal()
+
+	ctr := nm.GetCounterVec("counter", "label2", "label1")
+	ctr.With("value2", "value1").Incr(1)
+	ctr.With("value2", "value1").Incr(2)
+	ctr.With("value4", "value3").Incr(3)
+
+	assert.Equal(t, map[string]int64{
+		`counter{label1="value1",label2="value2"}`: 3,
+		`counter{label1="value3",label2="value4"}`: 3,
+	}, nm.GetCounters())
+}
+
 func TestReverseName(t *testing.T) {
 	tests := map[string]struct {
 		input     string
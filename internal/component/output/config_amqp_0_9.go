@@ -14,12 +14,27 @@ type AMQPExchangeDeclareConfig struct {
 	Durable bool   `json:"durable" yaml:"durable"`
 }
 
+// AMQPQueueDeclareConfig contains fields indicating whether a queue needs to be
+// declared and bound to the target exchange, as well as any fields specifying
+// how to accomplish that.
+type AMQPQueueDeclareConfig struct {
+	Enabled              bool     `json:"enabled" yaml:"enabled"`
+	Queue                string   `json:"queue" yaml:"queue"`
+	Durable              bool     `json:"durable" yaml:"durable"`
+	Type                 string   `json:"type" yaml:"type"`
+	DeadLetterExchange   string   `json:"dead_letter_exchange" yaml:"dead_letter_exchange"`
+	DeadLetterRoutingKey string   `json:"dead_letter_routing_key" yaml:"dead_letter_routing_key"`
+	DeliveryLimit        int      `json:"delivery_limit" yaml:"delivery_limit"`
+	BindingKeys          []string `json:"binding_keys" yaml:"binding_keys"`
+}
+
 // AMQPConfig contains configuration fields for the AMQP output type.
 type AMQPConfig struct {
 	URLs            []string                     `json:"urls" yaml:"urls"`
 	MaxInFlight     int                          `json:"max_in_flight" yaml:"max_in_flight"`
 	Exchange        string                       `json:"exchange" yaml:"exchange"`
 	ExchangeDeclare AMQPExchangeDeclareConfig    `json:"exchange_declare" yaml:"exchange_declare"`
+	QueueDeclare    AMQPQueueDeclareConfig       `json:"queue_declare" yaml:"queue_declare"`
 	BindingKey      string                       `json:"key" yaml:"key"`
 	Type            string                       `json:"type" yaml:"type"`
 	ContentType     string                       `json:"content_type" yaml:"content_type"`
@@ -43,6 +58,16 @@ func NewAMQPConfig() AMQPConfig {
 			Type:    "direct",
 			Durable: true,
 		},
+		QueueDeclare: AMQPQueueDeclareConfig{
+			Enabled:              false,
+			Queue:                "",
+			Durable:              true,
+			Type:                 "classic",
+			DeadLetterExchange:   "",
+			DeadLetterRoutingKey: "",
+			DeliveryLimit:        0,
+			BindingKeys:          []string{},
+		},
 		BindingKey:      "",
 		Type:            "",
 		ContentType:     "application/octet-stream",
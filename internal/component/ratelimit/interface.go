@@ -18,3 +18,13 @@ type V1 interface {
 	// is cancelled.
 	Close(ctx context.Context) error
 }
+
+// Keyed is an optional interface implemented by rate limits that are able to
+// track a separate limit for each of an arbitrary number of keys, such as one
+// per tenant.
+type Keyed interface {
+	// AccessKey accesses the rate limited resource identified by a key, where
+	// each key is limited independently of all others. The returned duration
+	// follows the same semantics as Access.
+	AccessKey(ctx context.Context, key string) (time.Duration, error)
+}
@@ -38,6 +38,24 @@ func (r *metricsRateLimit) Access(ctx context.Context) (time.Duration, error) {
 	return tout, err
 }
 
+// AccessKey accesses the rate limit for a given key when the underlying rate
+// limit implements Keyed, otherwise the key is ignored and the shared limit is
+// accessed instead.
+func (r *metricsRateLimit) AccessKey(ctx context.Context, key string) (time.Duration, error) {
+	kr, ok := r.r.(Keyed)
+	if !ok {
+		return r.Access(ctx)
+	}
+	r.mChecked.Incr(1)
+	tout, err := kr.AccessKey(ctx, key)
+	if err != nil {
+		r.mErr.Incr(1)
+	} else if tout > 0 {
+		r.mLimited.Incr(1)
+	}
+	return tout, err
+}
+
 func (r *metricsRateLimit) Close(ctx context.Context) error {
 	return r.r.Close(ctx)
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 )
@@ -31,3 +32,41 @@ func TestRateLimitAirGapShutdown(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, rl.closed)
 }
+
+type keyedRateLimit struct {
+	keys []string
+}
+
+func (k *keyedRateLimit) Access(ctx context.Context) (time.Duration, error) {
+	return time.Second, nil
+}
+
+func (k *keyedRateLimit) AccessKey(ctx context.Context, key string) (time.Duration, error) {
+	k.keys = append(k.keys, key)
+	return 0, nil
+}
+
+func (k *keyedRateLimit) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestRateLimitAirGapKeyed(t *testing.T) {
+	ctx := context.Background()
+
+	rl := &keyedRateLimit{}
+	agrl, ok := MetricsForRateLimit(rl, metrics.Noop()).(Keyed)
+	require.True(t, ok)
+
+	tout, err := agrl.AccessKey(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), tout)
+	assert.Equal(t, []string{"foo"}, rl.keys)
+
+	// Rate limits that aren't keyed fall back to the shared limit.
+	agrl, ok = MetricsForRateLimit(&closableRateLimit{}, metrics.Noop()).(Keyed)
+	require.True(t, ok)
+
+	tout, err = agrl.AccessKey(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), tout)
+}
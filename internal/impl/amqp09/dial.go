@@ -0,0 +1,56 @@
+package amqp09
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// reDialAMQP09 connects to the first of one or more fallback URLs that accepts
+// a connection.
+func reDialAMQP09(urls []string, tlsConf *tls.Config) (conn *amqp.Connection, err error) {
+	for _, u := range urls {
+		conn, err = dialAMQP09(u, tlsConf)
+		if err != nil {
+			if errors.Is(err, errAMQP09Connect) {
+				continue
+			}
+			break
+		}
+		return conn, nil
+	}
+	return nil, err
+}
+
+// dialAMQP09 attempts to connect to an AMQP URL, using TLS when a config is
+// provided.
+func dialAMQP09(amqpURL string, tlsConf *tls.Config) (conn *amqp.Connection, err error) {
+	u, err := url.Parse(amqpURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AMQP URL: %w", err)
+	}
+
+	if tlsConf != nil {
+		if u.User != nil {
+			conn, err = amqp.DialTLS(amqpURL, tlsConf)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
+			}
+		} else {
+			conn, err = amqp.DialTLS_ExternalAuth(amqpURL, tlsConf)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
+			}
+		}
+	} else {
+		conn, err = amqp.Dial(amqpURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
+		}
+	}
+
+	return conn, nil
+}
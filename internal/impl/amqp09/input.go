@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -82,10 +81,10 @@ You can access these metadata fields using
 			docs.FieldObject("queue_declare", `
 Allows you to passively declare the target queue. If the queue already exists
 then the declaration passively verifies that they match the target fields.`,
-			).WithChildren(
+			).WithChildren(append(docs.FieldSpecs{
 				docs.FieldBool("enabled", "Whether to enable queue declaration.").HasDefault(false),
 				docs.FieldBool("durable", "Whether the declared queue is durable.").HasDefault(true),
-			).Advanced(),
+			}, queueArgsFieldSpecs()...)...).Advanced(),
 			docs.FieldObject("bindings_declare",
 				"Allows you to passively declare bindings for the target queue.",
 				[]interface{}{
@@ -124,6 +123,7 @@ type amqp09Reader struct {
 	tlsConf *tls.Config
 
 	nackRejectPattens []*regexp.Regexp
+	queueArgs         amqp.Table
 
 	conf input.AMQP09Config
 	log  log.Modular
@@ -157,6 +157,19 @@ func newAMQP09Reader(conf input.AMQP09Config, log log.Modular) (*amqp09Reader, e
 		a.nackRejectPattens = append(a.nackRejectPattens, r)
 	}
 
+	if conf.QueueDeclare.Enabled {
+		var err error
+		if a.queueArgs, err = queueDeclareArgs(
+			conf.QueueDeclare.Type,
+			conf.QueueDeclare.Durable,
+			conf.QueueDeclare.DeadLetterExchange,
+			conf.QueueDeclare.DeadLetterRoutingKey,
+			conf.QueueDeclare.DeliveryLimit,
+		); err != nil {
+			return nil, fmt.Errorf("invalid queue declaration: %w", err)
+		}
+	}
+
 	if conf.TLS.Enabled {
 		var err error
 		if a.tlsConf, err = conf.TLS.Get(); err != nil {
@@ -181,7 +194,7 @@ func (a *amqp09Reader) ConnectWithContext(ctx context.Context) (err error) {
 	var amqpChan *amqp.Channel
 	var consumerChan <-chan amqp.Delivery
 
-	if conn, err = a.reDial(a.urls); err != nil {
+	if conn, err = reDialAMQP09(a.urls, a.tlsConf); err != nil {
 		return err
 	}
 
@@ -197,7 +210,7 @@ func (a *amqp09Reader) ConnectWithContext(ctx context.Context) (err error) {
 			false,                       // delete when unused
 			false,                       // exclusive
 			false,                       // noWait
-			nil,                         // arguments
+			a.queueArgs,                 // arguments
 		); err != nil {
 			return fmt.Errorf("queue Declare: %s", err)
 		}
@@ -264,7 +277,12 @@ func (a *amqp09Reader) disconnect() error {
 
 //------------------------------------------------------------------------------
 
-func amqpSetMetadata(p *message.Part, k string, v interface{}) {
+// metaSetter is implemented by both internal and public message types.
+type metaSetter interface {
+	MetaSet(key, value string)
+}
+
+func amqpSetMetadata(p metaSetter, k string, v interface{}) {
 	var metaValue string
 	var metaKey = strings.ReplaceAll(k, "-", "_")
 
@@ -309,6 +327,40 @@ func amqpSetMetadata(p *message.Part, k string, v interface{}) {
 	}
 }
 
+// amqpSetDeliveryMetadata adds the headers and properties of a delivery as
+// metadata.
+func amqpSetDeliveryMetadata(p metaSetter, data amqp.Delivery) {
+	for k, v := range data.Headers {
+		amqpSetMetadata(p, k, v)
+	}
+
+	amqpSetMetadata(p, "amqp_content_type", data.ContentType)
+	amqpSetMetadata(p, "amqp_content_encoding", data.ContentEncoding)
+
+	if data.DeliveryMode != 0 {
+		amqpSetMetadata(p, "amqp_delivery_mode", data.DeliveryMode)
+	}
+
+	amqpSetMetadata(p, "amqp_priority", data.Priority)
+	amqpSetMetadata(p, "amqp_correlation_id", data.CorrelationId)
+	amqpSetMetadata(p, "amqp_reply_to", data.ReplyTo)
+	amqpSetMetadata(p, "amqp_expiration", data.Expiration)
+	amqpSetMetadata(p, "amqp_message_id", data.MessageId)
+
+	if !data.Timestamp.IsZero() {
+		amqpSetMetadata(p, "amqp_timestamp", data.Timestamp.Unix())
+	}
+
+	amqpSetMetadata(p, "amqp_type", data.Type)
+	amqpSetMetadata(p, "amqp_user_id", data.UserId)
+	amqpSetMetadata(p, "amqp_app_id", data.AppId)
+	amqpSetMetadata(p, "amqp_consumer_tag", data.ConsumerTag)
+	amqpSetMetadata(p, "amqp_delivery_tag", data.DeliveryTag)
+	amqpSetMetadata(p, "amqp_redelivered", data.Redelivered)
+	amqpSetMetadata(p, "amqp_exchange", data.Exchange)
+	amqpSetMetadata(p, "amqp_routing_key", data.RoutingKey)
+}
+
 // ReadWithContext a new AMQP09 message.
 func (a *amqp09Reader) ReadWithContext(ctx context.Context) (*message.Batch, input.AsyncAckFn, error) {
 	var c <-chan amqp.Delivery
@@ -326,37 +378,7 @@ func (a *amqp09Reader) ReadWithContext(ctx context.Context) (*message.Batch, inp
 	msg := message.QuickBatch(nil)
 	addPart := func(data amqp.Delivery) {
 		part := message.NewPart(data.Body)
-
-		for k, v := range data.Headers {
-			amqpSetMetadata(part, k, v)
-		}
-
-		amqpSetMetadata(part, "amqp_content_type", data.ContentType)
-		amqpSetMetadata(part, "amqp_content_encoding", data.ContentEncoding)
-
-		if data.DeliveryMode != 0 {
-			amqpSetMetadata(part, "amqp_delivery_mode", data.DeliveryMode)
-		}
-
-		amqpSetMetadata(part, "amqp_priority", data.Priority)
-		amqpSetMetadata(part, "amqp_correlation_id", data.CorrelationId)
-		amqpSetMetadata(part, "amqp_reply_to", data.ReplyTo)
-		amqpSetMetadata(part, "amqp_expiration", data.Expiration)
-		amqpSetMetadata(part, "amqp_message_id", data.MessageId)
-
-		if !data.Timestamp.IsZero() {
-			amqpSetMetadata(part, "amqp_timestamp", data.Timestamp.Unix())
-		}
-
-		amqpSetMetadata(part, "amqp_type", data.Type)
-		amqpSetMetadata(part, "amqp_user_id", data.UserId)
-		amqpSetMetadata(part, "amqp_app_id", data.AppId)
-		amqpSetMetadata(part, "amqp_consumer_tag", data.ConsumerTag)
-		amqpSetMetadata(part, "amqp_delivery_tag", data.DeliveryTag)
-		amqpSetMetadata(part, "amqp_redelivered", data.Redelivered)
-		amqpSetMetadata(part, "amqp_exchange", data.Exchange)
-		amqpSetMetadata(part, "amqp_routing_key", data.RoutingKey)
-
+		amqpSetDeliveryMetadata(part, data)
 		msg.Append(part)
 	}
 
@@ -396,47 +418,3 @@ func (a *amqp09Reader) CloseAsync() {
 func (a *amqp09Reader) WaitForClose(timeout time.Duration) error {
 	return nil
 }
-
-// reDial connection to amqp with one or more fallback URLs
-func (a *amqp09Reader) reDial(urls []string) (conn *amqp.Connection, err error) {
-	for _, u := range urls {
-		conn, err = a.dial(u)
-		if err != nil {
-			if errors.Is(err, errAMQP09Connect) {
-				continue
-			}
-			break
-		}
-		return conn, nil
-	}
-	return nil, err
-}
-
-// dial attempts to connect to amqp URL
-func (a *amqp09Reader) dial(amqpURL string) (conn *amqp.Connection, err error) {
-	u, err := url.Parse(amqpURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid AMQP URL: %w", err)
-	}
-
-	if a.conf.TLS.Enabled {
-		if u.User != nil {
-			conn, err = amqp.DialTLS(amqpURL, a.tlsConf)
-			if err != nil {
-				return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
-			}
-		} else {
-			conn, err = amqp.DialTLS_ExternalAuth(amqpURL, a.tlsConf)
-			if err != nil {
-				return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
-			}
-		}
-	} else {
-		conn, err = amqp.Dial(amqpURL)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
-		}
-	}
-
-	return conn, nil
-}
@@ -0,0 +1,89 @@
+package amqp09
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestParseStreamOffset(t *testing.T) {
+	for _, spec := range []string{"first", "last", "next"} {
+		v, err := parseStreamOffset(spec)
+		require.NoError(t, err)
+		assert.Equal(t, spec, v)
+	}
+
+	v, err := parseStreamOffset("1337")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1337), v)
+
+	v, err = parseStreamOffset("2022-06-01T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC), v)
+
+	for _, spec := range []string{"", "-1", "yesterday"} {
+		_, err = parseStreamOffset(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestDeliveryStreamOffset(t *testing.T) {
+	offset, err := deliveryStreamOffset(amqp.Delivery{Headers: amqp.Table{"x-stream-offset": int64(10)}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+
+	_, err = deliveryStreamOffset(amqp.Delivery{})
+	require.Error(t, err)
+
+	_, err = deliveryStreamOffset(amqp.Delivery{Headers: amqp.Table{"x-stream-offset": "10"}})
+	require.Error(t, err)
+}
+
+func TestAMQPStreamCheckpoint(t *testing.T) {
+	conf, err := amqpStreamInputConfig().ParseYAML(`
+urls: [ amqp://localhost:5672/,amqp://localhost:5673/ ]
+stream: foo
+offset: first
+checkpoint_cache: offsets
+checkpoint_key: foo_offset
+`, nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("offsets"))
+
+	r, err := newAMQPStreamReaderFromConfig(conf, mgr)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"amqp://localhost:5672/", "amqp://localhost:5673/"}, r.urls)
+	assert.Equal(t, "first", r.offset)
+	assert.Equal(t, 100, r.prefetchCount)
+
+	ctx := context.Background()
+
+	stored, err := r.readCheckpoint(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	require.NoError(t, r.writeCheckpoint(ctx, 42))
+
+	stored, err = r.readCheckpoint(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, int64(42), *stored)
+
+	conf, err = amqpStreamInputConfig().ParseYAML(`
+urls: [ amqp://localhost:5672/ ]
+stream: foo
+checkpoint_cache: missing
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newAMQPStreamReaderFromConfig(conf, mgr)
+	require.Error(t, err)
+}
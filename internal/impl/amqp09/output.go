@@ -5,7 +5,6 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -47,7 +46,16 @@ The metadata from each message are delivered as headers.
 
 It's possible for this output type to create the target exchange by setting
 `+"`exchange_declare.enabled` to `true`"+`, if the exchange already exists
-then the declaration passively verifies that the settings match.
+then the declaration passively verifies that the settings match. Similarly, a
+queue can be declared and bound to the exchange with the `+"`queue_declare`"+`
+field, which supports quorum queues, dead letter exchanges and delivery limits.
+
+Messages are published with publisher confirms enabled, and are only considered
+delivered once the server has confirmed them. When the `+"`mandatory`"+` flag is
+set a message that could not be routed to a queue is returned by the server,
+and the write is considered failed so that it can be retried. In order to
+correlate returned messages with their writes the messages are published one at
+a time when the `+"`mandatory` or `immediate`"+` flags are set.
 
 TLS is automatic when connecting to an `+"`amqps`"+` URL, but custom
 settings can be enabled in the `+"`tls`"+` section.
@@ -69,6 +77,13 @@ The fields 'key' and 'type' can be dynamically set using function interpolations
 				).HasDefault("direct"),
 				docs.FieldBool("durable", "Whether the exchange should be durable.").HasDefault(true),
 			).Advanced(),
+			docs.FieldObject("queue_declare", "Optionally declare a queue and bind it to the target exchange, this requires the exchange to exist or be declared with `exchange_declare`.").WithChildren(append(append(docs.FieldSpecs{
+				docs.FieldBool("enabled", "Whether to declare the queue.").HasDefault(false),
+				docs.FieldString("queue", "The name of the queue to declare.").HasDefault(""),
+				docs.FieldBool("durable", "Whether the declared queue is durable.").HasDefault(true),
+			}, queueArgsFieldSpecs()...),
+				docs.FieldString("binding_keys", "A list of binding keys to bind the queue to the exchange with.", []string{"foo", "bar"}).Array().HasDefault([]interface{}{}),
+			)...).Advanced().AtVersion("4.5.0"),
 			docs.FieldString("key", "The binding key to set for each message.").IsInterpolated().HasDefault(""),
 			docs.FieldString("type", "The type property to set for each message.").IsInterpolated().HasDefault(""),
 			docs.FieldString("content_type", "The content type attribute to set for each message.").IsInterpolated().Advanced().HasDefault("application/octet-stream"),
@@ -107,9 +122,14 @@ type amqp09Writer struct {
 	conn       *amqp.Connection
 	amqpChan   *amqp.Channel
 	returnChan <-chan amqp.Return
+	queueArgs  amqp.Table
 
 	deliveryMode uint8
 
+	// When messages can be returned by the server we publish them one at a
+	// time so that a return can be attributed to the message that caused it.
+	returnLock sync.Mutex
+
 	connLock sync.RWMutex
 }
 
@@ -153,6 +173,21 @@ func newAMQP09Writer(mgr bundle.NewManagement, conf output.AMQPConfig, log log.M
 		return nil, errors.New("must specify at least one url")
 	}
 
+	if conf.QueueDeclare.Enabled {
+		if conf.QueueDeclare.Queue == "" {
+			return nil, errors.New("a queue name must be specified in order to declare a queue")
+		}
+		if a.queueArgs, err = queueDeclareArgs(
+			conf.QueueDeclare.Type,
+			conf.QueueDeclare.Durable,
+			conf.QueueDeclare.DeadLetterExchange,
+			conf.QueueDeclare.DeadLetterRoutingKey,
+			conf.QueueDeclare.DeliveryLimit,
+		); err != nil {
+			return nil, fmt.Errorf("invalid queue declaration: %w", err)
+		}
+	}
+
 	if conf.TLS.Enabled {
 		if a.tlsConf, err = conf.TLS.Get(); err != nil {
 			return nil, err
@@ -165,7 +200,7 @@ func (a *amqp09Writer) ConnectWithContext(ctx context.Context) error {
 	a.connLock.Lock()
 	defer a.connLock.Unlock()
 
-	conn, err := a.reDial(a.urls)
+	conn, err := reDialAMQP09(a.urls, a.tlsConf)
 	if err != nil {
 		return err
 	}
@@ -191,6 +226,32 @@ func (a *amqp09Writer) ConnectWithContext(ctx context.Context) error {
 		}
 	}
 
+	if a.conf.QueueDeclare.Enabled {
+		if _, err = amqpChan.QueueDeclare(
+			a.conf.QueueDeclare.Queue,   // name of the queue
+			a.conf.QueueDeclare.Durable, // durable
+			false,                       // delete when unused
+			false,                       // exclusive
+			false,                       // noWait
+			a.queueArgs,                 // arguments
+		); err != nil {
+			conn.Close()
+			return fmt.Errorf("amqp failed to declare queue: %v", err)
+		}
+		for _, key := range a.conf.QueueDeclare.BindingKeys {
+			if err = amqpChan.QueueBind(
+				a.conf.QueueDeclare.Queue, // name of the queue
+				key,                       // bindingKey
+				a.conf.Exchange,           // sourceExchange
+				false,                     // noWait
+				nil,                       // arguments
+			); err != nil {
+				conn.Close()
+				return fmt.Errorf("amqp failed to bind queue: %v", err)
+			}
+		}
+	}
+
 	if err = amqpChan.Confirm(false); err != nil {
 		conn.Close()
 		return fmt.Errorf("amqp channel could not be put into confirm mode: %v", err)
@@ -258,6 +319,11 @@ func (a *amqp09Writer) WriteWithContext(ctx context.Context, msg *message.Batch)
 			return nil
 		})
 
+		if returnChan != nil {
+			a.returnLock.Lock()
+			defer a.returnLock.Unlock()
+		}
+
 		conf, err := amqpChan.PublishWithDeferredConfirm(
 			a.conf.Exchange,  // publish to an exchange
 			bindingKey,       // routing to 0 or more queues
@@ -284,12 +350,14 @@ func (a *amqp09Writer) WriteWithContext(ctx context.Context, msg *message.Batch)
 			return component.ErrNoAck
 		}
 		if returnChan != nil {
+			// The server sends a return before the confirm of the same
+			// message, and therefore it will already be buffered.
 			select {
-			case _, open := <-returnChan:
+			case ret, open := <-returnChan:
 				if !open {
 					return fmt.Errorf("acknowledgement not supported, ensure server supports immediate and mandatory flags")
 				}
-				return component.ErrNoAck
+				return fmt.Errorf("message returned by server: %v (%v)", ret.ReplyText, ret.ReplyCode)
 			default:
 			}
 		}
@@ -304,47 +372,3 @@ func (a *amqp09Writer) CloseAsync() {
 func (a *amqp09Writer) WaitForClose(timeout time.Duration) error {
 	return nil
 }
-
-// reDial connection to amqp with one or more fallback URLs
-func (a *amqp09Writer) reDial(urls []string) (conn *amqp.Connection, err error) {
-	for _, u := range urls {
-		conn, err = a.dial(u)
-		if err != nil {
-			if errors.Is(err, errAMQP09Connect) {
-				continue
-			}
-			break
-		}
-		return conn, nil
-	}
-	return nil, err
-}
-
-// dial attempts to connect to amqp URL
-func (a *amqp09Writer) dial(amqpURL string) (conn *amqp.Connection, err error) {
-	u, err := url.Parse(amqpURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid AMQP URL: %w", err)
-	}
-
-	if a.conf.TLS.Enabled {
-		if u.User != nil {
-			conn, err = amqp.DialTLS(amqpURL, a.tlsConf)
-			if err != nil {
-				return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
-			}
-		} else {
-			conn, err = amqp.DialTLS_ExternalAuth(amqpURL, a.tlsConf)
-			if err != nil {
-				return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
-			}
-		}
-	} else {
-		conn, err = amqp.Dial(amqpURL)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %s", errAMQP09Connect, err)
-		}
-	}
-
-	return conn, nil
-}
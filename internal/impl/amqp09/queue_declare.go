@@ -0,0 +1,68 @@
+package amqp09
+
+import (
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+// queueArgsFieldSpecs returns the docs of fields that describe the arguments of
+// a declared queue, which are shared by the input and output.
+func queueArgsFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldString("type", "The type of the declared queue. Quorum queues and streams are only supported by RabbitMQ, and must be durable.").HasAnnotatedOptions(
+			"classic", "A classic queue.",
+			"quorum", "A replicated quorum queue, which supports a delivery limit.",
+			"stream", "A replicated, append only stream, which can be consumed with the [`amqp_stream`](/docs/components/inputs/amqp_stream) input.",
+		).HasDefault("classic").AtVersion("4.5.0"),
+		docs.FieldString("dead_letter_exchange", "An optional exchange that messages are routed to when they are rejected, expire or exceed the delivery limit of the queue.").HasDefault("").AtVersion("4.5.0"),
+		docs.FieldString("dead_letter_routing_key", "An optional routing key to use for messages routed to the dead letter exchange, by default the routing key of each message is kept.").HasDefault("").AtVersion("4.5.0"),
+		docs.FieldInt("delivery_limit", "The maximum number of times that a message of a quorum queue is delivered before it is dropped or routed to the dead letter exchange. Set to zero in order to deliver messages indefinitely.").HasDefault(0).AtVersion("4.5.0"),
+	}
+}
+
+// queueDeclareArgs returns the arguments to declare a queue with.
+func queueDeclareArgs(queueType string, durable bool, deadLetterExchange, deadLetterRoutingKey string, deliveryLimit int) (amqp.Table, error) {
+	args := amqp.Table{}
+
+	switch queueType {
+	case "", "classic":
+		if deliveryLimit > 0 {
+			return nil, errors.New("a delivery limit can only be set for quorum queues")
+		}
+	case "quorum", "stream":
+		if !durable {
+			return nil, fmt.Errorf("queues of type %v must be durable", queueType)
+		}
+		if deliveryLimit > 0 && queueType != "quorum" {
+			return nil, errors.New("a delivery limit can only be set for quorum queues")
+		}
+		args["x-queue-type"] = queueType
+	default:
+		return nil, fmt.Errorf("queue type %v was not recognised", queueType)
+	}
+
+	if deliveryLimit < 0 {
+		return nil, fmt.Errorf("delivery limit must not be negative, got %v", deliveryLimit)
+	}
+	if deliveryLimit > 0 {
+		args["x-delivery-limit"] = int64(deliveryLimit)
+	}
+	if deadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = deadLetterExchange
+	}
+	if deadLetterRoutingKey != "" {
+		if deadLetterExchange == "" {
+			return nil, errors.New("a dead letter routing key requires a dead letter exchange")
+		}
+		args["x-dead-letter-routing-key"] = deadLetterRoutingKey
+	}
+
+	if len(args) == 0 {
+		return nil, nil
+	}
+	return args, nil
+}
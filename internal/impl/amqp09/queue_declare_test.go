@@ -0,0 +1,108 @@
+package amqp09
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueDeclareArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		queueType     string
+		durable       bool
+		dlx           string
+		dlrk          string
+		deliveryLimit int
+		expected      amqp.Table
+		errContains   string
+	}{
+		{
+			name:      "classic without arguments",
+			queueType: "classic",
+			durable:   false,
+		},
+		{
+			name:      "classic with dead letters",
+			queueType: "classic",
+			dlx:       "dead",
+			dlrk:      "letters",
+			expected: amqp.Table{
+				"x-dead-letter-exchange":    "dead",
+				"x-dead-letter-routing-key": "letters",
+			},
+		},
+		{
+			name:          "quorum with delivery limit",
+			queueType:     "quorum",
+			durable:       true,
+			dlx:           "dead",
+			deliveryLimit: 5,
+			expected: amqp.Table{
+				"x-queue-type":           "quorum",
+				"x-delivery-limit":       int64(5),
+				"x-dead-letter-exchange": "dead",
+			},
+		},
+		{
+			name:      "stream",
+			queueType: "stream",
+			durable:   true,
+			expected: amqp.Table{
+				"x-queue-type": "stream",
+			},
+		},
+		{
+			name:        "quorum not durable",
+			queueType:   "quorum",
+			errContains: "must be durable",
+		},
+		{
+			name:          "classic with delivery limit",
+			queueType:     "classic",
+			deliveryLimit: 5,
+			errContains:   "only be set for quorum queues",
+		},
+		{
+			name:          "stream with delivery limit",
+			queueType:     "stream",
+			durable:       true,
+			deliveryLimit: 5,
+			errContains:   "only be set for quorum queues",
+		},
+		{
+			name:          "negative delivery limit",
+			queueType:     "quorum",
+			durable:       true,
+			deliveryLimit: -1,
+			errContains:   "must not be negative",
+		},
+		{
+			name:        "routing key without exchange",
+			queueType:   "classic",
+			dlrk:        "letters",
+			errContains: "requires a dead letter exchange",
+		},
+		{
+			name:        "unknown type",
+			queueType:   "lazy",
+			errContains: "not recognised",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			args, err := queueDeclareArgs(test.queueType, test.durable, test.dlx, test.dlrk, test.deliveryLimit)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, args)
+		})
+	}
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/linkedin/goavro/v2"
 
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent/sr"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -29,7 +30,15 @@ func schemaRegistryDecoderConfig() *service.ConfigSpec {
 		Description(`
 Decodes messages automatically from a schema stored within a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html) by extracting a schema ID from the message and obtaining the associated schema from the registry. If a message fails to match against the schema then it will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
 
-Currently only Avro schemas are supported.
+Avro, Protobuf and JSON schemas are supported, where the type of schema is detected from the registry. Schemas that reference other schemas registered under different subjects are also supported, and the referenced schemas are obtained from the registry along with the schema that references them.
+
+### Protobuf Format
+
+Messages encoded with Protobuf schemas are expected to follow the schema ID with the indexes of the message type within the schema, as written by Confluent serializers. Messages are decoded into JSON documents following the [Protobuf JSON mapping](https://developers.google.com/protocol-buffers/docs/proto3#json).
+
+### JSON Schema Format
+
+Messages encoded with JSON schemas are JSON documents following the schema ID, the schema ID is removed and the document is parsed.
 
 ### Avro JSON Format
 
@@ -69,6 +78,7 @@ type schemaRegistryDecoder struct {
 	avroRawJSON bool
 
 	schemaRegistryBaseURL *url.URL
+	refResolver           *referenceResolver
 
 	schemas    map[int]*cachedSchemaDecoder
 	cacheMut   sync.RWMutex
@@ -117,6 +127,11 @@ func newSchemaRegistryDecoder(urlStr string, tlsConf *tls.Config, avroRawJSON bo
 			}
 		}
 	}
+	refClient, err := sr.NewClient(urlStr, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+	s.refResolver = &referenceResolver{client: refClient}
 
 	go func() {
 		for {
@@ -295,21 +310,55 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 		return nil, err
 	}
 
-	resPayload := struct {
-		Schema string `json:"schema"`
-	}{}
+	var resPayload schemaPayload
 	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
 		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
 		return nil, err
 	}
 
-	var codec *goavro.Codec
-	if codec, err = goavro.NewCodecForStandardJSON(resPayload.Schema); err != nil {
+	decoder, err := s.newDecoder(ctx, resPayload)
+	if err != nil {
 		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
 		return nil, err
 	}
 
-	decoder := func(m *service.Message) error {
+	s.cacheMut.Lock()
+	s.schemas[id] = &cachedSchemaDecoder{
+		lastUsedUnixSeconds: time.Now().Unix(),
+		decoder:             decoder,
+	}
+	s.cacheMut.Unlock()
+
+	return decoder, nil
+}
+
+func (s *schemaRegistryDecoder) newDecoder(ctx context.Context, payload schemaPayload) (schemaDecoder, error) {
+	refs, err := s.refResolver.resolve(ctx, payload.References)
+	if err != nil {
+		return nil, err
+	}
+
+	switch payload.normalisedType() {
+	case schemaTypeProtobuf:
+		return newProtobufDecoder(payload.Schema, refs)
+	case schemaTypeJSON:
+		return newJSONSchemaDecoder(), nil
+	case schemaTypeAvro:
+	default:
+		return nil, fmt.Errorf("schema type %v not supported", payload.SchemaType)
+	}
+
+	schema, err := resolveAvroReferences(payload.Schema, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodecForStandardJSON(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(m *service.Message) error {
 		b, err := m.AsBytes()
 		if err != nil {
 			return err
@@ -332,14 +381,5 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 			m.SetStructured(native)
 		}
 		return nil
-	}
-
-	s.cacheMut.Lock()
-	s.schemas[id] = &cachedSchemaDecoder{
-		lastUsedUnixSeconds: time.Now().Unix(),
-		decoder:             decoder,
-	}
-	s.cacheMut.Unlock()
-
-	return decoder, nil
+	}, nil
 }
@@ -285,3 +285,181 @@ func TestSchemaRegistryDecodeClearExpired(t *testing.T) {
 	}, decoder.schemas)
 	decoder.cacheMut.Unlock()
 }
+
+const testProtoCommonSchema = `
+syntax = "proto3";
+package testing;
+
+message Address {
+  string city = 1;
+}
+`
+
+const testProtoSchema = `
+syntax = "proto3";
+package testing;
+
+import "common.proto";
+
+message Person {
+  string name = 1;
+  Address address = 2;
+}
+
+message Other {
+  int32 id = 1;
+
+  message Nested {
+    string value = 1;
+  }
+}
+`
+
+const testAvroAddressSchema = `{
+	"namespace": "com.example",
+	"type": "record",
+	"name": "Address",
+	"fields": [
+		{ "name": "city", "type": "string" }
+	]
+}`
+
+const testAvroPersonSchema = `{
+	"namespace": "com.example",
+	"type": "record",
+	"name": "Person",
+	"fields": [
+		{ "name": "name", "type": "string" },
+		{ "name": "address", "type": "Address" }
+	]
+}`
+
+const testJSONAddressSchema = `{
+	"type": "object",
+	"properties": {
+		"city": { "type": "string" }
+	},
+	"required": [ "city" ]
+}`
+
+const testJSONPersonSchema = `{
+	"type": "object",
+	"properties": {
+		"name": { "type": "string" },
+		"address": { "$ref": "https://example.com/address.json" }
+	},
+	"required": [ "name" ]
+}`
+
+func mustJSONPayload(t testing.TB, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
+
+func TestSchemaRegistryDecodeTypesWithReferences(t *testing.T) {
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/schemas/ids/3":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema":     testProtoSchema,
+				"schemaType": "PROTOBUF",
+				"references": []map[string]interface{}{
+					{"name": "common.proto", "subject": "common", "version": 1},
+				},
+			}), nil
+		case "/subjects/common/versions/1":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema":     testProtoCommonSchema,
+				"schemaType": "PROTOBUF",
+			}), nil
+		case "/schemas/ids/4":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema": testAvroPersonSchema,
+				"references": []map[string]interface{}{
+					{"name": "com.example.Address", "subject": "address", "version": 2},
+				},
+			}), nil
+		case "/subjects/address/versions/2":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema": testAvroAddressSchema,
+			}), nil
+		case "/schemas/ids/5":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema":     testJSONPersonSchema,
+				"schemaType": "JSON",
+			}), nil
+		case "/schemas/ids/6":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema":     testProtoSchema,
+				"schemaType": "PROTOBUF",
+				"references": []map[string]interface{}{
+					{"name": "common.proto", "subject": "missing", "version": 1},
+				},
+			}), nil
+		}
+		return nil, nil
+	})
+
+	decoder, err := newSchemaRegistryDecoder(urlStr, nil, true, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		input       string
+		output      string
+		errContains string
+	}{
+		{
+			name:   "protobuf first message",
+			input:  "\x00\x00\x00\x00\x03\x00\x0a\x03foo\x12\x05\x0a\x03bar",
+			output: `{"name":"foo","address":{"city":"bar"}}`,
+		},
+		{
+			name:   "protobuf nested message",
+			input:  "\x00\x00\x00\x00\x03\x04\x02\x00\x0a\x03baz",
+			output: `{"value":"baz"}`,
+		},
+		{
+			name:        "protobuf unknown message index",
+			input:       "\x00\x00\x00\x00\x03\x02\x0a\x0a\x03baz",
+			errContains: "message index [5] not found",
+		},
+		{
+			name:   "avro with reference",
+			input:  "\x00\x00\x00\x00\x04\x06foo\x06bar",
+			output: `{"name":"foo","address":{"city":"bar"}}`,
+		},
+		{
+			name:   "json schema",
+			input:  "\x00\x00\x00\x00\x05" + `{"name":"foo","address":{"city":"bar"}}`,
+			output: `{"name":"foo","address":{"city":"bar"}}`,
+		},
+		{
+			name:        "missing reference",
+			input:       "\x00\x00\x00\x00\x06\x00\x0a\x03foo",
+			errContains: "failed to resolve schema reference 'common.proto'",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			outMsgs, err := decoder.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, outMsgs, 1)
+
+			b, err := outMsgs[0].AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, test.output, string(b))
+		})
+	}
+
+	require.NoError(t, decoder.Close(context.Background()))
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/linkedin/goavro/v2"
 
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent/sr"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -31,7 +32,15 @@ Encodes messages automatically from schemas obtains from a [Confluent Schema Reg
 
 If a message fails to encode under the schema then it will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).
 
-Currently only Avro schemas are supported.
+Avro, Protobuf and JSON schemas are supported, where the type of schema is detected from the registry. Schemas that reference other schemas registered under different subjects are also supported, and the referenced schemas are obtained from the registry along with the schema that references them.
+
+### Protobuf Format
+
+Messages are expected to be JSON documents following the [Protobuf JSON mapping](https://developers.google.com/protocol-buffers/docs/proto3#json), and are encoded as the message type named by ` + "[`message_name`](#message_name)" + `, or the first message type defined by the schema when it is empty. The schema ID is followed by the indexes of the message type within the schema, as expected by Confluent deserializers.
+
+### JSON Schema Format
+
+Messages are validated against the JSON schema and are otherwise left unchanged, with the schema ID written before the document. Messages that fail validation remain unchanged and are flagged with an error.
 
 ### Avro JSON Format
 
@@ -59,6 +68,11 @@ However, it is possible to instead consume documents in raw JSON format (that ma
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether messages encoded in Avro format should be parsed as raw JSON documents rather than [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding).").
 			Advanced().Default(false).Version("3.59.0")).
+		Field(service.NewStringField("message_name").
+			Description("The fully qualified name of the message type to encode messages as when the schema of a subject is a Protobuf schema, which may be a nested message type. When empty messages are encoded as the first message type defined by the schema.").
+			Advanced().Default("").Version("4.5.0").
+			Example("testing.Person").
+			Example("testing.Person.Address")).
 		Field(service.NewTLSField("tls")).
 		Version("3.58.0")
 }
@@ -78,12 +92,14 @@ func init() {
 //------------------------------------------------------------------------------
 
 type schemaRegistryEncoder struct {
-	client             *http.Client
-	subject            *service.InterpolatedString
-	avroRawJSON        bool
-	schemaRefreshAfter time.Duration
+	client              *http.Client
+	subject             *service.InterpolatedString
+	avroRawJSON         bool
+	protobufMessageName string
+	schemaRefreshAfter  time.Duration
 
 	schemaRegistryBaseURL *url.URL
+	refResolver           *referenceResolver
 
 	schemas    map[string]*cachedSchemaEncoder
 	cacheMut   sync.RWMutex
@@ -119,11 +135,15 @@ func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, logger *serv
 	if refreshTicker < time.Second {
 		refreshTicker = time.Second
 	}
+	protobufMessageName, err := conf.FieldString("message_name")
+	if err != nil {
+		return nil, err
+	}
 	tlsConf, err := conf.FieldTLS("tls")
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryEncoder(urlStr, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, logger)
+	return newSchemaRegistryEncoder(urlStr, tlsConf, subject, avroRawJSON, protobufMessageName, refreshPeriod, refreshTicker, logger)
 }
 
 func newSchemaRegistryEncoder(
@@ -131,6 +151,7 @@ func newSchemaRegistryEncoder(
 	tlsConf *tls.Config,
 	subject *service.InterpolatedString,
 	avroRawJSON bool,
+	protobufMessageName string,
 	schemaRefreshAfter, schemaRefreshTicker time.Duration,
 	logger *service.Logger,
 ) (*schemaRegistryEncoder, error) {
@@ -143,6 +164,7 @@ func newSchemaRegistryEncoder(
 		schemaRegistryBaseURL: u,
 		subject:               subject,
 		avroRawJSON:           avroRawJSON,
+		protobufMessageName:   protobufMessageName,
 		schemaRefreshAfter:    schemaRefreshAfter,
 		schemas:               map[string]*cachedSchemaEncoder{},
 		shutSig:               shutdown.NewSignaller(),
@@ -163,6 +185,11 @@ func newSchemaRegistryEncoder(
 			}
 		}
 	}
+	refClient, err := sr.NewClient(urlStr, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+	s.refResolver = &referenceResolver{client: refClient}
 
 	go func() {
 		for {
@@ -338,20 +365,45 @@ func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder,
 		return nil, 0, err
 	}
 
-	resPayload := struct {
-		Schema string `json:"schema"`
-		ID     int    `json:"id"`
-	}{}
+	var resPayload schemaPayload
 	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
 		s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
 		return nil, 0, err
 	}
 
-	var codec *goavro.Codec
-	if codec, err = goavro.NewCodecForStandardJSON(resPayload.Schema); err != nil {
+	encoder, err := s.newEncoder(ctx, resPayload)
+	if err != nil {
 		s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
 		return nil, 0, err
 	}
+	return encoder, resPayload.ID, nil
+}
+
+func (s *schemaRegistryEncoder) newEncoder(ctx context.Context, payload schemaPayload) (schemaEncoder, error) {
+	refs, err := s.refResolver.resolve(ctx, payload.References)
+	if err != nil {
+		return nil, err
+	}
+
+	switch payload.normalisedType() {
+	case schemaTypeProtobuf:
+		return newProtobufEncoder(payload.Schema, refs, s.protobufMessageName)
+	case schemaTypeJSON:
+		return newJSONSchemaEncoder(payload.Schema, refs)
+	case schemaTypeAvro:
+	default:
+		return nil, fmt.Errorf("schema type %v not supported", payload.SchemaType)
+	}
+
+	schema, err := resolveAvroReferences(payload.Schema, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodecForStandardJSON(schema)
+	if err != nil {
+		return nil, err
+	}
 
 	return func(m *service.Message) error {
 		var datum interface{}
@@ -375,7 +427,7 @@ func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder,
 
 		m.SetBytes(binary)
 		return nil
-	}, resPayload.ID, nil
+	}, nil
 }
 
 func (s *schemaRegistryEncoder) getEncoder(subject string) (schemaEncoder, int, error) {
@@ -111,7 +111,7 @@ func TestSchemaRegistryEncodeAvroRawJSON(t *testing.T) {
 	subj, err := service.NewInterpolatedString("foo")
 	require.NoError(t, err)
 
-	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, true, time.Minute*10, time.Minute, nil)
+	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, true, "", time.Minute*10, time.Minute, nil)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -188,7 +188,7 @@ func TestSchemaRegistryEncodeAvro(t *testing.T) {
 	subj, err := service.NewInterpolatedString("foo")
 	require.NoError(t, err)
 
-	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, time.Minute*10, time.Minute, nil)
+	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, "", time.Minute*10, time.Minute, nil)
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -253,7 +253,7 @@ func TestSchemaRegistryEncodeClearExpired(t *testing.T) {
 	subj, err := service.NewInterpolatedString("foo")
 	require.NoError(t, err)
 
-	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, time.Minute*10, time.Minute, nil)
+	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, "", time.Minute*10, time.Minute, nil)
 	require.NoError(t, err)
 	require.NoError(t, encoder.Close(context.Background()))
 
@@ -314,7 +314,7 @@ func TestSchemaRegistryEncodeRefresh(t *testing.T) {
 	subj, err := service.NewInterpolatedString("foo")
 	require.NoError(t, err)
 
-	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, time.Minute*10, time.Minute, nil)
+	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, "", time.Minute*10, time.Minute, nil)
 	require.NoError(t, err)
 	require.NoError(t, encoder.Close(context.Background()))
 
@@ -387,3 +387,129 @@ func TestSchemaRegistryEncodeRefresh(t *testing.T) {
 	assert.Equal(t, int32(1), atomic.LoadInt32(&fooReqs))
 	assert.Equal(t, int32(1), atomic.LoadInt32(&barReqs))
 }
+
+func TestSchemaRegistryEncodeTypesWithReferences(t *testing.T) {
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/proto/versions/latest":
+			return mustJSONPayload(t, map[string]interface{}{
+				"id":         3,
+				"schema":     testProtoSchema,
+				"schemaType": "PROTOBUF",
+				"references": []map[string]interface{}{
+					{"name": "common.proto", "subject": "common", "version": 1},
+				},
+			}), nil
+		case "/subjects/common/versions/1":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema":     testProtoCommonSchema,
+				"schemaType": "PROTOBUF",
+			}), nil
+		case "/subjects/avro/versions/latest":
+			return mustJSONPayload(t, map[string]interface{}{
+				"id":     4,
+				"schema": testAvroPersonSchema,
+				"references": []map[string]interface{}{
+					{"name": "com.example.Address", "subject": "address", "version": 2},
+				},
+			}), nil
+		case "/subjects/address/versions/2":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema": testAvroAddressSchema,
+			}), nil
+		case "/subjects/json/versions/latest":
+			return mustJSONPayload(t, map[string]interface{}{
+				"id":         5,
+				"schema":     testJSONPersonSchema,
+				"schemaType": "JSON",
+				"references": []map[string]interface{}{
+					{"name": "https://example.com/address.json", "subject": "jsonaddress", "version": 1},
+				},
+			}), nil
+		case "/subjects/jsonaddress/versions/1":
+			return mustJSONPayload(t, map[string]interface{}{
+				"schema":     testJSONAddressSchema,
+				"schemaType": "JSON",
+			}), nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	subj, err := service.NewInterpolatedString(`${! meta("subject") }`)
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoder(urlStr, nil, subj, false, "", time.Minute*10, time.Minute, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		subject     string
+		input       string
+		output      string
+		errContains string
+	}{
+		{
+			name:    "protobuf",
+			subject: "proto",
+			input:   `{"name":"foo","address":{"city":"bar"}}`,
+			output:  "\x00\x00\x00\x00\x03\x00\x0a\x03foo\x12\x05\x0a\x03bar",
+		},
+		{
+			name:        "protobuf unknown field",
+			subject:     "proto",
+			input:       `{"nope":"foo"}`,
+			errContains: "failed to unmarshal JSON message",
+		},
+		{
+			name:    "avro with reference",
+			subject: "avro",
+			input:   `{"name":"foo","address":{"city":"bar"}}`,
+			output:  "\x00\x00\x00\x00\x04\x06foo\x06bar",
+		},
+		{
+			name:    "json schema",
+			subject: "json",
+			input:   `{"name":"foo","address":{"city":"bar"}}`,
+			output:  "\x00\x00\x00\x00\x05" + `{"name":"foo","address":{"city":"bar"}}`,
+		},
+		{
+			name:        "json schema invalid reference",
+			subject:     "json",
+			input:       `{"name":"foo","address":{}}`,
+			errContains: "city is required",
+		},
+		{
+			name:        "json schema invalid",
+			subject:     "json",
+			input:       `{"address":{"city":"bar"}}`,
+			errContains: "name is required",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			msg := service.NewMessage([]byte(test.input))
+			msg.MetaSet("subject", test.subject)
+
+			outBatches, err := encoder.ProcessBatch(context.Background(), service.MessageBatch{msg})
+			require.NoError(t, err)
+			require.Len(t, outBatches, 1)
+			require.Len(t, outBatches[0], 1)
+
+			err = outBatches[0][0].GetError()
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+
+			b, err := outBatches[0][0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(b))
+		})
+	}
+
+	require.NoError(t, encoder.Close(context.Background()))
+}
@@ -0,0 +1,115 @@
+package confluent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+var avroPrimitiveTypes = map[string]struct{}{
+	"null": {}, "boolean": {}, "int": {}, "long": {}, "float": {},
+	"double": {}, "bytes": {}, "string": {},
+}
+
+func avroFullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// resolveAvroReferences returns an Avro schema where the first use of each
+// named type defined by a referenced schema is replaced with its definition,
+// as named types can only be used once defined within the same schema.
+func resolveAvroReferences(schema string, refs map[string]string) (string, error) {
+	if len(refs) == 0 {
+		return schema, nil
+	}
+
+	named := map[string]interface{}{}
+	for refName, refSchema := range refs {
+		var node interface{}
+		if err := json.Unmarshal([]byte(refSchema), &node); err != nil {
+			return "", fmt.Errorf("failed to parse referenced schema '%v': %w", refName, err)
+		}
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("referenced schema '%v' does not define a named type", refName)
+		}
+		name, _ := obj["name"].(string)
+		namespace, _ := obj["namespace"].(string)
+		named[avroFullName(name, namespace)] = obj
+	}
+
+	var root interface{}
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		return "", fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	r := avroReferenceInliner{named: named, defined: map[string]struct{}{}}
+	b, err := json.Marshal(r.walk(root, ""))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type avroReferenceInliner struct {
+	named   map[string]interface{}
+	defined map[string]struct{}
+}
+
+func (r *avroReferenceInliner) walk(node interface{}, namespace string) interface{} {
+	switch t := node.(type) {
+	case string:
+		if _, primitive := avroPrimitiveTypes[t]; primitive {
+			return t
+		}
+		fullName := avroFullName(t, namespace)
+		if _, exists := r.defined[fullName]; exists {
+			return t
+		}
+		if _, exists := r.defined[t]; exists {
+			return t
+		}
+		def, exists := r.named[fullName]
+		if !exists {
+			if def, exists = r.named[t]; !exists {
+				return t
+			}
+		}
+		return r.walk(def, namespace)
+	case []interface{}:
+		for i, v := range t {
+			t[i] = r.walk(v, namespace)
+		}
+		return t
+	case map[string]interface{}:
+		typeStr, _ := t["type"].(string)
+		switch typeStr {
+		case "record", "error", "enum", "fixed":
+			name, _ := t["name"].(string)
+			if ns, exists := t["namespace"].(string); exists {
+				namespace = ns
+			} else if idx := strings.LastIndex(name, "."); idx >= 0 {
+				namespace = name[:idx]
+			}
+			r.defined[avroFullName(name, namespace)] = struct{}{}
+			if fields, ok := t["fields"].([]interface{}); ok {
+				for _, f := range fields {
+					if fObj, ok := f.(map[string]interface{}); ok {
+						fObj["type"] = r.walk(fObj["type"], namespace)
+					}
+				}
+			}
+		case "array":
+			t["items"] = r.walk(t["items"], namespace)
+		case "map":
+			t["values"] = r.walk(t["values"], namespace)
+		default:
+			t["type"] = r.walk(t["type"], namespace)
+		}
+		return t
+	}
+	return node
+}
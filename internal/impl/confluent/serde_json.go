@@ -0,0 +1,68 @@
+package confluent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func compileJSONSchema(schema string, refs map[string]string) (*gojsonschema.Schema, error) {
+	loader := gojsonschema.NewSchemaLoader()
+	for name, refSchema := range refs {
+		if err := loader.AddSchema(name, gojsonschema.NewStringLoader(refSchema)); err != nil {
+			return nil, fmt.Errorf("failed to add referenced schema '%v': %w", name, err)
+		}
+	}
+
+	compiled, err := loader.Compile(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile JSON schema: %w", err)
+	}
+	return compiled, nil
+}
+
+func validateJSONSchema(schema *gojsonschema.Schema, b []byte) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(b))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	var errStrs []string
+	for _, desc := range result.Errors() {
+		errStrs = append(errStrs, desc.String())
+	}
+	return errors.New(strings.Join(errStrs, "; "))
+}
+
+// newJSONSchemaEncoder returns an encoder that validates JSON documents
+// against a schema, documents are otherwise left unchanged.
+func newJSONSchemaEncoder(schema string, refs map[string]string) (schemaEncoder, error) {
+	compiled, err := compileJSONSchema(schema, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		return validateJSONSchema(compiled, b)
+	}, nil
+}
+
+// newJSONSchemaDecoder returns a decoder that parses the JSON documents that
+// follow the schema ID of messages.
+func newJSONSchemaDecoder() schemaDecoder {
+	return func(m *service.Message) error {
+		_, err := m.AsStructured()
+		return err
+	}
+}
@@ -0,0 +1,198 @@
+package confluent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// The name given to the file of the schema being parsed, referenced schemas
+// are named by their references.
+const protobufSchemaFileName = "benthos_schema_registry.proto"
+
+func parseProtobufSchema(schema string, refs map[string]string) (*desc.FileDescriptor, error) {
+	files := map[string]string{protobufSchemaFileName: schema}
+	for k, v := range refs {
+		files[k] = v
+	}
+
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(files),
+	}
+	fds, err := parser.ParseFiles(protobufSchemaFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse protobuf schema: %w", err)
+	}
+	if len(fds[0].GetMessageTypes()) == 0 {
+		return nil, errors.New("protobuf schema does not define any messages")
+	}
+	return fds[0], nil
+}
+
+// readMessageIndexes reads the message indexes that follow the schema ID of
+// messages encoded with protobuf schemas, which is the path of the message type
+// within the schema, and returns the remaining bytes.
+func readMessageIndexes(b []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(b)
+	if n <= 0 {
+		return nil, nil, errors.New("failed to read message indexes")
+	}
+	b = b[n:]
+
+	// An empty list of indexes is an optimisation for the first message.
+	if count == 0 {
+		return []int{0}, b, nil
+	}
+	if count < 0 || count > int64(len(b)) {
+		return nil, nil, fmt.Errorf("invalid number of message indexes: %v", count)
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(b)
+		if n <= 0 {
+			return nil, nil, errors.New("failed to read message indexes")
+		}
+		indexes[i] = int(index)
+		b = b[n:]
+	}
+	return indexes, b, nil
+}
+
+// appendMessageIndexes appends the message indexes of a message type.
+func appendMessageIndexes(b []byte, indexes []int) []byte {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return append(b, 0)
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	b = append(b, buf[:binary.PutVarint(buf, int64(len(indexes)))]...)
+	for _, index := range indexes {
+		b = append(b, buf[:binary.PutVarint(buf, int64(index))]...)
+	}
+	return b
+}
+
+func messageDescriptorByIndexes(fd *desc.FileDescriptor, indexes []int) (*desc.MessageDescriptor, error) {
+	messages := fd.GetMessageTypes()
+	var md *desc.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || index >= len(messages) {
+			return nil, fmt.Errorf("message index %v not found in protobuf schema", indexes)
+		}
+		md = messages[index]
+		messages = md.GetNestedMessageTypes()
+	}
+	if md == nil {
+		return nil, errors.New("message indexes are empty")
+	}
+	return md, nil
+}
+
+// messageDescriptorByName returns the message type of a protobuf schema with a
+// fully qualified name, along with its indexes within the schema.
+func messageDescriptorByName(fd *desc.FileDescriptor, name string) (*desc.MessageDescriptor, []int, error) {
+	md := fd.FindMessage(name)
+	if md == nil {
+		return nil, nil, fmt.Errorf("message type %v not found in protobuf schema", name)
+	}
+
+	var indexes []int
+	for d := md; d != nil; {
+		parent, _ := d.GetParent().(*desc.MessageDescriptor)
+
+		siblings := fd.GetMessageTypes()
+		if parent != nil {
+			siblings = parent.GetNestedMessageTypes()
+		}
+		for i, sibling := range siblings {
+			if sibling.GetFullyQualifiedName() == d.GetFullyQualifiedName() {
+				indexes = append([]int{i}, indexes...)
+				break
+			}
+		}
+		d = parent
+	}
+	return md, indexes, nil
+}
+
+func newProtobufDecoder(schema string, refs map[string]string) (schemaDecoder, error) {
+	fd, err := parseProtobufSchema(schema, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fd)
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		indexes, remaining, err := readMessageIndexes(b)
+		if err != nil {
+			return err
+		}
+
+		md, err := messageDescriptorByIndexes(fd, indexes)
+		if err != nil {
+			return err
+		}
+
+		msg := dynamic.NewMessage(md)
+		if err := msg.Unmarshal(remaining); err != nil {
+			return fmt.Errorf("failed to unmarshal protobuf message: %w", err)
+		}
+
+		jBytes, err := msg.MarshalJSONPB(&jsonpb.Marshaler{AnyResolver: resolver})
+		if err != nil {
+			return fmt.Errorf("failed to marshal protobuf message: %w", err)
+		}
+		m.SetBytes(jBytes)
+		return nil
+	}, nil
+}
+
+// newProtobufEncoder returns an encoder for the message type of a protobuf
+// schema with a fully qualified name, or the first message type of the schema
+// when the name is empty.
+func newProtobufEncoder(schema string, refs map[string]string, messageName string) (schemaEncoder, error) {
+	fd, err := parseProtobufSchema(schema, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	md, indexes := fd.GetMessageTypes()[0], []int{0}
+	if messageName != "" {
+		if md, indexes, err = messageDescriptorByName(fd, messageName); err != nil {
+			return nil, err
+		}
+	}
+
+	resolver := dynamic.AnyResolver(dynamic.NewMessageFactoryWithDefaults(), fd)
+	return func(m *service.Message) error {
+		b, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		msg := dynamic.NewMessage(md)
+		if err := msg.UnmarshalJSONPB(&jsonpb.Unmarshaler{AnyResolver: resolver}, b); err != nil {
+			return fmt.Errorf("failed to unmarshal JSON message: %w", err)
+		}
+
+		pBytes, err := msg.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal protobuf message: %w", err)
+		}
+		m.SetBytes(append(appendMessageIndexes(nil, indexes), pBytes...))
+		return nil
+	}, nil
+}
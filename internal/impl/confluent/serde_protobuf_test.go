@@ -0,0 +1,117 @@
+package confluent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestProtobufMessageIndexes(t *testing.T) {
+	tests := []struct {
+		indexes []int
+		encoded []byte
+	}{
+		{indexes: []int{0}, encoded: []byte{0}},
+		{indexes: []int{1}, encoded: []byte{2, 2}},
+		{indexes: []int{1, 0}, encoded: []byte{4, 2, 0}},
+		{indexes: []int{70, 3}, encoded: []byte{4, 0x8c, 0x01, 6}},
+	}
+
+	for _, test := range tests {
+		b := appendMessageIndexes(nil, test.indexes)
+		assert.Equal(t, test.encoded, b)
+
+		indexes, remaining, err := readMessageIndexes(append(b, "foo"...))
+		require.NoError(t, err)
+		assert.Equal(t, test.indexes, indexes)
+		assert.Equal(t, "foo", string(remaining))
+	}
+
+	_, _, err := readMessageIndexes(nil)
+	require.Error(t, err)
+
+	_, _, err = readMessageIndexes([]byte{20, 2})
+	require.Error(t, err)
+}
+
+func TestProtobufMessageDescriptorByName(t *testing.T) {
+	fd, err := parseProtobufSchema(testProtoSchema, map[string]string{
+		"common.proto": testProtoCommonSchema,
+	})
+	require.NoError(t, err)
+
+	for name, indexes := range map[string][]int{
+		"testing.Person":       {0},
+		"testing.Other":        {1},
+		"testing.Other.Nested": {1, 0},
+	} {
+		md, actual, err := messageDescriptorByName(fd, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, name, md.GetFullyQualifiedName())
+		assert.Equal(t, indexes, actual, name)
+
+		fromIndexes, err := messageDescriptorByIndexes(fd, actual)
+		require.NoError(t, err, name)
+		assert.Equal(t, name, fromIndexes.GetFullyQualifiedName())
+	}
+
+	// Message types of referenced schemas are not part of the schema.
+	_, _, err = messageDescriptorByName(fd, "testing.Address")
+	require.Error(t, err)
+
+	_, _, err = messageDescriptorByName(fd, "testing.Nope")
+	require.Error(t, err)
+}
+
+func TestProtobufEncoderMessageName(t *testing.T) {
+	refs := map[string]string{
+		"common.proto": testProtoCommonSchema,
+	}
+
+	tests := []struct {
+		messageName string
+		input       string
+		output      string
+	}{
+		{
+			input:  `{"name":"foo","address":{"city":"bar"}}`,
+			output: "\x00\x0a\x03foo\x12\x05\x0a\x03bar",
+		},
+		{
+			messageName: "testing.Other",
+			input:       `{"id":5}`,
+			output:      "\x02\x02\x08\x05",
+		},
+		{
+			messageName: "testing.Other.Nested",
+			input:       `{"value":"baz"}`,
+			output:      "\x04\x02\x00\x0a\x03baz",
+		},
+	}
+
+	decoder, err := newProtobufDecoder(testProtoSchema, refs)
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		encoder, err := newProtobufEncoder(testProtoSchema, refs, test.messageName)
+		require.NoError(t, err, test.messageName)
+
+		msg := service.NewMessage([]byte(test.input))
+		require.NoError(t, encoder(msg), test.messageName)
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, test.output, string(b), test.messageName)
+
+		require.NoError(t, decoder(msg), test.messageName)
+		b, err = msg.AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, test.input, string(b), test.messageName)
+	}
+
+	_, err = newProtobufEncoder(testProtoSchema, refs, "testing.Nope")
+	require.Error(t, err)
+}
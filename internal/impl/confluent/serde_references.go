@@ -0,0 +1,67 @@
+package confluent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent/sr"
+)
+
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+	schemaTypeJSON     = "JSON"
+)
+
+// schemaPayload is the subset of a schema registry response describing a
+// schema that we care about.
+type schemaPayload struct {
+	ID         int                  `json:"id"`
+	Schema     string               `json:"schema"`
+	SchemaType string               `json:"schemaType"`
+	References []sr.SchemaReference `json:"references"`
+}
+
+// normalisedType returns the type of the schema, where an empty type means
+// Avro for compatibility with older registries.
+func (s schemaPayload) normalisedType() string {
+	if s.SchemaType == "" {
+		return schemaTypeAvro
+	}
+	return s.SchemaType
+}
+
+// referenceResolver obtains the schemas referenced by other schemas from the
+// subject versions they are registered under.
+type referenceResolver struct {
+	client *sr.Client
+}
+
+// resolve returns the schemas of a list of references, along with all schemas
+// that they reference in turn, keyed by the names they are referenced by.
+func (r *referenceResolver) resolve(ctx context.Context, refs []sr.SchemaReference) (map[string]string, error) {
+	resolved := map[string]string{}
+	if err := r.resolveInto(ctx, resolved, refs); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+func (r *referenceResolver) resolveInto(ctx context.Context, resolved map[string]string, refs []sr.SchemaReference) error {
+	for _, ref := range refs {
+		if _, exists := resolved[ref.Name]; exists {
+			continue
+		}
+
+		info, err := r.client.GetSchemaBySubjectAndVersion(ctx, ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("failed to resolve schema reference '%v' to subject '%v' version %v: %w", ref.Name, ref.Subject, ref.Version, err)
+		}
+		resolved[ref.Name] = info.Schema
+
+		if err := r.resolveInto(ctx, resolved, info.References); err != nil {
+			return err
+		}
+	}
+	return nil
+}
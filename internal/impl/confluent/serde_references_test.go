@@ -0,0 +1,69 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent/sr"
+)
+
+func TestReferenceResolver(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		switch r.URL.EscapedPath() {
+		case "/subjects/foo%2Fcommon/versions/1":
+			_, _ = w.Write([]byte(`{"schema":"common","references":[{"name":"nested.proto","subject":"nested","version":2}]}`))
+		case "/subjects/nested/versions/2":
+			_, _ = w.Write([]byte(`{"schema":"nested"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	client, err := sr.NewClient(ts.URL, nil)
+	require.NoError(t, err)
+
+	r := &referenceResolver{client: client}
+
+	resolved, err := r.resolve(context.Background(), []sr.SchemaReference{
+		{Name: "common.proto", Subject: "foo/common", Version: 1},
+		{Name: "nested.proto", Subject: "nested", Version: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"common.proto": "common",
+		"nested.proto": "nested",
+	}, resolved)
+
+	// References that were already resolved through other references are not
+	// requested again.
+	assert.Equal(t, []string{
+		"/subjects/foo%2Fcommon/versions/1",
+		"/subjects/nested/versions/2",
+	}, paths)
+
+	_, err = r.resolve(context.Background(), []sr.SchemaReference{
+		{Name: "missing.proto", Subject: "missing", Version: 1},
+	})
+	require.Error(t, err)
+	assert.True(t, sr.IsNotFound(err))
+	assert.Contains(t, err.Error(), "failed to resolve schema reference 'missing.proto'")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = r.resolve(ctx, []sr.SchemaReference{
+		{Name: "nested.proto", Subject: "nested", Version: 2},
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
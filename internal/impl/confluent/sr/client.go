@@ -0,0 +1,212 @@
+// Package sr provides a client for the Confluent Schema Registry API along
+// with helpers for the wire format and subject naming strategies used by
+// Confluent serializers, which allows components other than the schema
+// registry processors to encode and decode messages with registered schemas.
+package sr
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// SchemaInfo describes a schema registered with the schema registry.
+type SchemaInfo struct {
+	ID int `json:"id,omitempty"`
+
+	// Type is the type of the schema, which is empty for Avro schemas.
+	Type string `json:"schemaType,omitempty"`
+
+	Schema string `json:"schema"`
+
+	// References are the other schemas that the schema imports.
+	References []SchemaReference `json:"references,omitempty"`
+}
+
+// SchemaReference is a reference from a schema to another schema registered
+// under a subject, where the name is the name by which the schema is imported.
+type SchemaReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Error is returned when the schema registry responds with an unsuccessful
+// status code.
+type Error struct {
+	StatusCode int    `json:"-"`
+	ErrorCode  int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("schema registry responded with status code %v", e.StatusCode)
+	}
+	return fmt.Sprintf("schema registry responded with status code %v: %v", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if an error indicates that a requested schema or
+// subject does not exist.
+func IsNotFound(err error) bool {
+	var sErr *Error
+	return errors.As(err, &sErr) && sErr.StatusCode == http.StatusNotFound
+}
+
+// Client executes requests against the API of a schema registry.
+type Client struct {
+	client  *http.Client
+	baseURL *url.URL
+}
+
+// NewClient creates a schema registry client for a base URL, with an optional
+// custom TLS config.
+func NewClient(urlStr string, tlsConf *tls.Config) (*Client, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	c := &Client{
+		client:  http.DefaultClient,
+		baseURL: u,
+	}
+	if tlsConf != nil {
+		c.client = &http.Client{}
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			c.client.Transport = cloned
+		} else {
+			c.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return c, nil
+}
+
+// GetSchemaByID returns the schema registered under an ID.
+func (c *Client) GetSchemaByID(ctx context.Context, id int) (SchemaInfo, error) {
+	var info SchemaInfo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%v", id), nil, &info); err != nil {
+		return info, err
+	}
+	info.ID = id
+	return info, nil
+}
+
+// GetLatestSchema returns the latest version of the schema of a subject.
+func (c *Client) GetLatestSchema(ctx context.Context, subject string) (SchemaInfo, error) {
+	var info SchemaInfo
+	err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &info)
+	return info, err
+}
+
+// GetSchemaBySubjectAndVersion returns the schema of a version of a subject.
+func (c *Client) GetSchemaBySubjectAndVersion(ctx context.Context, subject string, version int) (SchemaInfo, error) {
+	var info SchemaInfo
+	err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/"+strconv.Itoa(version), nil, &info)
+	return info, err
+}
+
+// RegisterSchema registers a schema under a subject and returns its ID. If the
+// schema is already registered under the subject then the existing ID is
+// returned.
+func (c *Client) RegisterSchema(ctx context.Context, subject string, schema SchemaInfo) (int, error) {
+	schema.ID = 0
+	var res SchemaInfo
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schema, &res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+// LookupSchema returns the ID of a schema that has already been registered
+// under a subject.
+func (c *Client) LookupSchema(ctx context.Context, subject string, schema SchemaInfo) (int, error) {
+	schema.ID = 0
+	var res SchemaInfo
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), schema, &res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+// do executes a request against a path relative to the base URL, where any
+// segments of the path that are taken from user input must already be escaped.
+func (c *Client) do(ctx context.Context, method, reqPath string, body, out interface{}) error {
+	reqURL := *c.baseURL
+	reqURL.RawPath = path.Join(c.baseURL.EscapedPath(), reqPath)
+
+	var err error
+	if reqURL.Path, err = url.PathUnescape(reqURL.RawPath); err != nil {
+		return err
+	}
+
+	var bodyBytes []byte
+	if body != nil {
+		if bodyBytes, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for i := 0; i < 3; i++ {
+		var reqBody io.Reader = http.NoBody
+		if bodyBytes != nil {
+			reqBody = bytes.NewReader(bodyBytes)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), reqBody)
+		if err != nil {
+			return err
+		}
+		req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+		if bodyBytes != nil {
+			req.Header.Add("Content-Type", "application/vnd.schemaregistry.v1+json")
+		}
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		resBytes, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if res.StatusCode != http.StatusOK {
+			sErr := &Error{StatusCode: res.StatusCode}
+			_ = json.Unmarshal(resBytes, sErr)
+			if res.StatusCode < http.StatusInternalServerError {
+				// Client errors such as missing subjects or incompatible
+				// schemas aren't going to change if we try again.
+				return sErr
+			}
+			lastErr = sErr
+			continue
+		}
+
+		if err := json.Unmarshal(resBytes, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		return nil
+	}
+	return lastErr
+}
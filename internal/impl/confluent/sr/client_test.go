@@ -0,0 +1,79 @@
+package sr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientErrors(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v1/subjects/foo/versions/latest":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40401,"message":"Subject 'foo' not found."}`))
+		case "/v1/subjects/bar/versions/latest":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`{"id":3,"schema":"\"string\""}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	c, err := NewClient(ts.URL+"/v1", nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = c.GetLatestSchema(ctx, "foo")
+	require.Error(t, err)
+	assert.True(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), "Subject 'foo' not found.")
+	assert.Equal(t, 1, requests)
+
+	requests = 0
+	_, err = c.GetLatestSchema(ctx, "bar")
+	require.Error(t, err)
+	assert.False(t, IsNotFound(err))
+	assert.Equal(t, 3, requests)
+
+	info, err := c.GetLatestSchema(ctx, "baz")
+	require.NoError(t, err)
+	assert.Equal(t, SchemaInfo{ID: 3, Schema: `"string"`}, info)
+}
+
+func TestClientEscapesSubjects(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"id":3,"schema":"\"string\""}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	c, err := NewClient(ts.URL+"/v1", nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	subject := "foo/bar baz?"
+
+	_, err = c.GetLatestSchema(ctx, subject)
+	require.NoError(t, err)
+
+	_, err = c.RegisterSchema(ctx, subject, SchemaInfo{Schema: `"string"`})
+	require.NoError(t, err)
+
+	_, err = c.LookupSchema(ctx, subject, SchemaInfo{Schema: `"string"`})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"GET /v1/subjects/foo%2Fbar%20baz%3F/versions/latest",
+		"POST /v1/subjects/foo%2Fbar%20baz%3F/versions",
+		"POST /v1/subjects/foo%2Fbar%20baz%3F",
+	}, paths)
+}
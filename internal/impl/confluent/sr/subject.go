@@ -0,0 +1,94 @@
+package sr
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SubjectNameStrategy determines the subject that the schema of a message is
+// registered under.
+type SubjectNameStrategy string
+
+// The subject name strategies of Confluent serializers.
+const (
+	// TopicName uses the topic name suffixed with -value.
+	TopicName SubjectNameStrategy = "topic_name"
+
+	// RecordName uses the fully qualified name of the record.
+	RecordName SubjectNameStrategy = "record_name"
+
+	// TopicRecordName uses the topic name followed by the fully qualified
+	// name of the record, separated by a hyphen.
+	TopicRecordName SubjectNameStrategy = "topic_record_name"
+)
+
+// NeedsRecordName returns true if the strategy derives subjects from the name
+// of the record.
+func (s SubjectNameStrategy) NeedsRecordName() bool {
+	return s == RecordName || s == TopicRecordName
+}
+
+// Subject returns the subject for a topic and the fully qualified name of a
+// record.
+func (s SubjectNameStrategy) Subject(topic, recordName string) (string, error) {
+	switch s {
+	case TopicName:
+		return topic + "-value", nil
+	case RecordName:
+		return recordName, nil
+	case TopicRecordName:
+		return topic + "-" + recordName, nil
+	}
+	return "", fmt.Errorf("subject name strategy %v not recognised", string(s))
+}
+
+// AvroRecordName returns the fully qualified name of the record defined by an
+// Avro schema.
+func AvroRecordName(schema string) (string, error) {
+	var s struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		return "", fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if s.Name == "" {
+		return "", errors.New("schema does not define a named record")
+	}
+	if s.Namespace == "" || strings.Contains(s.Name, ".") {
+		return s.Name, nil
+	}
+	return s.Namespace + "." + s.Name, nil
+}
+
+// AppendWithID returns the payload of a message prefixed with the magic byte
+// and schema ID of the Confluent wire format.
+func AppendWithID(id int, payload []byte) []byte {
+	b := make([]byte, len(payload)+5)
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	copy(b[5:], payload)
+	return b
+}
+
+// ExtractID returns the schema ID and remaining payload of a message encoded
+// in the Confluent wire format.
+func ExtractID(b []byte) (id int, remaining []byte, err error) {
+	if len(b) == 0 {
+		err = errors.New("message is empty")
+		return
+	}
+	if b[0] != 0 {
+		err = fmt.Errorf("serialization format version number %v not supported", b[0])
+		return
+	}
+	if len(b) < 5 {
+		err = errors.New("message is too short to contain a schema ID")
+		return
+	}
+	id = int(binary.BigEndian.Uint32(b[1:5]))
+	remaining = b[5:]
+	return
+}
@@ -0,0 +1,65 @@
+package sr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubjectNameStrategies(t *testing.T) {
+	for _, test := range []struct {
+		strategy SubjectNameStrategy
+		expected string
+	}{
+		{strategy: TopicName, expected: "foo-value"},
+		{strategy: RecordName, expected: "com.example.Bar"},
+		{strategy: TopicRecordName, expected: "foo-com.example.Bar"},
+	} {
+		subject, err := test.strategy.Subject("foo", "com.example.Bar")
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, subject)
+	}
+
+	_, err := SubjectNameStrategy("nope").Subject("foo", "bar")
+	require.Error(t, err)
+}
+
+func TestAvroRecordName(t *testing.T) {
+	for _, test := range []struct {
+		schema   string
+		expected string
+		errStr   string
+	}{
+		{schema: `{"type":"record","name":"Bar","namespace":"com.example","fields":[]}`, expected: "com.example.Bar"},
+		{schema: `{"type":"record","name":"Bar","fields":[]}`, expected: "Bar"},
+		{schema: `{"type":"record","name":"org.example.Bar","namespace":"com.example","fields":[]}`, expected: "org.example.Bar"},
+		{schema: `"string"`, errStr: "failed to parse schema"},
+		{schema: `{"type":"array","items":"string"}`, errStr: "schema does not define a named record"},
+	} {
+		name, err := AvroRecordName(test.schema)
+		if test.errStr != "" {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, name)
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	b := AppendWithID(258, []byte("foo"))
+	assert.Equal(t, []byte{0, 0, 0, 1, 2, 'f', 'o', 'o'}, b)
+
+	id, remaining, err := ExtractID(b)
+	require.NoError(t, err)
+	assert.Equal(t, 258, id)
+	assert.Equal(t, "foo", string(remaining))
+
+	_, _, err = ExtractID([]byte{0, 1})
+	require.Error(t, err)
+
+	_, _, err = ExtractID([]byte{1, 0, 0, 0, 1})
+	require.Error(t, err)
+}
@@ -0,0 +1,828 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dbFieldDirectory     = "directory"
+	dbFieldLimit         = "limit"
+	dbFieldSegmentSize   = "segment_size"
+	dbFieldFsync         = "fsync"
+	dbFieldFsyncInterval = "fsync_interval"
+)
+
+const (
+	dbFsyncAlways   = "always"
+	dbFsyncInterval = "interval"
+	dbFsyncNever    = "never"
+)
+
+func diskBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Summary("Stores consumed messages in append-only segment files on disk and acknowledges them at the input level once written. Messages that have not been acknowledged downstream are replayed after a restart.").
+		Description(`
+This buffer is appropriate when consuming messages from inputs that do not gracefully handle back pressure, but where messages should not be lost when Benthos is restarted or crashes.
+
+Message batches are written sequentially to segment files within the configured directory, along with their metadata. Once a segment has been written up to the ` + "`segment_size`" + ` a new segment is created. The buffer keeps track of the oldest message batch that has not yet been acknowledged downstream and records this position in a checkpoint file, segments that are entirely behind this position are deleted.
+
+This buffer has a configurable limit, where consumption will be stopped with back pressure upstream if the total size of unacknowledged messages in the buffer reaches this amount.
+
+## Delivery Guarantees
+
+Messages are acknowledged at the input level as soon as they have been written to disk, and how soon after that they are flushed to the underlying storage device depends on the ` + "`fsync`" + ` policy:
+
+- ` + "`always`" + `: Segment files are synced after every write, and therefore messages are only acknowledged once they are persisted.
+- ` + "`interval`" + `: Segment files are synced periodically according to ` + "`fsync_interval`" + `, a machine crash may lose messages written within the interval.
+- ` + "`never`" + `: Syncing is left entirely to the operating system.
+
+The checkpoint only advances past a message batch once it and all batches written before it have been acknowledged, therefore message batches that were acknowledged out of order may be delivered more than once after a restart.`).
+		Field(service.NewStringField(dbFieldDirectory).
+			Description("The directory within which to store segment files. The directory will be created if it does not already exist, and must not be shared with any other buffer.")).
+		Field(service.NewIntField(dbFieldLimit).
+			Description("The maximum total size (in bytes) of unacknowledged messages to allow before applying backpressure upstream.").
+			Default(1073741824)).
+		Field(service.NewIntField(dbFieldSegmentSize).
+			Description("The size (in bytes) beyond which a segment file is closed and a new one created. Segments are only removed once all messages within them are acknowledged, so smaller segments result in disk space being released sooner.").
+			Default(16777216).
+			Advanced()).
+		Field(service.NewStringEnumField(dbFieldFsync, dbFsyncAlways, dbFsyncInterval, dbFsyncNever).
+			Description("The policy for syncing segment files to the underlying storage device.").
+			Default(dbFsyncInterval)).
+		Field(service.NewDurationField(dbFieldFsyncInterval).
+			Description("The period at which segment files are synced when the `fsync` policy is `interval`.").
+			Default("1s").
+			Advanced())
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"disk", diskBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newDiskBufferFromConfig(conf, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newDiskBufferFromConfig(conf *service.ParsedConfig, log *service.Logger) (*diskBuffer, error) {
+	directory, err := conf.FieldString(dbFieldDirectory)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := conf.FieldInt(dbFieldLimit)
+	if err != nil {
+		return nil, err
+	}
+	segmentSize, err := conf.FieldInt(dbFieldSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	fsync, err := conf.FieldString(dbFieldFsync)
+	if err != nil {
+		return nil, err
+	}
+	fsyncInterval, err := conf.FieldDuration(dbFieldFsyncInterval)
+	if err != nil {
+		return nil, err
+	}
+	return newDiskBuffer(diskBufferOpts{
+		directory:     directory,
+		limit:         limit,
+		segmentSize:   int64(segmentSize),
+		fsync:         fsync,
+		fsyncInterval: fsyncInterval,
+	}, log)
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	diskSegmentSuffix      = ".seg"
+	diskCheckpointFile     = "checkpoint"
+	diskRecordHeaderLength = 8
+)
+
+var errDiskRecordCorrupt = errors.New("segment record is corrupt")
+
+// diskPosition identifies the start of a record within the buffer by its
+// segment index and byte offset within that segment.
+type diskPosition struct {
+	segment uint64
+	offset  int64
+}
+
+func (p diskPosition) less(o diskPosition) bool {
+	if p.segment == o.segment {
+		return p.offset < o.offset
+	}
+	return p.segment < o.segment
+}
+
+type diskBufferOpts struct {
+	directory     string
+	limit         int
+	segmentSize   int64
+	fsync         string
+	fsyncInterval time.Duration
+}
+
+type diskRetry struct {
+	batch   service.MessageBatch
+	size    int
+	resolve func() interface{}
+}
+
+type diskBuffer struct {
+	opts diskBufferOpts
+	log  *service.Logger
+
+	cond *sync.Cond
+
+	// Write state
+	writeFile    *os.File
+	writePos     diskPosition
+	pendingFsync bool
+
+	// Read state
+	readFile *os.File
+	readBuf  *bufio.Reader
+	readPos  diskPosition
+	retries  []diskRetry
+
+	// Acknowledgement state
+	checkpointer *checkpoint.Type
+	committed    diskPosition
+
+	bytes      int
+	endOfInput bool
+	closed     bool
+
+	shutSig chan struct{}
+	closeWG sync.WaitGroup
+}
+
+func newDiskBuffer(opts diskBufferOpts, log *service.Logger) (*diskBuffer, error) {
+	if opts.directory == "" {
+		return nil, errors.New("a directory must be specified")
+	}
+	if opts.segmentSize <= 0 {
+		return nil, fmt.Errorf("segment size must be greater than zero, got %v", opts.segmentSize)
+	}
+	if opts.fsync == dbFsyncInterval && opts.fsyncInterval <= 0 {
+		return nil, fmt.Errorf("fsync interval must be greater than zero, got %v", opts.fsyncInterval)
+	}
+	if err := os.MkdirAll(opts.directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &diskBuffer{
+		opts:         opts,
+		log:          log,
+		cond:         sync.NewCond(&sync.Mutex{}),
+		checkpointer: checkpoint.New(),
+		shutSig:      make(chan struct{}),
+	}
+	if err := d.recover(); err != nil {
+		d.closeFiles()
+		return nil, err
+	}
+
+	if opts.fsync == dbFsyncInterval {
+		d.closeWG.Add(1)
+		go d.fsyncLoop()
+	}
+	return d, nil
+}
+
+func (d *diskBuffer) segmentPath(index uint64) string {
+	return filepath.Join(d.opts.directory, fmt.Sprintf("%020d%v", index, diskSegmentSuffix))
+}
+
+func (d *diskBuffer) listSegments() ([]uint64, error) {
+	entries, err := os.ReadDir(d.opts.directory)
+	if err != nil {
+		return nil, err
+	}
+	var segments []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, diskSegmentSuffix) {
+			continue
+		}
+		index, err := strconv.ParseUint(strings.TrimSuffix(name, diskSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, index)
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i] < segments[j]
+	})
+	return segments, nil
+}
+
+func (d *diskBuffer) readCheckpoint() (diskPosition, error) {
+	var pos diskPosition
+	b, err := os.ReadFile(filepath.Join(d.opts.directory, diskCheckpointFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pos, nil
+		}
+		return pos, err
+	}
+	if len(b) != 16 {
+		return pos, fmt.Errorf("checkpoint file has unexpected length %v", len(b))
+	}
+	pos.segment = binary.BigEndian.Uint64(b[:8])
+	pos.offset = int64(binary.BigEndian.Uint64(b[8:]))
+	return pos, nil
+}
+
+func (d *diskBuffer) writeCheckpoint(pos diskPosition) error {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b[:8], pos.segment)
+	binary.BigEndian.PutUint64(b[8:], uint64(pos.offset))
+
+	tmpPath := filepath.Join(d.opts.directory, diskCheckpointFile+".tmp")
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if d.opts.fsync == dbFsyncAlways {
+		if err = f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(d.opts.directory, diskCheckpointFile))
+}
+
+// recover scans the segments remaining from a previous run, removing those
+// that are fully acknowledged and truncating any partially written record from
+// the tail, and then opens the read and write positions.
+func (d *diskBuffer) recover() error {
+	committed, err := d.readCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	segments, err := d.listSegments()
+	if err != nil {
+		return err
+	}
+
+	var live []uint64
+	for _, index := range segments {
+		if index < committed.segment {
+			if err := os.Remove(d.segmentPath(index)); err != nil {
+				return err
+			}
+			continue
+		}
+		live = append(live, index)
+	}
+
+	if len(live) == 0 || live[0] > committed.segment {
+		// The committed segment no longer exists, which means everything up to
+		// the first remaining segment has been consumed.
+		if len(live) > 0 {
+			committed = diskPosition{segment: live[0]}
+		} else {
+			committed = diskPosition{segment: committed.segment + 1}
+		}
+	}
+
+	for i, index := range live {
+		from := int64(0)
+		if index == committed.segment {
+			from = committed.offset
+		}
+		validEnd, size, err := d.scanSegment(index, from)
+		if err != nil {
+			return err
+		}
+		d.bytes += size
+		if i == len(live)-1 {
+			if err := os.Truncate(d.segmentPath(index), validEnd); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.committed = committed
+	d.readPos = committed
+	d.writePos = committed
+	if len(live) > 0 {
+		lastIndex := live[len(live)-1]
+		info, err := os.Stat(d.segmentPath(lastIndex))
+		if err != nil {
+			return err
+		}
+		d.writePos = diskPosition{segment: lastIndex, offset: info.Size()}
+	}
+
+	if err := d.openWriteSegment(d.writePos); err != nil {
+		return err
+	}
+	return d.openReadSegment(d.readPos)
+}
+
+// scanSegment walks the records of a segment from an offset and returns the
+// offset at which the last valid record ends and the total size of the valid
+// records encountered.
+func (d *diskBuffer) scanSegment(index uint64, from int64) (validEnd int64, size int, err error) {
+	f, err := os.Open(d.segmentPath(index))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(from, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	r := bufio.NewReader(f)
+	validEnd = from
+	for {
+		payload, rErr := readDiskRecord(r)
+		if rErr != nil {
+			if !errors.Is(rErr, io.EOF) {
+				d.log.Warnf("Discarding segment %v from offset %v: %v", index, validEnd, rErr)
+			}
+			return validEnd, size, nil
+		}
+		validEnd += int64(diskRecordHeaderLength + len(payload))
+		size += len(payload)
+	}
+}
+
+func (d *diskBuffer) openWriteSegment(pos diskPosition) error {
+	f, err := os.OpenFile(d.segmentPath(pos.segment), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	d.writeFile = f
+	d.writePos = pos
+	return nil
+}
+
+func (d *diskBuffer) openReadSegment(pos diskPosition) error {
+	f, err := os.Open(d.segmentPath(pos.segment))
+	if err != nil {
+		return err
+	}
+	if _, err = f.Seek(pos.offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	d.readFile = f
+	d.readBuf = bufio.NewReader(f)
+	d.readPos = pos
+	return nil
+}
+
+func (d *diskBuffer) closeFiles() {
+	if d.writeFile != nil {
+		if d.opts.fsync != dbFsyncNever {
+			_ = d.writeFile.Sync()
+		}
+		_ = d.writeFile.Close()
+		d.writeFile = nil
+	}
+	if d.readFile != nil {
+		_ = d.readFile.Close()
+		d.readFile = nil
+	}
+}
+
+func (d *diskBuffer) fsyncLoop() {
+	defer d.closeWG.Done()
+
+	ticker := time.NewTicker(d.opts.fsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.shutSig:
+			return
+		}
+		d.cond.L.Lock()
+		if d.pendingFsync && d.writeFile != nil {
+			if err := d.writeFile.Sync(); err != nil {
+				d.log.Errorf("Failed to sync segment file: %v", err)
+			} else {
+				d.pendingFsync = false
+			}
+		}
+		d.cond.L.Unlock()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func writeDiskRecord(w io.Writer, payload []byte) error {
+	record := make([]byte, diskRecordHeaderLength, diskRecordHeaderLength+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	_, err := w.Write(append(record, payload...))
+	return err
+}
+
+func readDiskRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, diskRecordHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errDiskRecordCorrupt
+		}
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errDiskRecordCorrupt
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errDiskRecordCorrupt
+	}
+	return payload, nil
+}
+
+func appendDiskUvarint(b []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(b, tmp[:n]...)
+}
+
+func appendDiskBytes(b, v []byte) []byte {
+	b = appendDiskUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func encodeDiskBatch(batch service.MessageBatch) ([]byte, error) {
+	b := appendDiskUvarint(nil, uint64(len(batch)))
+	for _, msg := range batch {
+		var metaKeys []string
+		_ = msg.MetaWalk(func(k, _ string) error {
+			metaKeys = append(metaKeys, k)
+			return nil
+		})
+		sort.Strings(metaKeys)
+
+		b = appendDiskUvarint(b, uint64(len(metaKeys)))
+		for _, k := range metaKeys {
+			v, _ := msg.MetaGet(k)
+			b = appendDiskBytes(b, []byte(k))
+			b = appendDiskBytes(b, []byte(v))
+		}
+
+		content, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		b = appendDiskBytes(b, content)
+	}
+	return b, nil
+}
+
+type diskBatchDecoder struct {
+	b []byte
+}
+
+func (d *diskBatchDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		return 0, errDiskRecordCorrupt
+	}
+	d.b = d.b[n:]
+	return v, nil
+}
+
+func (d *diskBatchDecoder) bytes() ([]byte, error) {
+	l, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(d.b)) < l {
+		return nil, errDiskRecordCorrupt
+	}
+	v := d.b[:l]
+	d.b = d.b[l:]
+	return v, nil
+}
+
+func decodeDiskBatch(payload []byte) (service.MessageBatch, error) {
+	dec := &diskBatchDecoder{b: payload}
+
+	count, err := dec.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, count)
+	for i := uint64(0); i < count; i++ {
+		metaCount, err := dec.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		meta := make([][2]string, 0, metaCount)
+		for j := uint64(0); j < metaCount; j++ {
+			k, err := dec.bytes()
+			if err != nil {
+				return nil, err
+			}
+			v, err := dec.bytes()
+			if err != nil {
+				return nil, err
+			}
+			meta = append(meta, [2]string{string(k), string(v)})
+		}
+
+		content, err := dec.bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		msg := service.NewMessage(append([]byte(nil), content...))
+		for _, kv := range meta {
+			msg.MetaSet(kv[0], kv[1])
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+//------------------------------------------------------------------------------
+
+// nextRecord attempts to read the next record from the read position, moving
+// onto later segments when the current one is exhausted. Returns io.EOF when
+// there are no further records available. Must be called with the lock held.
+func (d *diskBuffer) nextRecord() ([]byte, diskPosition, error) {
+	for {
+		if d.readPos == d.writePos {
+			return nil, d.readPos, io.EOF
+		}
+
+		startPos := d.readPos
+		payload, err := readDiskRecord(d.readBuf)
+		if err == nil {
+			d.readPos.offset += int64(diskRecordHeaderLength + len(payload))
+			return payload, startPos, nil
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, errDiskRecordCorrupt) {
+			return nil, startPos, err
+		}
+		if d.readPos.segment >= d.writePos.segment {
+			// The writer has not yet flushed this record.
+			if err := d.openReadSegment(d.readPos); err != nil {
+				return nil, startPos, err
+			}
+			return nil, startPos, io.EOF
+		}
+		if errors.Is(err, errDiskRecordCorrupt) {
+			d.log.Warnf("Skipping the remainder of segment %v from offset %v: %v", d.readPos.segment, d.readPos.offset, err)
+		}
+
+		_ = d.readFile.Close()
+		d.readFile = nil
+		if err := d.openReadSegment(diskPosition{segment: d.readPos.segment + 1}); err != nil {
+			return nil, startPos, err
+		}
+	}
+}
+
+// commit is called with the latest fully resolved read position, and persists
+// it before removing any segments that are no longer needed. Must be called
+// with the lock held.
+func (d *diskBuffer) commit(pos diskPosition) error {
+	if !d.committed.less(pos) {
+		return nil
+	}
+	if err := d.writeCheckpoint(pos); err != nil {
+		return err
+	}
+	for index := d.committed.segment; index < pos.segment; index++ {
+		if err := os.Remove(d.segmentPath(index)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	d.committed = pos
+	return nil
+}
+
+func (d *diskBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	go func() {
+		<-ctx.Done()
+		d.cond.Broadcast()
+	}()
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	var retry diskRetry
+	for {
+		if d.closed {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		if len(d.retries) > 0 {
+			retry = d.retries[0]
+			d.retries[0] = diskRetry{}
+			d.retries = d.retries[1:]
+			break
+		}
+
+		payload, startPos, err := d.nextRecord()
+		if err == nil {
+			batch, err := decodeDiskBatch(payload)
+			if err != nil {
+				d.log.Errorf("Failed to decode record at segment %v offset %v: %v", startPos.segment, startPos.offset, err)
+				batch = nil
+			}
+			endPos := d.readPos
+			resolve := d.checkpointer.Track(endPos, 1)
+			if len(batch) == 0 {
+				// Nothing to deliver, resolve immediately so that the
+				// checkpoint is able to move past this record.
+				d.bytes -= len(payload)
+				if hw, ok := resolve().(diskPosition); ok {
+					if err := d.commit(hw); err != nil {
+						return nil, nil, err
+					}
+				}
+				continue
+			}
+			retry = diskRetry{
+				batch:   batch,
+				size:    len(payload),
+				resolve: resolve,
+			}
+			break
+		}
+		if !errors.Is(err, io.EOF) {
+			return nil, nil, err
+		}
+		if d.endOfInput {
+			return nil, nil, service.ErrEndOfBuffer
+		}
+
+		d.cond.Wait()
+	}
+
+	d.cond.Broadcast()
+	return retry.batch.Copy(), func(ctx context.Context, err error) error {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+		defer d.cond.Broadcast()
+
+		if err != nil {
+			d.retries = append(d.retries, retry)
+			return nil
+		}
+
+		d.bytes -= retry.size
+		if hw, ok := retry.resolve().(diskPosition); ok {
+			return d.commit(hw)
+		}
+		return nil
+	}, nil
+}
+
+func (d *diskBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	payload, err := encodeDiskBatch(msgBatch)
+	if err != nil {
+		return err
+	}
+	if len(payload) > d.opts.limit {
+		return component.ErrMessageTooLarge
+	}
+
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	if d.closed {
+		return component.ErrTypeClosed
+	}
+
+	for (d.bytes + len(payload)) > d.opts.limit {
+		d.cond.Wait()
+		if d.closed {
+			return component.ErrTypeClosed
+		}
+	}
+
+	if d.writePos.offset > 0 && d.writePos.offset+int64(diskRecordHeaderLength+len(payload)) > d.opts.segmentSize {
+		if d.opts.fsync != dbFsyncNever {
+			if err := d.writeFile.Sync(); err != nil {
+				return err
+			}
+		}
+		if err := d.writeFile.Close(); err != nil {
+			return err
+		}
+		d.writeFile = nil
+		if err := d.openWriteSegment(diskPosition{segment: d.writePos.segment + 1}); err != nil {
+			return err
+		}
+	}
+
+	if err := writeDiskRecord(d.writeFile, payload); err != nil {
+		return err
+	}
+	switch d.opts.fsync {
+	case dbFsyncAlways:
+		if err := d.writeFile.Sync(); err != nil {
+			return err
+		}
+	case dbFsyncInterval:
+		d.pendingFsync = true
+	}
+
+	d.writePos.offset += int64(diskRecordHeaderLength + len(payload))
+	d.bytes += len(payload)
+
+	d.cond.Broadcast()
+	return aFn(ctx, nil)
+}
+
+func (d *diskBuffer) EndOfInput() {
+	go func() {
+		d.cond.L.Lock()
+		defer d.cond.L.Unlock()
+
+		d.endOfInput = true
+		d.cond.Broadcast()
+
+		for d.bytes > 0 && !d.closed {
+			d.cond.Wait()
+		}
+		d.closed = true
+		d.cond.Broadcast()
+	}()
+}
+
+func (d *diskBuffer) Close(ctx context.Context) error {
+	d.cond.L.Lock()
+	if !d.closed {
+		d.closed = true
+	}
+	alreadyShut := d.writeFile == nil
+	if !alreadyShut {
+		d.closeFiles()
+	}
+	d.cond.Broadcast()
+	d.cond.L.Unlock()
+
+	if !alreadyShut {
+		close(d.shutSig)
+	}
+
+	waitChan := make(chan struct{})
+	go func() {
+		d.closeWG.Wait()
+		close(waitChan)
+	}()
+	select {
+	case <-waitChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
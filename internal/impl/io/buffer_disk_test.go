@@ -0,0 +1,254 @@
+package io
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func diskBufFromConf(t *testing.T, conf string) *diskBuffer {
+	t.Helper()
+
+	parsedConf, err := diskBufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	buf, err := newDiskBufferFromConfig(parsedConf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	return buf
+}
+
+func diskNoopAck(context.Context, error) error {
+	return nil
+}
+
+func TestDiskBufferBasic(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	buf := diskBufFromConf(t, fmt.Sprintf(`
+directory: %v
+fsync: always
+`, dir))
+	defer buf.Close(ctx)
+
+	n := 100
+	for i := 0; i < n; i++ {
+		msg := service.NewMessage([]byte(fmt.Sprintf("hello world %v", i)))
+		msg.MetaSet("index", fmt.Sprintf("%v", i))
+		require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{
+			msg, service.NewMessage([]byte("second")),
+		}, diskNoopAck))
+	}
+
+	for i := 0; i < n; i++ {
+		b, ackFn, err := buf.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.Len(t, b, 2)
+
+		mBytes, err := b[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("hello world %v", i), string(mBytes))
+
+		v, _ := b[0].MetaGet("index")
+		assert.Equal(t, fmt.Sprintf("%v", i), v)
+
+		mBytes, err = b[1].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(mBytes))
+
+		require.NoError(t, ackFn(ctx, nil))
+	}
+
+	buf.cond.L.Lock()
+	assert.Equal(t, 0, buf.bytes)
+	buf.cond.L.Unlock()
+}
+
+func TestDiskBufferNackRedelivers(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	buf := diskBufFromConf(t, fmt.Sprintf(`
+directory: %v
+`, dir))
+	defer buf.Close(ctx)
+
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("foo"))}, diskNoopAck))
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("bar"))}, diskNoopAck))
+
+	b, ackFn, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, b, 1)
+	mBytes, _ := b[0].AsBytes()
+	assert.Equal(t, "foo", string(mBytes))
+
+	require.NoError(t, ackFn(ctx, fmt.Errorf("nope")))
+
+	b, ackFn, err = buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	mBytes, _ = b[0].AsBytes()
+	assert.Equal(t, "foo", string(mBytes))
+	require.NoError(t, ackFn(ctx, nil))
+
+	b, ackFn, err = buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	mBytes, _ = b[0].AsBytes()
+	assert.Equal(t, "bar", string(mBytes))
+	require.NoError(t, ackFn(ctx, nil))
+}
+
+func TestDiskBufferReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	conf := fmt.Sprintf(`
+directory: %v
+segment_size: 64
+fsync: always
+`, dir)
+
+	buf := diskBufFromConf(t, conf)
+	for i := 0; i < 10; i++ {
+		msg := service.NewMessage([]byte(fmt.Sprintf("message %v", i)))
+		msg.MetaSet("foo", fmt.Sprintf("bar%v", i))
+		require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{msg}, diskNoopAck))
+	}
+
+	segments, err := buf.listSegments()
+	require.NoError(t, err)
+	assert.Greater(t, len(segments), 1)
+
+	// Acknowledge the first four messages, then read one more without
+	// acknowledging it.
+	for i := 0; i < 5; i++ {
+		_, ackFn, err := buf.ReadBatch(ctx)
+		require.NoError(t, err)
+		if i < 4 {
+			require.NoError(t, ackFn(ctx, nil))
+		}
+	}
+	require.NoError(t, buf.Close(ctx))
+
+	buf = diskBufFromConf(t, conf)
+	defer buf.Close(ctx)
+
+	for i := 4; i < 10; i++ {
+		b, ackFn, err := buf.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.Len(t, b, 1)
+
+		mBytes, err := b[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("message %v", i), string(mBytes))
+
+		v, _ := b[0].MetaGet("foo")
+		assert.Equal(t, fmt.Sprintf("bar%v", i), v)
+
+		require.NoError(t, ackFn(ctx, nil))
+	}
+
+	segments, err = buf.listSegments()
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestDiskBufferTruncatesPartialRecord(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	conf := fmt.Sprintf(`
+directory: %v
+fsync: always
+`, dir)
+
+	buf := diskBufFromConf(t, conf)
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("foo"))}, diskNoopAck))
+	require.NoError(t, buf.Close(ctx))
+
+	segments, err := buf.listSegments()
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+
+	f, err := os.OpenFile(buf.segmentPath(segments[0]), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 50, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	buf = diskBufFromConf(t, conf)
+	defer buf.Close(ctx)
+
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("bar"))}, diskNoopAck))
+
+	for _, exp := range []string{"foo", "bar"} {
+		b, ackFn, err := buf.ReadBatch(ctx)
+		require.NoError(t, err)
+		mBytes, _ := b[0].AsBytes()
+		assert.Equal(t, exp, string(mBytes))
+		require.NoError(t, ackFn(ctx, nil))
+	}
+}
+
+func TestDiskBufferLimit(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	buf := diskBufFromConf(t, fmt.Sprintf(`
+directory: %v
+limit: 20
+`, filepath.Join(dir, "nested")))
+	defer buf.Close(ctx)
+
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("hello world"))}, diskNoopAck))
+
+	writeErr := make(chan error)
+	go func() {
+		writeErr <- buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("hello world"))}, diskNoopAck)
+	}()
+
+	select {
+	case err := <-writeErr:
+		t.Fatalf("Expected write to block, got: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	_, ackFn, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	select {
+	case err := <-writeErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for blocked write")
+	}
+}
+
+func TestDiskBufferEndOfInput(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	buf := diskBufFromConf(t, fmt.Sprintf(`
+directory: %v
+`, dir))
+	defer buf.Close(ctx)
+
+	require.NoError(t, buf.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte("foo"))}, diskNoopAck))
+	buf.EndOfInput()
+
+	_, ackFn, err := buf.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	_, _, err = buf.ReadBatch(ctx)
+	assert.Equal(t, service.ErrEndOfBuffer, err)
+}
@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// topicCreationConfig describes how topics that do not yet exist are created.
+type topicCreationConfig struct {
+	partitions        int32
+	replicationFactor int16
+	configs           map[string]string
+}
+
+// createTopics creates topics with a given config, topics that already exist
+// are ignored.
+func createTopics(ctx context.Context, cl *kgo.Client, conf topicCreationConfig, timeout time.Duration, topics ...string) error {
+	req := kmsg.NewPtrCreateTopicsRequest()
+	req.TimeoutMillis = int32(timeout.Milliseconds())
+
+	// Sort the config keys so that requests are deterministic.
+	configKeys := make([]string, 0, len(conf.configs))
+	for k := range conf.configs {
+		configKeys = append(configKeys, k)
+	}
+	sort.Strings(configKeys)
+
+	for _, topic := range topics {
+		reqTopic := kmsg.NewCreateTopicsRequestTopic()
+		reqTopic.Topic = topic
+		reqTopic.NumPartitions = conf.partitions
+		reqTopic.ReplicationFactor = conf.replicationFactor
+		for _, k := range configKeys {
+			reqConfig := kmsg.NewCreateTopicsRequestTopicConfig()
+			reqConfig.Name = k
+			v := conf.configs[k]
+			reqConfig.Value = &v
+			reqTopic.Configs = append(reqTopic.Configs, reqConfig)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return err
+	}
+	for _, t := range res.Topics {
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil && !errors.Is(err, kerr.TopicAlreadyExists) {
+			return fmt.Errorf("failed to create topic %v: %w", t.Topic, err)
+		}
+	}
+	return nil
+}
+
+// topicPartitions returns the partitions of each of a list of topics.
+func topicPartitions(ctx context.Context, cl *kgo.Client, topics []string) (map[string][]int32, error) {
+	// An empty list of topics would request the metadata of all topics.
+	if len(topics) == 0 {
+		return map[string][]int32{}, nil
+	}
+
+	req := kmsg.NewPtrMetadataRequest()
+	for _, topic := range topics {
+		reqTopic := kmsg.NewMetadataRequestTopic()
+		topic := topic
+		reqTopic.Topic = &topic
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := map[string][]int32{}
+	for _, t := range res.Topics {
+		if t.Topic == nil {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			return nil, fmt.Errorf("failed to obtain metadata of topic %v: %w", *t.Topic, err)
+		}
+		for _, p := range t.Partitions {
+			partitions[*t.Topic] = append(partitions[*t.Topic], p.Partition)
+		}
+	}
+	for _, ps := range partitions {
+		sort.Slice(ps, func(i, j int) bool { return ps[i] < ps[j] })
+	}
+	return partitions, nil
+}
+
+// The timestamps used in order to list the earliest and latest offsets of
+// partitions.
+const (
+	listOffsetsEarliest int64 = -2
+	listOffsetsLatest   int64 = -1
+)
+
+// listOffsets returns the offsets of partitions at a given timestamp, keyed by
+// topic and then partition.
+func listOffsets(ctx context.Context, cl *kgo.Client, partitions map[string][]int32, timestamp int64) (map[string]map[int32]int64, error) {
+	req := kmsg.NewPtrListOffsetsRequest()
+	for topic, ps := range partitions {
+		reqTopic := kmsg.NewListOffsetsRequestTopic()
+		reqTopic.Topic = topic
+		for _, p := range ps {
+			reqPartition := kmsg.NewListOffsetsRequestTopicPartition()
+			reqPartition.Partition = p
+			reqPartition.Timestamp = timestamp
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := map[string]map[int32]int64{}
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("failed to list offsets of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+			if offsets[t.Topic] == nil {
+				offsets[t.Topic] = map[int32]int64{}
+			}
+			offsets[t.Topic][p.Partition] = p.Offset
+		}
+	}
+	return offsets, nil
+}
+
+// fetchGroupOffsets returns the offsets committed by a consumer group, keyed by
+// topic and then partition, where partitions without a committed offset have
+// an offset of -1. When no partitions are provided the offsets of all topics
+// that the group has committed to are returned.
+func fetchGroupOffsets(ctx context.Context, cl *kgo.Client, group string, partitions map[string][]int32) (map[string]map[int32]int64, error) {
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = group
+	for topic, ps := range partitions {
+		reqTopic := kmsg.NewOffsetFetchRequestTopic()
+		reqTopic.Topic = topic
+		reqTopic.Partitions = ps
+		req.Topics = append(req.Topics, reqTopic)
+	}
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+	if err := kerr.ErrorForCode(res.ErrorCode); err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of consumer group %v: %w", group, err)
+	}
+
+	offsets := map[string]map[int32]int64{}
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("failed to fetch offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+			if offsets[t.Topic] == nil {
+				offsets[t.Topic] = map[int32]int64{}
+			}
+			offsets[t.Topic][p.Partition] = p.Offset
+		}
+	}
+	return offsets, nil
+}
@@ -0,0 +1,323 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent/sr"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func schemaRegistryInputField() *service.ConfigField {
+	return service.NewObjectField("schema_registry",
+		service.NewStringField("url").
+			Description("The base URL of the schema registry service."),
+		service.NewTLSField("tls"),
+	).
+		Description("Optionally decode the values of records with schemas obtained from a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html). The schema ID is extracted from each record and the schema is obtained from the registry and cached. Records that fail to decode remain unchanged and are flagged with an error that can be caught using error handling methods outlined [here](/docs/configuration/error_handling). Currently only Avro schemas are supported, and messages are decoded into [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding) documents.").
+		Optional().
+		Advanced().
+		Version("4.5.0")
+}
+
+func schemaRegistryOutputField() *service.ConfigField {
+	return service.NewObjectField("schema_registry",
+		service.NewStringField("url").
+			Description("The base URL of the schema registry service."),
+		service.NewStringAnnotatedEnumField("subject_name_strategy", map[string]string{
+			string(sr.TopicName):       "The subject is the topic name suffixed with `-value`.",
+			string(sr.RecordName):      "The subject is the fully qualified name of the record defined by the schema.",
+			string(sr.TopicRecordName): "The subject is the topic name followed by the fully qualified name of the record, separated by a hyphen.",
+		}).
+			Description("The strategy used to determine the subject of the schema of each record from its topic. The strategies `record_name` and `topic_record_name` require the field `schema`.").
+			Default(string(sr.TopicName)),
+		service.NewStringField("schema").
+			Description("An Avro schema to encode messages with. When empty the latest schema of the subject of each topic is used, which is refreshed periodically.").
+			Example(`{"type":"record","name":"Order","namespace":"com.example","fields":[{"name":"id","type":"string"}]}`).
+			Default(""),
+		service.NewBoolField("auto_register").
+			Description("Whether to register the `schema` under the subject of each topic when it is not yet registered. When disabled the schema must already be registered.").
+			Default(false),
+		service.NewDurationField("refresh_period").
+			Description("The period after which the schema ID of a subject is obtained again from the registry.").
+			Default("10m"),
+		service.NewBoolField("avro_raw_json").
+			Description("Whether messages should be parsed as raw JSON documents rather than [Avro JSON](https://avro.apache.org/docs/current/spec.html#json_encoding).").
+			Default(false),
+		service.NewTLSField("tls"),
+	).
+		Description("Optionally encode the values of records with schemas from a [Confluent Schema Registry service](https://docs.confluent.io/platform/current/schema-registry/index.html), prefixed with the schema ID following the Confluent wire format. The subject of each record is derived from its topic according to a subject name strategy, and schema IDs are cached for each subject. Messages that fail to encode cause the batch to fail. Currently only Avro schemas are supported.").
+		Optional().
+		Advanced().
+		Version("4.5.0")
+}
+
+//------------------------------------------------------------------------------
+
+// franzSchemaDecoder decodes record values prefixed with a schema ID.
+type franzSchemaDecoder struct {
+	client *sr.Client
+
+	mut    sync.RWMutex
+	codecs map[int]*goavro.Codec
+}
+
+func franzSchemaDecoderFromParsed(conf *service.ParsedConfig) (*franzSchemaDecoder, error) {
+	urlStr, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS("tls")
+	if err != nil {
+		return nil, err
+	}
+	client, err := sr.NewClient(urlStr, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+	return &franzSchemaDecoder{
+		client: client,
+		codecs: map[int]*goavro.Codec{},
+	}, nil
+}
+
+func (d *franzSchemaDecoder) getCodec(ctx context.Context, id int) (*goavro.Codec, error) {
+	d.mut.RLock()
+	codec, exists := d.codecs[id]
+	d.mut.RUnlock()
+	if exists {
+		return codec, nil
+	}
+
+	info, err := d.client.GetSchemaByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain schema %v: %w", id, err)
+	}
+	if info.Type != "" && info.Type != "AVRO" {
+		return nil, fmt.Errorf("schema %v has unsupported type %v", id, info.Type)
+	}
+	if codec, err = goavro.NewCodecForStandardJSON(info.Schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %v: %w", id, err)
+	}
+
+	// Schemas registered under an ID never change and therefore are cached
+	// indefinitely.
+	d.mut.Lock()
+	d.codecs[id] = codec
+	d.mut.Unlock()
+	return codec, nil
+}
+
+// decode replaces the contents of a message with the structured document
+// decoded from it.
+func (d *franzSchemaDecoder) decode(ctx context.Context, msg *service.Message) error {
+	b, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	id, remaining, err := sr.ExtractID(b)
+	if err != nil {
+		return err
+	}
+
+	codec, err := d.getCodec(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := codec.NativeFromBinary(remaining)
+	if err != nil {
+		return err
+	}
+	msg.SetStructured(native)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type cachedSchemaID struct {
+	id      int
+	codec   *goavro.Codec
+	updated time.Time
+}
+
+// franzSchemaEncoder encodes record values with the schema of the subject of
+// their topic.
+type franzSchemaEncoder struct {
+	client        *sr.Client
+	strategy      sr.SubjectNameStrategy
+	schema        string
+	codec         *goavro.Codec
+	recordName    string
+	autoRegister  bool
+	refreshPeriod time.Duration
+	avroRawJSON   bool
+
+	mut       sync.RWMutex
+	subjects  map[string]*cachedSchemaID
+	resolving singleflight.Group
+	nowFn     func() time.Time
+}
+
+func franzSchemaEncoderFromParsed(conf *service.ParsedConfig) (*franzSchemaEncoder, error) {
+	e := &franzSchemaEncoder{
+		subjects: map[string]*cachedSchemaID{},
+		nowFn:    time.Now,
+	}
+
+	urlStr, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS("tls")
+	if err != nil {
+		return nil, err
+	}
+	if e.client, err = sr.NewClient(urlStr, tlsConf); err != nil {
+		return nil, err
+	}
+
+	strategyStr, err := conf.FieldString("subject_name_strategy")
+	if err != nil {
+		return nil, err
+	}
+	e.strategy = sr.SubjectNameStrategy(strategyStr)
+
+	if e.schema, err = conf.FieldString("schema"); err != nil {
+		return nil, err
+	}
+	if e.autoRegister, err = conf.FieldBool("auto_register"); err != nil {
+		return nil, err
+	}
+	if e.refreshPeriod, err = conf.FieldDuration("refresh_period"); err != nil {
+		return nil, err
+	}
+	if e.avroRawJSON, err = conf.FieldBool("avro_raw_json"); err != nil {
+		return nil, err
+	}
+
+	if e.schema == "" {
+		if e.strategy.NeedsRecordName() {
+			return nil, fmt.Errorf("subject_name_strategy %v requires a schema", strategyStr)
+		}
+		if e.autoRegister {
+			return nil, errors.New("auto_register requires a schema")
+		}
+		return e, nil
+	}
+
+	if e.codec, err = goavro.NewCodecForStandardJSON(e.schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if e.strategy.NeedsRecordName() {
+		if e.recordName, err = sr.AvroRecordName(e.schema); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *franzSchemaEncoder) resolve(ctx context.Context, subject string) (*cachedSchemaID, error) {
+	if e.codec == nil {
+		info, err := e.client.GetLatestSchema(ctx, subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain latest schema of subject %v: %w", subject, err)
+		}
+		if info.Type != "" && info.Type != "AVRO" {
+			return nil, fmt.Errorf("schema of subject %v has unsupported type %v", subject, info.Type)
+		}
+		codec, err := goavro.NewCodecForStandardJSON(info.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schema of subject %v: %w", subject, err)
+		}
+		return &cachedSchemaID{id: info.ID, codec: codec}, nil
+	}
+
+	schema := sr.SchemaInfo{Schema: e.schema}
+
+	var id int
+	var err error
+	if e.autoRegister {
+		id, err = e.client.RegisterSchema(ctx, subject, schema)
+	} else {
+		id, err = e.client.LookupSchema(ctx, subject, schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain ID of schema for subject %v: %w", subject, err)
+	}
+	return &cachedSchemaID{id: id, codec: e.codec}, nil
+}
+
+func (e *franzSchemaEncoder) getSchema(ctx context.Context, topic string) (*cachedSchemaID, error) {
+	subject, err := e.strategy.Subject(topic, e.recordName)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mut.RLock()
+	cached, exists := e.subjects[subject]
+	e.mut.RUnlock()
+	if exists && e.nowFn().Sub(cached.updated) < e.refreshPeriod {
+		return cached, nil
+	}
+
+	// Subjects are resolved outside of the lock so that a slow registry only
+	// blocks the records of the subjects being resolved, and concurrent
+	// resolutions of the same subject share a single request.
+	v, err, _ := e.resolving.Do(subject, func() (interface{}, error) {
+		resolved, err := e.resolve(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+		resolved.updated = e.nowFn()
+
+		e.mut.Lock()
+		e.subjects[subject] = resolved
+		e.mut.Unlock()
+		return resolved, nil
+	})
+	if err != nil {
+		if exists {
+			// Continue with the schema we already have until the registry
+			// becomes available again.
+			return cached, nil
+		}
+		return nil, err
+	}
+	return v.(*cachedSchemaID), nil
+}
+
+// encode returns the contents of a message encoded with the schema of the
+// subject of a topic.
+func (e *franzSchemaEncoder) encode(ctx context.Context, topic string, msg *service.Message) ([]byte, error) {
+	s, err := e.getSchema(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	var datum interface{}
+	if e.avroRawJSON {
+		b, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if datum, _, err = s.codec.NativeFromTextual(b); err != nil {
+			return nil, err
+		}
+	} else if datum, err = msg.AsStructured(); err != nil {
+		return nil, err
+	}
+
+	b, err := s.codec.BinaryFromNative(nil, datum)
+	if err != nil {
+		return nil, err
+	}
+	return sr.AppendWithID(s.id, b), nil
+}
@@ -0,0 +1,357 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testFranzAvroSchema = `{
+  "type": "record",
+  "name": "Foo",
+  "namespace": "com.example",
+  "fields": [
+    { "name": "name", "type": "string" },
+    { "name": "tag", "type": "string" }
+  ]
+}`
+
+// fakeSchemaRegistry is a minimal schema registry that tracks the requests it
+// receives.
+type fakeSchemaRegistry struct {
+	mut      sync.Mutex
+	requests []string
+	subjects map[string]int
+	schemas  map[int]string
+}
+
+func runFakeSchemaRegistry(t *testing.T) (*fakeSchemaRegistry, string) {
+	t.Helper()
+
+	f := &fakeSchemaRegistry{
+		subjects: map[string]int{},
+		schemas:  map[int]string{},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mut.Lock()
+		defer f.mut.Unlock()
+
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+		var reqBody struct {
+			Schema string `json:"schema"`
+		}
+		if r.Body != nil {
+			b, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(b, &reqBody)
+		}
+
+		path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.Method == http.MethodGet && len(path) == 3 && path[0] == "schemas":
+			for id, schema := range f.schemas {
+				if path[2] == strconv.Itoa(id) {
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"schema": schema})
+					return
+				}
+			}
+		case r.Method == http.MethodGet && len(path) == 4 && path[0] == "subjects":
+			if id, exists := f.subjects[path[1]]; exists {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "schema": f.schemas[id]})
+				return
+			}
+		case r.Method == http.MethodPost && len(path) == 3 && path[0] == "subjects":
+			id, exists := f.subjects[path[1]]
+			if !exists {
+				id = len(f.schemas) + 1
+				f.subjects[path[1]] = id
+				f.schemas[id] = reqBody.Schema
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": id})
+			return
+		case r.Method == http.MethodPost && len(path) == 2 && path[0] == "subjects":
+			if id, exists := f.subjects[path[1]]; exists {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "schema": f.schemas[id]})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	return f, ts.URL
+}
+
+func (f *fakeSchemaRegistry) register(subject, schema string) int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	id := len(f.schemas) + 1
+	f.subjects[subject] = id
+	f.schemas[id] = schema
+	return id
+}
+
+func (f *fakeSchemaRegistry) popRequests() []string {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	r := f.requests
+	f.requests = nil
+	return r
+}
+
+func testFranzSchemaEncoder(t *testing.T, conf string) *franzSchemaEncoder {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(schemaRegistryOutputField())
+	pConf, err := spec.ParseYAML(conf, service.NewEnvironment())
+	require.NoError(t, err)
+
+	e, err := franzSchemaEncoderFromParsed(pConf.Namespace("schema_registry"))
+	require.NoError(t, err)
+	return e
+}
+
+func testFranzSchemaDecoder(t *testing.T, urlStr string) *franzSchemaDecoder {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(schemaRegistryInputField())
+	pConf, err := spec.ParseYAML("schema_registry:\n  url: "+urlStr, service.NewEnvironment())
+	require.NoError(t, err)
+
+	d, err := franzSchemaDecoderFromParsed(pConf.Namespace("schema_registry"))
+	require.NoError(t, err)
+	return d
+}
+
+func TestFranzSchemaRegistryTopicName(t *testing.T) {
+	registry, urlStr := runFakeSchemaRegistry(t)
+	id := registry.register("foo-value", testFranzAvroSchema)
+
+	e := testFranzSchemaEncoder(t, `
+schema_registry:
+  url: `+urlStr+`
+  refresh_period: 1m
+`)
+	now := time.Unix(1000, 0)
+	e.nowFn = func() time.Time {
+		return now
+	}
+
+	d := testFranzSchemaDecoder(t, urlStr)
+	ctx := context.Background()
+
+	for _, doc := range []string{`{"name":"first","tag":"a"}`, `{"name":"second","tag":"b"}`} {
+		b, err := e.encode(ctx, "foo", service.NewMessage([]byte(doc)))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0, 0, 0, 0, byte(id)}, b[:5])
+
+		msg := service.NewMessage(b)
+		require.NoError(t, d.decode(ctx, msg))
+
+		res, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, doc, string(res))
+	}
+
+	// The schema ID and decoder schemas are cached.
+	assert.Equal(t, []string{
+		"GET /subjects/foo-value/versions/latest",
+		"GET /schemas/ids/1",
+	}, registry.popRequests())
+
+	now = now.Add(time.Minute * 2)
+	_, err := e.encode(ctx, "foo", service.NewMessage([]byte(`{"name":"third","tag":"c"}`)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET /subjects/foo-value/versions/latest"}, registry.popRequests())
+
+	_, err = e.encode(ctx, "bar", service.NewMessage([]byte(`{"name":"fourth","tag":"d"}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Subject not found")
+}
+
+func TestFranzSchemaRegistryAutoRegister(t *testing.T) {
+	registry, urlStr := runFakeSchemaRegistry(t)
+
+	schemaJSON, err := json.Marshal(testFranzAvroSchema)
+	require.NoError(t, err)
+
+	e := testFranzSchemaEncoder(t, `
+schema_registry:
+  url: `+urlStr+`
+  subject_name_strategy: topic_record_name
+  auto_register: true
+  schema: `+string(schemaJSON)+`
+`)
+	ctx := context.Background()
+
+	b, err := e.encode(ctx, "foo", service.NewMessage([]byte(`{"name":"first","tag":"a"}`)))
+	require.NoError(t, err)
+	_, err = e.encode(ctx, "bar", service.NewMessage([]byte(`{"name":"second","tag":"b"}`)))
+	require.NoError(t, err)
+	_, err = e.encode(ctx, "foo", service.NewMessage([]byte(`{"name":"third","tag":"c"}`)))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"POST /subjects/foo-com.example.Foo/versions",
+		"POST /subjects/bar-com.example.Foo/versions",
+	}, registry.popRequests())
+
+	msg := service.NewMessage(b)
+	require.NoError(t, testFranzSchemaDecoder(t, urlStr).decode(ctx, msg))
+	res, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"first","tag":"a"}`, string(res))
+
+	_, err = e.encode(ctx, "foo", service.NewMessage([]byte(`{"name":"fourth"}`)))
+	require.Error(t, err)
+}
+
+func TestFranzSchemaRegistryLookup(t *testing.T) {
+	registry, urlStr := runFakeSchemaRegistry(t)
+	id := registry.register("com.example.Foo", testFranzAvroSchema)
+
+	schemaJSON, err := json.Marshal(testFranzAvroSchema)
+	require.NoError(t, err)
+
+	e := testFranzSchemaEncoder(t, `
+schema_registry:
+  url: `+urlStr+`
+  subject_name_strategy: record_name
+  schema: `+string(schemaJSON)+`
+`)
+
+	b, err := e.encode(context.Background(), "foo", service.NewMessage([]byte(`{"name":"first","tag":"a"}`)))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, byte(id)}, b[:5])
+	assert.Equal(t, []string{"POST /subjects/com.example.Foo"}, registry.popRequests())
+}
+
+func TestFranzSchemaRegistryConcurrentSubjects(t *testing.T) {
+	slowStarted := make(chan struct{})
+	slowRelease := make(chan struct{})
+
+	var mut sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		requests[r.URL.Path]++
+		mut.Unlock()
+
+		if r.URL.Path == "/subjects/slow-value/versions/latest" {
+			close(slowStarted)
+			<-slowRelease
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "schema": testFranzAvroSchema})
+	}))
+	t.Cleanup(ts.Close)
+
+	e := testFranzSchemaEncoder(t, `
+schema_registry:
+  url: `+ts.URL+`
+`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := e.getSchema(context.Background(), "slow")
+			assert.NoError(t, err)
+		}()
+	}
+	<-slowStarted
+
+	// Resolving the schema of a subject is not blocked by a slow registry
+	// response for another subject.
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	_, err := e.getSchema(ctx, "fast")
+	close(slowRelease)
+	require.NoError(t, err)
+
+	wg.Wait()
+
+	mut.Lock()
+	defer mut.Unlock()
+	assert.Equal(t, map[string]int{
+		"/subjects/fast-value/versions/latest": 1,
+		"/subjects/slow-value/versions/latest": 1,
+	}, requests)
+}
+
+func TestFranzSchemaRegistryConfigErrors(t *testing.T) {
+	spec := service.NewConfigSpec().Field(schemaRegistryOutputField())
+
+	for _, test := range []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "record name without schema",
+			config: `
+schema_registry:
+  url: http://localhost:8081
+  subject_name_strategy: record_name
+`,
+			errContains: "requires a schema",
+		},
+		{
+			name: "auto register without schema",
+			config: `
+schema_registry:
+  url: http://localhost:8081
+  auto_register: true
+`,
+			errContains: "auto_register requires a schema",
+		},
+		{
+			name: "bad schema",
+			config: `
+schema_registry:
+  url: http://localhost:8081
+  schema: '{"type":"nope"}'
+`,
+			errContains: "failed to parse schema",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := spec.ParseYAML(test.config, service.NewEnvironment())
+			require.NoError(t, err)
+
+			_, err = franzSchemaEncoderFromParsed(pConf.Namespace("schema_registry"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestFranzSchemaRegistryDecodeErrors(t *testing.T) {
+	_, urlStr := runFakeSchemaRegistry(t)
+	d := testFranzSchemaDecoder(t, urlStr)
+
+	err := d.decode(context.Background(), service.NewMessage([]byte("not encoded")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported")
+
+	err = d.decode(context.Background(), service.NewMessage([]byte{0, 0, 0, 0, 5, 1}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to obtain schema 5")
+}
@@ -0,0 +1,269 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// txnOffsetKey is the context key under which the kafka_franz input stores the
+// consumer offset of a message when exactly-once delivery is enabled, which is
+// committed within the transaction of a kafka_franz output that writes it.
+type txnOffsetKey struct{}
+
+type txnOffset struct {
+	session *txnSession
+	rewinds int64
+
+	group      string
+	memberID   string
+	generation int32
+
+	topic     string
+	partition int32
+	epoch     int32
+	offset    int64
+}
+
+func (t *txnOffset) generationKey() txnGeneration {
+	return txnGeneration{group: t.group, memberID: t.memberID, generation: t.generation}
+}
+
+// superseded returns true when the session that consumed the offset has since
+// been rewound, in which case the message is consumed again.
+func (t *txnOffset) superseded() bool {
+	return t.rewinds != atomic.LoadInt64(&t.session.requested)
+}
+
+func messageTxnOffset(msg *service.Message) *txnOffset {
+	t, _ := msg.Context().Value(txnOffsetKey{}).(*txnOffset)
+	return t
+}
+
+// isTxnSuperseded returns true when a message was consumed by a kafka_franz
+// input with exactly-once delivery enabled that has since been rewound.
+func isTxnSuperseded(msg *service.Message) bool {
+	t := messageTxnOffset(msg)
+	return t != nil && t.superseded()
+}
+
+// txnSession is the consumer session of a kafka_franz input with exactly-once
+// delivery enabled. Messages of a transaction that fails can't simply be
+// retried, as the offsets of later messages from the same partitions might
+// already have been committed by then. Instead the output requests a rewind of
+// the session, after which the input resumes consuming from the committed
+// offsets of the consumer group and any messages consumed before the rewind
+// are superseded by their new copies.
+type txnSession struct {
+	// Accessed atomically.
+	requested int64
+
+	// Only accessed by the consuming goroutine of the input.
+	applied      int64
+	firstOffsets map[string]map[int32]kgo.EpochOffset
+}
+
+func newTxnSession() *txnSession {
+	return &txnSession{
+		firstOffsets: map[string]map[int32]kgo.EpochOffset{},
+	}
+}
+
+// rewind requests that the input resumes consuming from the committed offsets
+// of the consumer group, which supersedes all messages consumed until then.
+func (s *txnSession) rewind() {
+	atomic.AddInt64(&s.requested, 1)
+}
+
+// consumed records the offset of a consumed record, the first offset consumed
+// from each partition is where a partition without a committed offset resumes
+// from after a rewind.
+func (s *txnSession) consumed(topic string, partition, epoch int32, offset int64) {
+	partitions := s.firstOffsets[topic]
+	if partitions == nil {
+		partitions = map[int32]kgo.EpochOffset{}
+		s.firstOffsets[topic] = partitions
+	}
+	if _, exists := partitions[partition]; !exists {
+		partitions[partition] = kgo.EpochOffset{Epoch: epoch, Offset: offset}
+	}
+}
+
+// resume rewinds the consumer of the session to the committed offsets of the
+// consumer group if a rewind has been requested since the last, and returns
+// the number of rewinds that records consumed from then on belong to.
+func (s *txnSession) resume(ctx context.Context, cl *kgo.Client, group string) (int64, error) {
+	requested := atomic.LoadInt64(&s.requested)
+	if requested == s.applied {
+		return s.applied, nil
+	}
+
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = group
+	req.RequireStable = true
+
+	res, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return 0, err
+	}
+	offsets, err := txnResumeOffsets(res, s.firstOffsets)
+	if err != nil {
+		return 0, err
+	}
+
+	cl.SetOffsets(offsets)
+	s.applied = requested
+	return s.applied, nil
+}
+
+// rewindTxnSessions rewinds the sessions that consumed the messages of a batch
+// that failed to be written within a transaction.
+func rewindTxnSessions(b service.MessageBatch) {
+	for _, msg := range b {
+		// Once rewound the remaining messages of a session are superseded.
+		if t := messageTxnOffset(msg); t != nil && !t.superseded() {
+			t.session.rewind()
+		}
+	}
+}
+
+// txnResumeOffsets returns the offsets to resume consuming from after a rewind,
+// which are the committed offsets of the consumer group, or the first offsets
+// consumed from partitions without a committed offset.
+func txnResumeOffsets(res *kmsg.OffsetFetchResponse, firstOffsets map[string]map[int32]kgo.EpochOffset) (map[string]map[int32]kgo.EpochOffset, error) {
+	if err := kerr.ErrorForCode(res.ErrorCode); err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+
+	offsets := map[string]map[int32]kgo.EpochOffset{}
+	for topic, partitions := range firstOffsets {
+		offsets[topic] = map[int32]kgo.EpochOffset{}
+		for partition, offset := range partitions {
+			offsets[topic][partition] = offset
+		}
+	}
+
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("failed to fetch committed offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+			if p.Offset < 0 {
+				continue
+			}
+			if offsets[t.Topic] == nil {
+				offsets[t.Topic] = map[int32]kgo.EpochOffset{}
+			}
+			offsets[t.Topic][p.Partition] = kgo.EpochOffset{Epoch: p.LeaderEpoch, Offset: p.Offset}
+		}
+	}
+	return offsets, nil
+}
+
+// txnGeneration identifies a generation of a consumer group member, offsets
+// are only accepted by a transaction for the current generation.
+type txnGeneration struct {
+	group      string
+	memberID   string
+	generation int32
+}
+
+// txnGroupOffsets are the offsets to commit for a generation of a consumer
+// group member.
+type txnGroupOffsets struct {
+	txnGeneration
+	offsets map[string]map[int32]kgo.EpochOffset
+}
+
+// txnOffsetsFromBatch returns the offsets to commit for the consumed messages
+// of a batch, which are the offsets following the highest offset of each
+// partition. Messages that were not consumed by a kafka_franz input with
+// exactly-once delivery enabled, or that have been superseded, are ignored.
+func txnOffsetsFromBatch(b service.MessageBatch) []*txnGroupOffsets {
+	groups := map[txnGeneration]*txnGroupOffsets{}
+	for _, msg := range b {
+		t := messageTxnOffset(msg)
+		if t == nil || t.superseded() {
+			continue
+		}
+
+		key := t.generationKey()
+
+		g, exists := groups[key]
+		if !exists {
+			g = &txnGroupOffsets{
+				txnGeneration: key,
+				offsets:       map[string]map[int32]kgo.EpochOffset{},
+			}
+			groups[key] = g
+		}
+
+		partitions := g.offsets[t.topic]
+		if partitions == nil {
+			partitions = map[int32]kgo.EpochOffset{}
+			g.offsets[t.topic] = partitions
+		}
+		if current, exists := partitions[t.partition]; !exists || current.Offset <= t.offset {
+			partitions[t.partition] = kgo.EpochOffset{Epoch: t.epoch, Offset: t.offset + 1}
+		}
+	}
+
+	offsets := make([]*txnGroupOffsets, 0, len(groups))
+	for _, g := range groups {
+		offsets = append(offsets, g)
+	}
+	sort.Slice(offsets, func(i, j int) bool {
+		if offsets[i].group != offsets[j].group {
+			return offsets[i].group < offsets[j].group
+		}
+		return offsets[i].generation < offsets[j].generation
+	})
+	return offsets
+}
+
+// newTxnOffsetCommitRequest returns a request that commits the offsets of a
+// consumer group generation within a transaction.
+func newTxnOffsetCommitRequest(transactionalID string, producerID int64, producerEpoch int16, g *txnGroupOffsets) *kmsg.TxnOffsetCommitRequest {
+	req := kmsg.NewPtrTxnOffsetCommitRequest()
+	req.TransactionalID = transactionalID
+	req.Group = g.group
+	req.ProducerID = producerID
+	req.ProducerEpoch = producerEpoch
+	req.Generation = g.generation
+	req.MemberID = g.memberID
+
+	topics := make([]string, 0, len(g.offsets))
+	for topic := range g.offsets {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	for _, topic := range topics {
+		reqTopic := kmsg.NewTxnOffsetCommitRequestTopic()
+		reqTopic.Topic = topic
+
+		partitions := g.offsets[topic]
+		ids := make([]int32, 0, len(partitions))
+		for id := range partitions {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		for _, id := range ids {
+			reqPartition := kmsg.NewTxnOffsetCommitRequestTopicPartition()
+			reqPartition.Partition = id
+			reqPartition.Offset = partitions[id].Offset
+			reqPartition.LeaderEpoch = partitions[id].Epoch
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		req.Topics = append(req.Topics, reqTopic)
+	}
+	return req
+}
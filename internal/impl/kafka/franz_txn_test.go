@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func txnTestMessage(t *txnOffset) *service.Message {
+	msg := service.NewMessage(nil)
+	if t == nil {
+		return msg
+	}
+	return msg.WithContext(context.WithValue(msg.Context(), txnOffsetKey{}, t))
+}
+
+func TestTxnOffsetsFromBatch(t *testing.T) {
+	session := newTxnSession()
+	gen := func(generation int32, topic string, partition, epoch int32, offset int64) *txnOffset {
+		return &txnOffset{
+			session:    session,
+			group:      "foogroup",
+			memberID:   "foomember",
+			generation: generation,
+			topic:      topic,
+			partition:  partition,
+			epoch:      epoch,
+			offset:     offset,
+		}
+	}
+
+	b := service.MessageBatch{
+		txnTestMessage(gen(1, "foo", 0, 3, 10)),
+		txnTestMessage(gen(1, "foo", 0, 3, 11)),
+		txnTestMessage(gen(1, "foo", 1, 2, 5)),
+		txnTestMessage(nil),
+		txnTestMessage(gen(1, "bar", 0, 1, 20)),
+		txnTestMessage(gen(2, "foo", 1, 2, 6)),
+		txnTestMessage(gen(1, "foo", 0, 3, 9)),
+	}
+
+	offsets := txnOffsetsFromBatch(b)
+	require.Len(t, offsets, 2)
+
+	assert.Equal(t, txnGeneration{group: "foogroup", memberID: "foomember", generation: 1}, offsets[0].txnGeneration)
+	assert.Equal(t, map[string]map[int32]kgo.EpochOffset{
+		"foo": {
+			0: {Epoch: 3, Offset: 12},
+			1: {Epoch: 2, Offset: 6},
+		},
+		"bar": {
+			0: {Epoch: 1, Offset: 21},
+		},
+	}, offsets[0].offsets)
+
+	assert.Equal(t, txnGeneration{group: "foogroup", memberID: "foomember", generation: 2}, offsets[1].txnGeneration)
+	assert.Equal(t, map[string]map[int32]kgo.EpochOffset{
+		"foo": {
+			1: {Epoch: 2, Offset: 7},
+		},
+	}, offsets[1].offsets)
+
+	assert.Empty(t, txnOffsetsFromBatch(service.MessageBatch{txnTestMessage(nil)}))
+
+	// Messages consumed before a rewind are superseded.
+	rewindTxnSessions(b)
+	assert.Empty(t, txnOffsetsFromBatch(b))
+
+	rewound := gen(3, "foo", 0, 3, 10)
+	rewound.rewinds = 1
+	offsets = txnOffsetsFromBatch(append(b, txnTestMessage(rewound)))
+	require.Len(t, offsets, 1)
+	assert.Equal(t, int32(3), offsets[0].generation)
+}
+
+func TestTxnSessionRewind(t *testing.T) {
+	sessionA, sessionB := newTxnSession(), newTxnSession()
+
+	b := service.MessageBatch{
+		txnTestMessage(&txnOffset{session: sessionA, topic: "foo", offset: 1}),
+		txnTestMessage(&txnOffset{session: sessionA, topic: "foo", offset: 2}),
+		txnTestMessage(nil),
+		txnTestMessage(&txnOffset{session: sessionB, topic: "bar", offset: 1}),
+	}
+	for _, msg := range b {
+		assert.False(t, isTxnSuperseded(msg))
+	}
+
+	rewindTxnSessions(b)
+	assert.Equal(t, int64(1), sessionA.requested)
+	assert.Equal(t, int64(1), sessionB.requested)
+
+	assert.True(t, isTxnSuperseded(b[0]))
+	assert.True(t, isTxnSuperseded(b[1]))
+	assert.False(t, isTxnSuperseded(b[2]))
+	assert.True(t, isTxnSuperseded(b[3]))
+
+	// Superseded messages don't rewind their sessions again.
+	rewindTxnSessions(b)
+	assert.Equal(t, int64(1), sessionA.requested)
+	assert.Equal(t, int64(1), sessionB.requested)
+}
+
+func TestTxnResumeOffsets(t *testing.T) {
+	session := newTxnSession()
+	session.consumed("foo", 0, 1, 5)
+	session.consumed("foo", 0, 1, 6)
+	session.consumed("foo", 1, 2, 20)
+	session.consumed("bar", 0, 1, 3)
+
+	res := kmsg.NewPtrOffsetFetchResponse()
+	for _, topic := range []struct {
+		name       string
+		partitions []kmsg.OffsetFetchResponseTopicPartition
+	}{
+		{"foo", []kmsg.OffsetFetchResponseTopicPartition{
+			{Partition: 0, LeaderEpoch: 1, Offset: 8},
+			{Partition: 1, LeaderEpoch: -1, Offset: -1},
+			{Partition: 2, LeaderEpoch: 3, Offset: 40},
+		}},
+	} {
+		resTopic := kmsg.NewOffsetFetchResponseTopic()
+		resTopic.Topic = topic.name
+		resTopic.Partitions = topic.partitions
+		res.Topics = append(res.Topics, resTopic)
+	}
+
+	offsets, err := txnResumeOffsets(res, session.firstOffsets)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int32]kgo.EpochOffset{
+		"foo": {
+			0: {Epoch: 1, Offset: 8},
+			1: {Epoch: 2, Offset: 20},
+			2: {Epoch: 3, Offset: 40},
+		},
+		"bar": {
+			0: {Epoch: 1, Offset: 3},
+		},
+	}, offsets)
+
+	res.Topics[0].Partitions[1].ErrorCode = kerr.UnstableOffsetCommit.Code
+	_, err = txnResumeOffsets(res, session.firstOffsets)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, kerr.UnstableOffsetCommit))
+}
+
+func TestTxnOffsetCommitRequest(t *testing.T) {
+	req := newTxnOffsetCommitRequest("footxn", 7, 2, &txnGroupOffsets{
+		txnGeneration: txnGeneration{group: "foogroup", memberID: "foomember", generation: 4},
+		offsets: map[string]map[int32]kgo.EpochOffset{
+			"foo": {
+				1: {Epoch: 3, Offset: 12},
+				0: {Epoch: 3, Offset: 10},
+			},
+			"bar": {
+				0: {Epoch: 1, Offset: 21},
+			},
+		},
+	})
+
+	assert.Equal(t, "footxn", req.TransactionalID)
+	assert.Equal(t, "foogroup", req.Group)
+	assert.Equal(t, "foomember", req.MemberID)
+	assert.Equal(t, int32(4), req.Generation)
+	assert.Equal(t, int64(7), req.ProducerID)
+	assert.Equal(t, int16(2), req.ProducerEpoch)
+
+	type partitionOffset struct {
+		topic     string
+		partition int32
+		epoch     int32
+		offset    int64
+	}
+	var actual []partitionOffset
+	for _, topic := range req.Topics {
+		for _, p := range topic.Partitions {
+			actual = append(actual, partitionOffset{topic.Topic, p.Partition, p.LeaderEpoch, p.Offset})
+		}
+	}
+	assert.Equal(t, []partitionOffset{
+		{"bar", 0, 1, 21},
+		{"foo", 0, 3, 10},
+		{"foo", 1, 3, 12},
+	}, actual)
+}
@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
@@ -46,6 +47,23 @@ This input adds the following metadata fields to each message:
 - kafka_timestamp_unix
 - All record headers
 ` + "```" + `
+
+### Exactly-Once Delivery
+
+When ` + "`exactly_once`" + ` is enabled records are consumed with the ` + "`read_committed`" + ` isolation level, and therefore records of transactions that were aborted or are still open are never consumed. This input also stops committing offsets itself, and instead the offset of each message is committed within the transaction that writes it to a ` + "[`kafka_franz` output](/docs/components/outputs/kafka_franz)" + ` configured with a ` + "`transactional_id`" + `. This allows Kafka to Kafka streams to process each record exactly once, as the records produced from a batch of messages and the offsets of those messages are either committed together or not at all.
+
+Offsets are committed for the generation of the consumer group that the messages were consumed within, and therefore when partitions are rebalanced any messages still in flight from before the rebalance are rejected by the transaction. When a transaction fails for this or any other reason the input resumes consuming from the offsets committed for the consumer group, and the messages of the failed transaction are consumed again, either by this input or by the new owner of their partitions. Messages must reach the output in the order that they were consumed, so pipelines should not process messages in parallel across multiple threads, and any output other than a transactional ` + "`kafka_franz`" + ` output never commits the offsets of messages.
+
+### Metrics
+
+This input emits the following gauges for each topic partition assigned to it, labelled with ` + "`topic` and `partition`" + `:
+
+` + "```text" + `
+- input_kafka_lag
+- input_kafka_time_lag_ns
+` + "```" + `
+
+The gauge ` + "`input_kafka_lag`" + ` is the difference between the high watermark of the partition and the offset of the next message to be committed, and ` + "`input_kafka_time_lag_ns`" + ` is the time elapsed since the oldest message that has been consumed but not yet committed was produced, which is zero when the consumer is caught up. The gauges are updated as messages are consumed and committed. When partitions are revoked or lost during a rebalance their gauges are reset to zero and are no longer updated.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -67,10 +85,39 @@ This input adds the following metadata fields to each message:
 			Description("The period of time between each commit of the current partition offsets. Offsets are always committed during shutdown.").
 			Default("5s").
 			Advanced()).
+		Field(service.NewBoolField("exactly_once").
+			Description("Whether to consume only committed records and leave the committing of offsets to a `kafka_franz` output configured with a `transactional_id`, in order to achieve exactly-once delivery. For more information read the [exactly-once section](#exactly-once-delivery).").
+			Version("4.5.0").
+			Default(false).
+			Advanced()).
 		Field(service.NewBoolField("start_from_oldest").
 			Description("If an offset is not found for a topic partition, determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset.").
 			Default(true).
 			Advanced()).
+		Field(service.NewObjectField("start_offset",
+			service.NewStringAnnotatedEnumField("type", startOffsetTypeOptions).
+				Description("The type of start offset.").
+				Default(startOffsetNone),
+			service.NewIntField("relative").
+				Description(startOffsetRelativeDescription).
+				Default(0),
+			service.NewStringField("timestamp").
+				Description(startOffsetTimestampDescription).
+				Example("2022-08-01T00:00:00Z").
+				Example("1659312000000").
+				Default(""),
+			service.NewIntMapField("partition_offsets").
+				Description(startOffsetPartitionOffsetsDescription).
+				Example(map[string]interface{}{"foo:0": 1200, "foo:1": 850}).
+				Default(map[string]interface{}{}),
+			service.NewBoolField("force").
+				Description(startOffsetForceDescription).
+				Default(false),
+		).
+			Description(startOffsetFieldDescription).
+			Version("4.5.0").
+			Advanced()).
+		Field(schemaRegistryInputField()).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField())
 }
@@ -78,7 +125,7 @@ This input adds the following metadata fields to each message:
 func init() {
 	err := service.RegisterInput("kafka_franz", franzKafkaInputConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-			rdr, err := newFranzKafkaReaderFromConfig(conf, mgr.Logger())
+			rdr, err := newFranzKafkaReaderFromConfig(conf, mgr.Logger(), mgr.Metrics())
 			if err != nil {
 				return nil, err
 			}
@@ -107,10 +154,15 @@ type franzKafkaReader struct {
 	startFromOldest bool
 	commitPeriod    time.Duration
 	regexPattern    bool
-
-	msgChan atomic.Value
-	log     *service.Logger
-	shutSig *shutdown.Signaller
+	exactlyOnce     bool
+	startOffset     *startOffset
+	schemaDecoder   *franzSchemaDecoder
+
+	msgChan      atomic.Value
+	log          *service.Logger
+	lagGauge     *service.MetricGauge
+	timeLagGauge *service.MetricGauge
+	shutSig      *shutdown.Signaller
 }
 
 func (f *franzKafkaReader) getMsgChan() chan msgWithAckFn {
@@ -122,10 +174,12 @@ func (f *franzKafkaReader) storeMsgChan(c chan msgWithAckFn) {
 	f.msgChan.Store(c)
 }
 
-func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logger) (*franzKafkaReader, error) {
+func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logger, metrics *service.Metrics) (*franzKafkaReader, error) {
 	f := franzKafkaReader{
-		log:     log,
-		shutSig: shutdown.NewSignaller(),
+		log:          log,
+		lagGauge:     metrics.NewGauge(lagMetricName, "topic", "partition"),
+		timeLagGauge: metrics.NewGauge(timeLagMetricName, "topic", "partition"),
+		shutSig:      shutdown.NewSignaller(),
 	}
 
 	brokerList, err := conf.FieldStringList("seed_brokers")
@@ -156,6 +210,10 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if f.exactlyOnce, err = conf.FieldBool("exactly_once"); err != nil {
+		return nil, err
+	}
+
 	if f.startFromOldest, err = conf.FieldBool("start_from_oldest"); err != nil {
 		return nil, err
 	}
@@ -164,6 +222,16 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if f.startOffset, err = startOffsetFromParsed(conf.Namespace("start_offset")); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("schema_registry") {
+		if f.schemaDecoder, err = franzSchemaDecoderFromParsed(conf.Namespace("schema_registry")); err != nil {
+			return nil, err
+		}
+	}
+
 	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
 	if err != nil {
 		return nil, err
@@ -178,6 +246,86 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logg
 	return &f, nil
 }
 
+func startOffsetFromParsed(conf *service.ParsedConfig) (*startOffset, error) {
+	kind, err := conf.FieldString("type")
+	if err != nil {
+		return nil, err
+	}
+	relative, err := conf.FieldInt("relative")
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := conf.FieldString("timestamp")
+	if err != nil {
+		return nil, err
+	}
+	partitionOffsetsInt, err := conf.FieldIntMap("partition_offsets")
+	if err != nil {
+		return nil, err
+	}
+	partitionOffsets := make(map[string]int64, len(partitionOffsetsInt))
+	for k, v := range partitionOffsetsInt {
+		partitionOffsets[k] = int64(v)
+	}
+	force, err := conf.FieldBool("force")
+	if err != nil {
+		return nil, err
+	}
+	return newStartOffset(kind, int64(relative), timestamp, partitionOffsets, force)
+}
+
+// franzOffset returns the offset to start consuming a topic partition from, or
+// false if the partition should start from the reset offset.
+func (s *startOffset) franzOffset(topic string, partition int32) (kgo.Offset, bool) {
+	switch s.kind {
+	case startOffsetEarliest:
+		return kgo.NewOffset().AtStart().Relative(s.relative), true
+	case startOffsetLatest:
+		return kgo.NewOffset().AtEnd().Relative(s.relative), true
+	case startOffsetTimestamp:
+		return kgo.NewOffset().AfterMilli(s.timestampMillis), true
+	case startOffsetPartitionOffsets:
+		if offset, exists := s.explicitOffset(topic, partition); exists {
+			return kgo.NewOffset().At(offset), true
+		}
+	}
+	return kgo.Offset{}, false
+}
+
+// adjustFetchOffsets replaces the offsets fetched for the consumer group with
+// the start offset where it applies. Partitions without a committed offset are
+// given the reset offset, which is always negative.
+func (f *franzKafkaReader) adjustFetchOffsets(_ context.Context, offsets map[string]map[int32]kgo.Offset) (map[string]map[int32]kgo.Offset, error) {
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			if !f.startOffset.shouldApply(topic, partition, franzOffsetAt(offset) >= 0) {
+				continue
+			}
+			if startOffset, exists := f.startOffset.franzOffset(topic, partition); exists {
+				partitions[partition] = startOffset
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// franzOffsetAt returns the absolute offset of a kgo.Offset, which the client
+// only exposes through its JSON representation. Offsets relative to the start
+// or end of a partition are negative.
+func franzOffsetAt(o kgo.Offset) int64 {
+	var v struct {
+		At int64
+	}
+	b, err := o.MarshalJSON()
+	if err != nil {
+		return -1
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return -1
+	}
+	return v.At
+}
+
 //------------------------------------------------------------------------------
 
 type checkpointTracker struct {
@@ -280,6 +428,11 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 	}
 
 	checkpoints := newCheckpointTracker()
+	lags := newLagTracker(func(topic string, partition int32, lag, timeLag int64) {
+		partStr := strconv.Itoa(int(partition))
+		f.lagGauge.Set(lag, topic, partStr)
+		f.timeLagGauge.Set(timeLag, topic, partStr)
+	})
 
 	var initialOffset kgo.Offset
 	if f.startFromOldest {
@@ -294,7 +447,19 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		kgo.ConsumeTopics(f.topics...),
 		kgo.ConsumeResetOffset(initialOffset),
 		kgo.SASL(f.saslConfs...),
+		kgo.OnPartitionsAssigned(func(_ context.Context, _ *kgo.Client, m map[string][]int32) {
+			lags.assign(m)
+		}),
 		kgo.OnPartitionsRevoked(func(rctx context.Context, c *kgo.Client, m map[string][]int32) {
+			lags.remove(m)
+			if f.exactlyOnce {
+				// Offsets are committed by transactions, and any that are
+				// still in flight are rejected by the new generation, which
+				// rewinds the session of this input.
+				checkpoints.removeTopicPartitions(m)
+				return
+			}
+
 			// Note: this is a best attempt, there's a chance of duplicates if
 			// the checkpoint limit is borked with slow moving pending messages,
 			// but we can't block here, so work with that we have.
@@ -323,12 +488,28 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		kgo.OnPartitionsLost(func(_ context.Context, _ *kgo.Client, m map[string][]int32) {
 			// No point trying to commit our offsets, just clean up our topic map
 			checkpoints.removeTopicPartitions(m)
+			lags.remove(m)
 		}),
-		kgo.AutoCommitMarks(),
-		kgo.AutoCommitInterval(f.commitPeriod),
 		kgo.WithLogger(&kgoLogger{f.log}),
 	}
 
+	if f.exactlyOnce {
+		clientOpts = append(clientOpts,
+			kgo.DisableAutoCommit(),
+			kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+			kgo.RequireStableFetchOffsets(),
+		)
+	} else {
+		clientOpts = append(clientOpts,
+			kgo.AutoCommitMarks(),
+			kgo.AutoCommitInterval(f.commitPeriod),
+		)
+	}
+
+	if f.startOffset != nil {
+		clientOpts = append(clientOpts, kgo.AdjustFetchOffsetsFn(f.adjustFetchOffsets))
+	}
+
 	if f.tlsConf != nil {
 		clientOpts = append(clientOpts, kgo.DialTLSConfig(f.tlsConf))
 	}
@@ -342,6 +523,8 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		return err
 	}
 
+	session := newTxnSession()
+
 	msgChan := make(chan msgWithAckFn)
 	go func() {
 		defer func() {
@@ -357,6 +540,23 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		defer done()
 
 		for {
+			// When a transaction fails we resume from the committed offsets
+			// before consuming anything further, and records consumed since
+			// the last rewind are superseded.
+			var rewinds int64
+			if f.exactlyOnce {
+				var err error
+				if rewinds, err = session.resume(closeCtx, cl, f.consumerGroup); err != nil {
+					f.log.Errorf("Failed to rewind to committed offsets: %v", err)
+					select {
+					case <-time.After(time.Second):
+					case <-closeCtx.Done():
+						return
+					}
+					continue
+				}
+			}
+
 			// Using a stall prevention context here because I've realised we
 			// might end up disabling literally all the partitions and topics
 			// we're allocated.
@@ -384,11 +584,38 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 				return
 			}
 
+			// The group generation is captured after each poll so that the
+			// offsets of records are only committed by transactions whilst
+			// the partitions are still assigned to us.
+			var memberID string
+			var generation int32
+			if f.exactlyOnce {
+				memberID, generation = cl.GroupMetadata()
+			}
+
+			fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+				lags.setHighWatermark(p.Topic, p.Partition, p.HighWatermark)
+			})
+
 			pauseTopicPartitions := map[string][]int32{}
 			iter := fetches.RecordIter()
 			for !iter.Done() {
 				record := iter.Next()
 				msg := recordToMessage(record)
+				if f.exactlyOnce {
+					session.consumed(record.Topic, record.Partition, record.LeaderEpoch, record.Offset)
+					msg = msg.WithContext(context.WithValue(msg.Context(), txnOffsetKey{}, &txnOffset{
+						session:    session,
+						rewinds:    rewinds,
+						group:      f.consumerGroup,
+						memberID:   memberID,
+						generation: generation,
+						topic:      record.Topic,
+						partition:  record.Partition,
+						epoch:      record.LeaderEpoch,
+						offset:     record.Offset,
+					}))
+				}
 
 				// The record lives on for checkpointing, but we don't need the
 				// contents going forward so discard these. This looked fine to
@@ -397,6 +624,7 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 				record.Key = nil
 				record.Value = nil
 
+				lags.consumed(record.Topic, record.Partition, record.Offset, record.Timestamp)
 				releaseFn, pending := checkpoints.addRecord(record)
 				if pending >= f.checkpointLimit {
 					// If the number of in flight messages from this partition
@@ -414,7 +642,12 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 				case msgChan <- msgWithAckFn{
 					msg: msg,
 					onAck: func() {
-						if maxRec := releaseFn(); maxRec != nil {
+						maxRec := releaseFn()
+						if maxRec == nil {
+							return
+						}
+						lags.committed(maxRec.Topic, maxRec.Partition, maxRec.Offset+1)
+						if !f.exactlyOnce {
 							cl.MarkCommitRecords(maxRec)
 						}
 					},
@@ -477,6 +710,13 @@ func (f *franzKafkaReader) Read(ctx context.Context) (*service.Message, service.
 		return nil, nil, ctx.Err()
 	}
 
+	if f.schemaDecoder != nil {
+		if err := f.schemaDecoder.decode(ctx, mAck.msg); err != nil {
+			f.log.Debugf("Failed to decode message with schema registry: %v", err)
+			mAck.msg.SetError(err)
+		}
+	}
+
 	return mAck.msg, func(ctx context.Context, res error) error {
 		// Res will always be nil because we initialize with service.AutoRetryNacks
 		mAck.onAck()
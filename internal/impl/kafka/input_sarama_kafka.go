@@ -58,6 +58,17 @@ The field ` + "`kafka_lag`" + ` is the calculated difference between the high wa
 
 You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).
 
+### Metrics
+
+This input emits the following gauges for each topic partition it consumes, whether assigned to it as a consumer group or listed explicitly, labelled with ` + "`topic` and `partition`" + `:
+
+` + "```text" + `
+- input_kafka_lag
+- input_kafka_time_lag_ns
+` + "```" + `
+
+The gauge ` + "`input_kafka_lag`" + ` is the difference between the high water mark of the partition and the offset of the next message to be committed, and ` + "`input_kafka_time_lag_ns`" + ` is the time elapsed since the oldest message that has been consumed but not yet committed was produced, which is zero when the consumer is caught up. The gauges are updated as messages are consumed and committed. When partitions are revoked during a rebalance, or the input reconnects, their gauges are reset to zero and are no longer updated until the partitions are consumed again.
+
 ### Ordering
 
 By default messages of a topic partition can be processed in parallel, up to a limit determined by the field ` + "`checkpoint_limit`" + `. However, if strict ordered processing is required then this value must be set to 1 in order to process shard messages in lock-step. When doing so it is recommended that you perform batching at this component for performance as it will not be possible to batch lock-stepped messages at the output level.
@@ -90,6 +101,19 @@ Unfortunately this error message will appear for a wide range of connection prob
 			docs.FieldString("client_id", "An identifier for the client connection.").Advanced(),
 			docs.FieldString("rack_id", "A rack identifier for this client.").Advanced(),
 			docs.FieldBool("start_from_oldest", "If an offset is not found for a topic partition, determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset.").Advanced(),
+			docs.FieldObject("start_offset", startOffsetFieldDescription).WithChildren(
+				docs.FieldString("type", "The type of start offset.").HasAnnotatedOptions(
+					startOffsetNone, startOffsetTypeOptions[startOffsetNone],
+					startOffsetEarliest, startOffsetTypeOptions[startOffsetEarliest],
+					startOffsetLatest, startOffsetTypeOptions[startOffsetLatest],
+					startOffsetTimestamp, startOffsetTypeOptions[startOffsetTimestamp],
+					startOffsetPartitionOffsets, startOffsetTypeOptions[startOffsetPartitionOffsets],
+				),
+				docs.FieldInt("relative", startOffsetRelativeDescription),
+				docs.FieldString("timestamp", startOffsetTimestampDescription, "2022-08-01T00:00:00Z", "1659312000000"),
+				docs.FieldInt("partition_offsets", startOffsetPartitionOffsetsDescription, map[string]interface{}{"foo:0": 1200, "foo:1": 850}).Map(),
+				docs.FieldBool("force", startOffsetForceDescription),
+			).AtVersion("4.5.0").Advanced(),
 			docs.FieldInt(
 				"checkpoint_limit", "The maximum number of messages of the same topic and partition that can be processed at a given time. Increasing this limit enables parallel processing and batching at the output level to work on individual partitions. Any given offset will not be committed unless all messages under that offset are delivered in order to preserve at least once delivery guarantees.",
 			).AtVersion("3.33.0"),
@@ -162,6 +186,9 @@ type kafkaReader struct {
 	consumerDoneCtx context.Context
 	msgChan         chan asyncMessage
 	session         offsetMarker
+	client          sarama.Client
+	lags            *lagTracker
+	startOffset     *startOffset
 
 	conf input.KafkaConfig
 	log  log.Modular
@@ -215,6 +242,14 @@ func newKafkaReader(conf input.KafkaConfig, mgr bundle.NewManagement, log log.Mo
 		closedChan:      make(chan struct{}),
 		topicPartitions: map[string][]int32{},
 	}
+
+	lagGauge := mgr.Metrics().GetGaugeVec(lagMetricName, "topic", "partition")
+	timeLagGauge := mgr.Metrics().GetGaugeVec(timeLagMetricName, "topic", "partition")
+	k.lags = newLagTracker(func(topic string, partition int32, lag, timeLag int64) {
+		partStr := strconv.Itoa(int(partition))
+		lagGauge.With(topic, partStr).Set(lag)
+		timeLagGauge.With(topic, partStr).Set(timeLag)
+	})
 	if conf.TLS.Enabled {
 		var err error
 		if k.tlsConf, err = conf.TLS.Get(); err != nil {
@@ -292,12 +327,63 @@ func newKafkaReader(conf input.KafkaConfig, mgr bundle.NewManagement, log log.Mo
 	}
 
 	var err error
+	if k.startOffset, err = newStartOffset(
+		conf.StartOffset.Type, conf.StartOffset.Relative, conf.StartOffset.Timestamp,
+		conf.StartOffset.PartitionOffsets, conf.StartOffset.Force,
+	); err != nil {
+		return nil, err
+	}
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
 	return &k, nil
 }
 
+// saramaOffset returns the offset to start consuming a topic partition from, or
+// false if the partition should start from the offset determined by
+// start_from_oldest.
+func (s *startOffset) saramaOffset(client sarama.Client, topic string, partition int32) (int64, bool, error) {
+	switch s.kind {
+	case startOffsetEarliest, startOffsetLatest:
+		oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return 0, false, err
+		}
+		newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return 0, false, err
+		}
+		offset := oldest
+		if s.kind == startOffsetLatest {
+			offset = newest
+		}
+		offset += s.relative
+		if offset < oldest {
+			offset = oldest
+		}
+		if offset > newest {
+			offset = newest
+		}
+		return offset, true, nil
+	case startOffsetTimestamp:
+		offset, err := client.GetOffset(topic, partition, s.timestampMillis)
+		if err != nil {
+			return 0, false, err
+		}
+		if offset < 0 {
+			// There are no messages at or after the timestamp.
+			if offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+				return 0, false, err
+			}
+		}
+		return offset, true, nil
+	case startOffsetPartitionOffsets:
+		offset, exists := s.explicitOffset(topic, partition)
+		return offset, exists, nil
+	}
+	return 0, false, nil
+}
+
 //------------------------------------------------------------------------------
 
 func (k *kafkaReader) asyncCheckpointer(topic string, partition int32) func(context.Context, chan<- asyncMessage, *message.Batch, int64) bool {
@@ -325,6 +411,7 @@ func (k *kafkaReader) asyncCheckpointer(topic string, partition int32) func(cont
 				if k.session != nil {
 					k.log.Debugf("Marking offset for topic '%v' partition '%v'.\n", topic, partition)
 					k.session.MarkOffset(topic, partition, maxOffset.(int64), "")
+					k.lags.committed(topic, partition, maxOffset.(int64))
 				} else {
 					k.log.Debugf("Unable to mark offset for topic '%v' partition '%v'.\n", topic, partition)
 				}
@@ -355,6 +442,7 @@ func (k *kafkaReader) syncCheckpointer(topic string, partition int32) func(conte
 					if k.session != nil {
 						k.log.Debugf("Marking offset for topic '%v' partition '%v'.\n", topic, partition)
 						k.session.MarkOffset(topic, partition, offset, "")
+						k.lags.committed(topic, partition, offset)
 					} else {
 						k.log.Debugf("Unable to mark offset for topic '%v' partition '%v'.\n", topic, partition)
 					}
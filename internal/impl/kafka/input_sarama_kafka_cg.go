@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"time"
 
@@ -15,7 +16,68 @@ import (
 func (k *kafkaReader) Setup(sesh sarama.ConsumerGroupSession) error {
 	k.cMut.Lock()
 	k.session = sesh
+	client := k.client
 	k.cMut.Unlock()
+	k.lags.assign(sesh.Claims())
+
+	if k.startOffset != nil && client != nil {
+		if err := k.seekStartOffsets(client, sesh); err != nil {
+			k.log.Errorf("Failed to apply start offsets: %v\n", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// seekStartOffsets moves the offsets of the claimed topic partitions to the
+// start offset where it applies. This happens before the claims are consumed,
+// and therefore consumption begins at the new offsets.
+func (k *kafkaReader) seekStartOffsets(client sarama.Client, sesh sarama.ConsumerGroupSession) error {
+	claims := sesh.Claims()
+
+	coordinator, err := client.Coordinator(k.conf.ConsumerGroup)
+	if err != nil {
+		return err
+	}
+	offsetGetReq := sarama.OffsetFetchRequest{
+		Version:       k.offsetVersion(),
+		ConsumerGroup: k.conf.ConsumerGroup,
+	}
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			offsetGetReq.AddPartition(topic, partition)
+		}
+	}
+	offsetRes, err := coordinator.FetchOffset(&offsetGetReq)
+	if err != nil {
+		return fmt.Errorf("failed to acquire offsets from broker: %w", err)
+	}
+
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			hasCommitted := false
+			if block := offsetRes.GetBlock(topic, partition); block != nil && block.Err == sarama.ErrNoError {
+				hasCommitted = block.Offset >= 0
+			}
+			if !k.startOffset.shouldApply(topic, partition, hasCommitted) {
+				continue
+			}
+
+			offset, exists, err := k.startOffset.saramaOffset(client, topic, partition)
+			if err != nil {
+				return fmt.Errorf("failed to resolve start offset of topic %v partition %v: %w", topic, partition, err)
+			}
+			if !exists {
+				continue
+			}
+
+			// Marking an offset only moves it forwards and resetting an offset
+			// only moves it backwards, so we do both in order to seek.
+			k.log.Debugf("Starting topic '%v' partition '%v' from offset '%v'.\n", topic, partition, offset)
+			sesh.MarkOffset(topic, partition, offset, "")
+			sesh.ResetOffset(topic, partition, offset, "")
+		}
+	}
 	return nil
 }
 
@@ -25,6 +87,7 @@ func (k *kafkaReader) Cleanup(sesh sarama.ConsumerGroupSession) error {
 	k.cMut.Lock()
 	k.session = nil
 	k.cMut.Unlock()
+	k.lags.remove(sesh.Claims())
 	return nil
 }
 
@@ -75,6 +138,9 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 			latestOffset = data.Offset
 			part := dataToPart(claim.HighWaterMarkOffset(), data)
 
+			k.lags.consumed(topic, partition, data.Offset, data.Timestamp)
+			k.lags.setHighWatermark(topic, partition, claim.HighWaterMarkOffset())
+
 			if batchPolicy.Add(part) {
 				nextTimedBatchChan = nil
 				if !flushBatch(sess.Context(), k.msgChan, batchPolicy.Flush(), latestOffset+1) {
@@ -91,8 +157,13 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 
 func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.Config) error {
 	// Start a new consumer group
-	group, err := sarama.NewConsumerGroup(k.addresses, k.conf.ConsumerGroup, config)
+	client, err := sarama.NewClient(k.addresses, config)
+	if err != nil {
+		return err
+	}
+	group, err := sarama.NewConsumerGroupFromClient(k.conf.ConsumerGroup, client)
 	if err != nil {
+		client.Close()
 		return err
 	}
 
@@ -144,15 +215,18 @@ func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.
 		k.log.Debugln("Closing consumer group")
 
 		group.Close()
+		client.Close()
 
 		k.cMut.Lock()
 		if k.msgChan != nil {
 			close(k.msgChan)
 			k.msgChan = nil
 		}
+		k.client = nil
 		k.cMut.Unlock()
 	}()
 
+	k.client = client
 	k.msgChan = make(chan asyncMessage)
 	k.consumerDoneCtx = consumerDoneCtx
 	k.log.Infof("Consuming kafka topics %v from brokers %s as group '%v'\n", k.balancedTopics, k.addresses, k.conf.ConsumerGroup)
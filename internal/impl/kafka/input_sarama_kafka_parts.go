@@ -78,6 +78,9 @@ partMsgLoop:
 			latestOffset = data.Offset
 			part := dataToPart(consumer.HighWaterMarkOffset(), data)
 
+			k.lags.consumed(topic, partition, data.Offset, data.Timestamp)
+			k.lags.setHighWatermark(topic, partition, consumer.HighWaterMarkOffset())
+
 			if batchPolicy.Add(part) {
 				nextTimedBatchChan = nil
 				if !flushBatch(ctx, k.msgChan, batchPolicy.Flush(), latestOffset+1) {
@@ -133,6 +136,7 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 
 	defer func() {
 		if err != nil {
+			k.lags.remove(k.topicPartitions)
 			if consumer != nil {
 				consumer.Close()
 			}
@@ -190,6 +194,8 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 		},
 	}
 
+	k.lags.assign(k.topicPartitions)
+
 	partConsumers := []sarama.PartitionConsumer{}
 	consumerWG := sync.WaitGroup{}
 	msgChan := make(chan asyncMessage)
@@ -204,10 +210,12 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 			if k.conf.StartFromOldest {
 				offset = sarama.OffsetOldest
 			}
+			hasCommitted := false
 			if block := offsetRes.GetBlock(topic, partition); block != nil {
 				if block.Err == sarama.ErrNoError {
 					if block.Offset > 0 {
 						offset = block.Offset
+						hasCommitted = true
 					}
 				} else {
 					k.log.Debugf("Failed to acquire offset for topic %v partition %v: %v\n", topic, partition, block.Err)
@@ -216,6 +224,18 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 				k.log.Debugf("Failed to acquire offset for topic %v partition %v\n", topic, partition)
 			}
 
+			if k.startOffset != nil && k.startOffset.shouldApply(topic, partition, hasCommitted) {
+				var startOffset int64
+				var exists bool
+				if startOffset, exists, err = k.startOffset.saramaOffset(client, topic, partition); err != nil {
+					doneFn()
+					return fmt.Errorf("failed to resolve start offset of topic %v partition %v: %w", topic, partition, err)
+				}
+				if exists {
+					offset = startOffset
+				}
+			}
+
 			var partConsumer sarama.PartitionConsumer
 			if partConsumer, err = consumer.ConsumePartition(topic, partition, offset); err != nil {
 				// TODO: Actually verify the error was caused by a non-existent offset
@@ -264,6 +284,7 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 			consumer.AsyncClose()
 		}
 		consumerWG.Done()
+		k.lags.remove(k.topicPartitions)
 
 		k.cMut.Lock()
 		if k.msgChan != nil {
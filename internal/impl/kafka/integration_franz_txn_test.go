@@ -0,0 +1,237 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/internal/integration"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func produceFranzRecords(t *testing.T, address, topic string, from, to int) {
+	t.Helper()
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers(address))
+	require.NoError(t, err)
+	defer cl.Close()
+
+	var records []*kgo.Record
+	for i := from; i < to; i++ {
+		records = append(records, &kgo.Record{
+			Topic: topic,
+			Key:   []byte(strconv.Itoa(i)),
+			Value: []byte(strconv.Itoa(i)),
+		})
+	}
+	require.NoError(t, cl.ProduceSync(context.Background(), records...).FirstErr())
+}
+
+// readCommittedFranzRecords reads all committed records of a topic until at
+// least n have been read and no more arrive within a grace period.
+func readCommittedFranzRecords(t *testing.T, address, topic string, n int) []string {
+	t.Helper()
+
+	cl, err := kgo.NewClient(
+		kgo.SeedBrokers(address),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+	)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	var values []string
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		wait := time.Second
+		if len(values) >= n {
+			wait = time.Second * 5
+		}
+
+		ctx, done := context.WithTimeout(context.Background(), wait)
+		fetches := cl.PollFetches(ctx)
+		done()
+
+		before := len(values)
+		fetches.EachRecord(func(r *kgo.Record) {
+			values = append(values, string(r.Value))
+		})
+		if len(values) >= n && len(values) == before {
+			break
+		}
+	}
+	return values
+}
+
+// waitForCommittedFranzRecords blocks until at least n committed records have
+// been written to a topic.
+func waitForCommittedFranzRecords(t *testing.T, address, topic string, n int) {
+	t.Helper()
+
+	cl, err := kgo.NewClient(
+		kgo.SeedBrokers(address),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+	)
+	require.NoError(t, err)
+	defer cl.Close()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	var count int
+	for count < n {
+		fetches := cl.PollFetches(ctx)
+		require.NoError(t, ctx.Err())
+		count += fetches.NumRecords()
+	}
+}
+
+func startFranzExactlyOnceStream(t *testing.T, address, testID, instance, processors string) func() {
+	t.Helper()
+
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetYAML(fmt.Sprintf(`
+input:
+  kafka_franz:
+    seed_brokers: [ %[1]v ]
+    topics: [ topic-%[2]v-in ]
+    consumer_group: group-%[2]v
+    exactly_once: true
+    start_from_oldest: true
+
+pipeline:
+  threads: 1
+  processors: [ %[4]v ]
+
+output:
+  kafka_franz:
+    seed_brokers: [ %[1]v ]
+    topic: topic-%[2]v-out
+    transactional_id: txn-%[2]v%[3]v
+    batching:
+      count: 7
+      period: 100ms
+`, address, testID, instance, processors)))
+
+	stream, err := builder.Build()
+	require.NoError(t, err)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- stream.Run(context.Background())
+	}()
+
+	return func() {
+		require.NoError(t, stream.StopWithin(time.Second*30))
+		<-runErr
+	}
+}
+
+func runFranzExactlyOnceStream(t *testing.T, address, testID string, expected int) {
+	t.Helper()
+
+	stop := startFranzExactlyOnceStream(t, address, testID, "", "")
+
+	values := readCommittedFranzRecords(t, address, fmt.Sprintf("topic-%v-out", testID), expected)
+	assert.Len(t, values, expected)
+
+	stop()
+}
+
+func assertFranzExactlyOnce(t *testing.T, values []string, expected int) {
+	t.Helper()
+
+	seen := map[string]int{}
+	for _, v := range values {
+		seen[v]++
+	}
+	for i := 0; i < expected; i++ {
+		assert.Equal(t, 1, seen[strconv.Itoa(i)], "value %v", i)
+	}
+	assert.Len(t, values, expected)
+}
+
+func TestIntegrationKafkaFranzExactlyOnce(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	kafkaPort, err := integration.GetFreePort()
+	require.NoError(t, err)
+
+	kafkaPortStr := strconv.Itoa(kafkaPort)
+	address := "localhost:" + kafkaPortStr
+
+	options := &dockertest.RunOptions{
+		Repository:   "docker.vectorized.io/vectorized/redpanda",
+		Tag:          "latest",
+		Hostname:     "redpanda",
+		ExposedPorts: []string{"9092"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"9092/tcp": {{HostIP: "", HostPort: kafkaPortStr}},
+		},
+		Cmd: []string{
+			"redpanda", "start", "--smp 1", "--overprovisioned",
+			"--kafka-addr 0.0.0.0:9092",
+			fmt.Sprintf("--advertise-kafka-addr localhost:%v", kafkaPort),
+		},
+	}
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.RunWithOptions(options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		return createKafkaTopic(address, "testingconnection", 1)
+	}))
+
+	testID := "exactlyonce"
+	require.NoError(t, createKafkaTopic(address, testID+"-in", 4))
+	require.NoError(t, createKafkaTopic(address, testID+"-out", 4))
+
+	produceFranzRecords(t, address, "topic-"+testID+"-in", 0, 100)
+	runFranzExactlyOnceStream(t, address, testID, 100)
+
+	// A restarted stream continues from the offsets committed by the
+	// transactions of the previous run.
+	produceFranzRecords(t, address, "topic-"+testID+"-in", 100, 200)
+	runFranzExactlyOnceStream(t, address, testID, 200)
+
+	values := readCommittedFranzRecords(t, address, "topic-"+testID+"-out", 200)
+	assertFranzExactlyOnce(t, values, 200)
+
+	// A second stream joining the consumer group part way through rebalances
+	// the partitions whilst transactions of the first are in flight.
+	testID = "exactlyoncerebalance"
+	require.NoError(t, createKafkaTopic(address, testID+"-in", 4))
+	require.NoError(t, createKafkaTopic(address, testID+"-out", 4))
+
+	produceFranzRecords(t, address, "topic-"+testID+"-in", 0, 500)
+
+	stopFirst := startFranzExactlyOnceStream(t, address, testID, "a", `{ sleep: { duration: 10ms } }`)
+	waitForCommittedFranzRecords(t, address, "topic-"+testID+"-out", 50)
+	stopSecond := startFranzExactlyOnceStream(t, address, testID, "b", `{ sleep: { duration: 10ms } }`)
+
+	values = readCommittedFranzRecords(t, address, "topic-"+testID+"-out", 500)
+	stopFirst()
+	stopSecond()
+
+	assertFranzExactlyOnce(t, values, 500)
+}
@@ -0,0 +1,170 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	lagMetricName     = "input_kafka_lag"
+	timeLagMetricName = "input_kafka_time_lag_ns"
+)
+
+// lagSetter sets the lag gauges of a topic partition, where lag is the number
+// of messages and timeLag is in nanoseconds.
+type lagSetter func(topic string, partition int32, lag, timeLag int64)
+
+type lagRecord struct {
+	offset    int64
+	timestamp time.Time
+}
+
+type partitionLag struct {
+	highWatermark int64
+	committed     int64
+	pending       []lagRecord
+}
+
+// lagTracker tracks the lag of each topic partition assigned to a consumer and
+// reports it through gauges. The lag of a partition is the difference between
+// its high watermark and the committed offset, and the time lag is the time
+// elapsed since the oldest message consumed but not yet committed was produced,
+// which is zero when the consumer is caught up.
+//
+// Since gauges cannot be unregistered the gauges of partitions that are lost
+// or revoked are reset to zero and are no longer updated until the partition
+// is assigned again.
+type lagTracker struct {
+	mut        sync.Mutex
+	partitions map[string]map[int32]*partitionLag
+	set        lagSetter
+	now        func() time.Time
+}
+
+func newLagTracker(set lagSetter) *lagTracker {
+	return &lagTracker{
+		partitions: map[string]map[int32]*partitionLag{},
+		set:        set,
+		now:        time.Now,
+	}
+}
+
+// assign adds gauges for newly assigned topic partitions. Only assigned
+// partitions are tracked, and so updates to any other partition, such as
+// records still buffered after a partition is revoked, are ignored.
+func (l *lagTracker) assign(m map[string][]int32) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for topic, partitions := range m {
+		topicLags := l.partitions[topic]
+		if topicLags == nil {
+			topicLags = map[int32]*partitionLag{}
+			l.partitions[topic] = topicLags
+		}
+		for _, partition := range partitions {
+			if _, exists := topicLags[partition]; exists {
+				continue
+			}
+			topicLags[partition] = &partitionLag{highWatermark: -1, committed: -1}
+			l.set(topic, partition, 0, 0)
+		}
+	}
+}
+
+// remove resets and stops updating the gauges of lost or revoked topic
+// partitions.
+func (l *lagTracker) remove(m map[string][]int32) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for topic, partitions := range m {
+		topicLags, exists := l.partitions[topic]
+		if !exists {
+			continue
+		}
+		for _, partition := range partitions {
+			if _, exists := topicLags[partition]; !exists {
+				continue
+			}
+			delete(topicLags, partition)
+			l.set(topic, partition, 0, 0)
+		}
+		if len(topicLags) == 0 {
+			delete(l.partitions, topic)
+		}
+	}
+}
+
+// setHighWatermark updates the high watermark of a topic partition. Partitions
+// that are not assigned are ignored.
+func (l *lagTracker) setHighWatermark(topic string, partition int32, highWatermark int64) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	p, exists := l.partitions[topic][partition]
+	if !exists {
+		return
+	}
+	p.highWatermark = highWatermark
+	l.update(topic, partition, p)
+}
+
+// consumed registers a message that has been consumed from a topic partition
+// but not yet committed. Partitions that are not assigned are ignored.
+func (l *lagTracker) consumed(topic string, partition int32, offset int64, timestamp time.Time) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	p, exists := l.partitions[topic][partition]
+	if !exists {
+		return
+	}
+	if p.committed < 0 {
+		// Until an offset is committed the consumer is positioned at the
+		// first message it consumes.
+		p.committed = offset
+	}
+	p.pending = append(p.pending, lagRecord{offset: offset, timestamp: timestamp})
+	l.update(topic, partition, p)
+}
+
+// committed registers the offset of the next message to be consumed from a
+// topic partition once all prior messages are committed. Partitions that are
+// not assigned are ignored.
+func (l *lagTracker) committed(topic string, partition int32, offset int64) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	p, exists := l.partitions[topic][partition]
+	if !exists {
+		return
+	}
+	p.committed = offset
+
+	i := 0
+	for i < len(p.pending) && p.pending[i].offset < offset {
+		i++
+	}
+	p.pending = p.pending[i:]
+	l.update(topic, partition, p)
+}
+
+func (l *lagTracker) update(topic string, partition int32, p *partitionLag) {
+	if p.highWatermark < 0 || p.committed < 0 {
+		return
+	}
+
+	lag := p.highWatermark - p.committed
+	if lag < 0 {
+		lag = 0
+	}
+
+	var timeLag int64
+	if lag > 0 && len(p.pending) > 0 {
+		if timeLag = l.now().Sub(p.pending[0].timestamp).Nanoseconds(); timeLag < 0 {
+			timeLag = 0
+		}
+	}
+	l.set(topic, partition, lag, timeLag)
+}
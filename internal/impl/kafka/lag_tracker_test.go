@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lagGauges map[string]map[int32][2]int64
+
+func newTestLagTracker(now time.Time) (*lagTracker, lagGauges) {
+	gauges := lagGauges{}
+	l := newLagTracker(func(topic string, partition int32, lag, timeLag int64) {
+		if gauges[topic] == nil {
+			gauges[topic] = map[int32][2]int64{}
+		}
+		gauges[topic][partition] = [2]int64{lag, timeLag}
+	})
+	l.now = func() time.Time {
+		return now
+	}
+	return l, gauges
+}
+
+func TestLagTrackerConsumedAndCommitted(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l, gauges := newTestLagTracker(now)
+
+	l.assign(map[string][]int32{"foo": {0, 1}})
+	assert.Equal(t, lagGauges{
+		"foo": {0: {0, 0}, 1: {0, 0}},
+	}, gauges)
+
+	l.setHighWatermark("foo", 0, 20)
+	l.consumed("foo", 0, 10, now.Add(-time.Minute))
+	l.consumed("foo", 0, 11, now.Add(-time.Second*30))
+	l.consumed("foo", 0, 12, now.Add(-time.Second*10))
+	assert.Equal(t, [2]int64{10, time.Minute.Nanoseconds()}, gauges["foo"][0])
+
+	l.committed("foo", 0, 12)
+	assert.Equal(t, [2]int64{8, (time.Second * 10).Nanoseconds()}, gauges["foo"][0])
+
+	l.setHighWatermark("foo", 0, 13)
+	l.committed("foo", 0, 13)
+	assert.Equal(t, [2]int64{0, 0}, gauges["foo"][0])
+
+	assert.Equal(t, [2]int64{0, 0}, gauges["foo"][1])
+}
+
+func TestLagTrackerRemove(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l, gauges := newTestLagTracker(now)
+
+	l.assign(map[string][]int32{"foo": {0}, "bar": {0}})
+	l.setHighWatermark("foo", 0, 20)
+	l.consumed("foo", 0, 10, now.Add(-time.Minute))
+	l.setHighWatermark("bar", 0, 5)
+	l.consumed("bar", 0, 2, now.Add(-time.Minute))
+	assert.Equal(t, lagGauges{
+		"foo": {0: {10, time.Minute.Nanoseconds()}},
+		"bar": {0: {3, time.Minute.Nanoseconds()}},
+	}, gauges)
+
+	l.remove(map[string][]int32{"foo": {0}})
+	assert.Equal(t, lagGauges{
+		"foo": {0: {0, 0}},
+		"bar": {0: {3, time.Minute.Nanoseconds()}},
+	}, gauges)
+
+	// Commits of removed partitions are ignored.
+	l.committed("foo", 0, 11)
+	assert.Equal(t, [2]int64{0, 0}, gauges["foo"][0])
+
+	assert.Len(t, l.partitions, 1)
+}
+
+func TestLagTrackerLateUpdatesAfterRemove(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l, gauges := newTestLagTracker(now)
+
+	// Updates of partitions that were never assigned are ignored.
+	l.setHighWatermark("foo", 0, 20)
+	l.consumed("foo", 0, 10, now.Add(-time.Minute))
+	assert.Equal(t, lagGauges{}, gauges)
+	assert.Empty(t, l.partitions)
+
+	l.assign(map[string][]int32{"foo": {0}})
+	l.setHighWatermark("foo", 0, 20)
+	l.consumed("foo", 0, 10, now.Add(-time.Minute))
+	assert.Equal(t, [2]int64{10, time.Minute.Nanoseconds()}, gauges["foo"][0])
+
+	l.remove(map[string][]int32{"foo": {0}})
+	assert.Equal(t, [2]int64{0, 0}, gauges["foo"][0])
+
+	// Records still buffered after the partition is revoked must not bring
+	// it back.
+	l.setHighWatermark("foo", 0, 25)
+	l.consumed("foo", 0, 11, now.Add(-time.Second*30))
+	l.consumed("foo", 0, 12, now.Add(-time.Second*20))
+	assert.Equal(t, [2]int64{0, 0}, gauges["foo"][0])
+	assert.Empty(t, l.partitions)
+
+	// Assigning the partition again starts from a clean slate.
+	l.assign(map[string][]int32{"foo": {0}})
+	l.setHighWatermark("foo", 0, 30)
+	l.consumed("foo", 0, 28, now.Add(-time.Second*5))
+	assert.Equal(t, [2]int64{2, (time.Second * 5).Nanoseconds()}, gauges["foo"][0])
+	assert.Len(t, l.partitions["foo"][0].pending, 1)
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
@@ -30,6 +33,18 @@ This output is new and experimental, and the existing ` + "`kafka`" + ` input is
 - You like shiny new stuff
 - You are experiencing issues with the existing ` + "`kafka`" + ` output
 - Someone told you to
+
+### Exactly-Once Delivery
+
+When the field ` + "`transactional_id`" + ` is set messages are written within Kafka transactions. When combined with a ` + "[`kafka_franz` input](/docs/components/inputs/kafka_franz)" + ` that has ` + "`exactly_once`" + ` enabled the offsets of the consumed messages are committed within the same transaction, and therefore each consumed message is written exactly once to the output topics even in the event of crashes, restarts and consumer group rebalances.
+
+A transaction is committed for each batch, and therefore batching messages with the ` + "`batching`" + ` field reduces the overhead of transactions. Batches are written one at a time in order to preserve the ordering of offsets, which means the field ` + "`max_in_flight`" + ` is ignored. Processing must also preserve the order of messages consumed from each partition, and therefore pipelines should be configured with a single processing thread.
+
+The ` + "`transactional_id`" + ` must be unique to each running instance and must remain the same across restarts, so that transactions left open by a previous run of the same instance are aborted when it reconnects. Consumers of the output topics should read with the isolation level ` + "`read_committed`" + ` in order to ignore messages of aborted transactions.
+
+### Topic Creation
+
+When ` + "`topic_creation.enabled`" + ` is set the output creates each topic that it writes to before the first message is written to it, using the number of partitions, replication factor and topic configs specified within ` + "`topic_creation`" + `. Topics that already exist are left untouched. This is useful when the brokers have automatic topic creation disabled, or when topics must be created with configs other than the broker defaults.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -68,6 +83,31 @@ This output is new and experimental, and the existing ` + "`kafka`" + ` input is
 			Description("Optionally set an explicit compression type. The default preference is to use snappy when the broker supports it, and fall back to none if not.").
 			Optional().
 			Advanced()).
+		Field(service.NewStringField("transactional_id").
+			Description("An optional transactional ID that enables writing messages within Kafka transactions. When set the offsets of messages consumed by a `kafka_franz` input with `exactly_once` enabled are committed within the same transactions. This ID must be unique to each running instance of the output.").
+			Example("benthos-orders-0").
+			Optional().
+			Advanced().
+			Version("4.5.0")).
+		Field(service.NewObjectField("topic_creation",
+			service.NewBoolField("enabled").
+				Description("Whether topics should be created before messages are written to them.").
+				Default(false),
+			service.NewIntField("partitions").
+				Description("The number of partitions of created topics. Set to `-1` in order to use the default of the brokers.").
+				Default(-1),
+			service.NewIntField("replication_factor").
+				Description("The replication factor of created topics. Set to `-1` in order to use the default of the brokers.").
+				Default(-1),
+			service.NewStringMapField("configs").
+				Description("A map of topic configs to set on created topics.").
+				Example(map[string]interface{}{"retention.ms": "86400000", "cleanup.policy": "compact"}).
+				Default(map[string]string{}),
+		).
+			Description("Allows topics that do not yet exist to be created by the output.").
+			Advanced().
+			Version("4.5.0")).
+		Field(schemaRegistryOutputField()).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField())
 }
@@ -86,7 +126,16 @@ func init() {
 			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
 				return
 			}
-			output, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger())
+			var w *franzKafkaWriter
+			if w, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger()); err != nil {
+				return
+			}
+			if w.transactionalID != "" {
+				// Transactions must be committed in the order that messages
+				// were consumed.
+				maxInFlight = 1
+			}
+			output = w
 			return
 		})
 
@@ -109,9 +158,15 @@ type franzKafkaWriter struct {
 	timeout          time.Duration
 	produceMaxBytes  int32
 	compressionPrefs []kgo.CompressionCodec
+	transactionalID  string
+	schemaEncoder    *franzSchemaEncoder
+	topicCreation    *topicCreationConfig
 
 	client *kgo.Client
 
+	createdMut    sync.Mutex
+	createdTopics map[string]struct{}
+
 	log     *service.Logger
 	shutSig *shutdown.Signaller
 }
@@ -217,9 +272,61 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if conf.Contains("schema_registry") {
+		if f.schemaEncoder, err = franzSchemaEncoderFromParsed(conf.Namespace("schema_registry")); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains("transactional_id") {
+		if f.transactionalID, err = conf.FieldString("transactional_id"); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.topicCreation, err = topicCreationFromParsed(conf.Namespace("topic_creation")); err != nil {
+		return nil, err
+	}
+
 	return &f, nil
 }
 
+// topicCreationFromParsed returns the topic creation config of the output, or
+// nil when topic creation is disabled.
+func topicCreationFromParsed(conf *service.ParsedConfig) (*topicCreationConfig, error) {
+	enabled, err := conf.FieldBool("enabled")
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	partitions, err := conf.FieldInt("partitions")
+	if err != nil {
+		return nil, err
+	}
+	if partitions == 0 || partitions < -1 || partitions > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid topic_creation.partitions: %v", partitions)
+	}
+
+	replicationFactor, err := conf.FieldInt("replication_factor")
+	if err != nil {
+		return nil, err
+	}
+	if replicationFactor == 0 || replicationFactor < -1 || replicationFactor > math.MaxInt16 {
+		return nil, fmt.Errorf("invalid topic_creation.replication_factor: %v", replicationFactor)
+	}
+
+	configs, err := conf.FieldStringMap("configs")
+	if err != nil {
+		return nil, err
+	}
+
+	return &topicCreationConfig{
+		partitions:        int32(partitions),
+		replicationFactor: int16(replicationFactor),
+		configs:           configs,
+	}, nil
+}
+
 //------------------------------------------------------------------------------
 
 func (f *franzKafkaWriter) Connect(ctx context.Context) error {
@@ -244,6 +351,9 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	if len(f.compressionPrefs) > 0 {
 		clientOpts = append(clientOpts, kgo.ProducerBatchCompression(f.compressionPrefs...))
 	}
+	if f.transactionalID != "" {
+		clientOpts = append(clientOpts, kgo.TransactionalID(f.transactionalID))
+	}
 
 	cl, err := kgo.NewClient(clientOpts...)
 	if err != nil {
@@ -255,16 +365,87 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	return nil
 }
 
-func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatch) (err error) {
+// ensureTopics creates any topics of a batch that have not yet been created by
+// the output, when topic creation is enabled.
+func (f *franzKafkaWriter) ensureTopics(ctx context.Context, b service.MessageBatch) error {
+	if f.topicCreation == nil {
+		return nil
+	}
+
+	f.createdMut.Lock()
+	defer f.createdMut.Unlock()
+
+	if f.createdTopics == nil {
+		f.createdTopics = map[string]struct{}{}
+	}
+
+	var topics []string
+	seen := map[string]struct{}{}
+	for i := range b {
+		topic := b.InterpolatedString(i, f.topic)
+		if _, exists := f.createdTopics[topic]; exists {
+			continue
+		}
+		if _, exists := seen[topic]; exists {
+			continue
+		}
+		seen[topic] = struct{}{}
+		topics = append(topics, topic)
+	}
+	if len(topics) == 0 {
+		return nil
+	}
+
+	if err := createTopics(ctx, f.client, *f.topicCreation, f.timeout, topics...); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		f.createdTopics[topic] = struct{}{}
+	}
+	return nil
+}
+
+func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
 	if f.client == nil {
 		return service.ErrNotConnected
 	}
 
+	if f.transactionalID != "" {
+		return f.writeBatchTxn(ctx, b)
+	}
+
+	if err := f.ensureTopics(ctx, b); err != nil {
+		return err
+	}
+
+	records, err := f.batchToRecords(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	// TODO: This is very cool and allows us to easily return granular errors,
+	// so we should honor travis by doing it.
+	return f.client.ProduceSync(ctx, records...).FirstErr()
+}
+
+// batchToRecords converts a batch of messages into records, messages that have
+// been superseded by a rewind of the input that consumed them are skipped.
+func (f *franzKafkaWriter) batchToRecords(ctx context.Context, b service.MessageBatch) ([]*kgo.Record, error) {
 	records := make([]*kgo.Record, 0, len(b))
 	for i, msg := range b {
+		if isTxnSuperseded(msg) {
+			continue
+		}
+
 		record := &kgo.Record{Topic: b.InterpolatedString(i, f.topic)}
-		if record.Value, err = msg.AsBytes(); err != nil {
-			return
+
+		var err error
+		if f.schemaEncoder != nil {
+			if record.Value, err = f.schemaEncoder.encode(ctx, record.Topic, msg); err != nil {
+				return nil, fmt.Errorf("failed to encode message for topic %v: %w", record.Topic, err)
+			}
+		} else if record.Value, err = msg.AsBytes(); err != nil {
+			return nil, err
 		}
 		if f.key != nil {
 			record.Key = b.InterpolatedBytes(i, f.key)
@@ -278,11 +459,100 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		})
 		records = append(records, record)
 	}
+	return records, nil
+}
 
-	// TODO: This is very cool and allows us to easily return granular errors,
-	// so we should honor travis by doing it.
-	err = f.client.ProduceSync(ctx, records...).FirstErr()
-	return
+// writeBatchTxn writes a batch of messages within a transaction, along with
+// the offsets of any messages that were consumed with exactly-once delivery.
+// When the batch fails to be written the inputs that consumed its messages are
+// rewound to their committed offsets, including when the offsets are rejected
+// because the consumer group has since rebalanced. The messages are then
+// consumed again, and therefore the messages of the failed batch are skipped
+// when retried.
+func (f *franzKafkaWriter) writeBatchTxn(ctx context.Context, b service.MessageBatch) (err error) {
+	defer func() {
+		if err != nil {
+			rewindTxnSessions(b)
+		}
+	}()
+
+	if err = f.ensureTopics(ctx, b); err != nil {
+		return err
+	}
+
+	records, err := f.batchToRecords(ctx, b)
+	if err != nil {
+		return err
+	}
+	return f.commitTxn(ctx, records, txnOffsetsFromBatch(b))
+}
+
+// commitTxn writes records and commits consumer group offsets within a single
+// transaction. Transactions without records are skipped, as the client only
+// ends transactions that it produced records within and the offsets would be
+// left within an open transaction. Every message that carries an offset also
+// produces a record, so this only happens for empty batches and batches of
+// superseded messages, and any offsets skipped are committed by the next
+// transaction of the same partitions.
+func (f *franzKafkaWriter) commitTxn(ctx context.Context, records []*kgo.Record, offsets []*txnGroupOffsets) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := f.client.BeginTransaction(); err != nil {
+		return err
+	}
+
+	err := f.client.ProduceSync(ctx, records...).FirstErr()
+	for _, g := range offsets {
+		if err != nil {
+			break
+		}
+		err = f.addTxnOffsets(ctx, g)
+	}
+	if err != nil {
+		if aErr := f.client.EndTransaction(ctx, kgo.TryAbort); aErr != nil {
+			f.log.Errorf("Failed to abort transaction: %v", aErr)
+		}
+		return err
+	}
+	return f.client.EndTransaction(ctx, kgo.TryCommit)
+}
+
+// addTxnOffsets adds the offsets of a consumer group generation to the current
+// transaction, which are committed along with it.
+func (f *franzKafkaWriter) addTxnOffsets(ctx context.Context, g *txnGroupOffsets) error {
+	producerID, producerEpoch, err := f.client.ProducerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	addReq := kmsg.NewPtrAddOffsetsToTxnRequest()
+	addReq.TransactionalID = f.transactionalID
+	addReq.ProducerID = producerID
+	addReq.ProducerEpoch = producerEpoch
+	addReq.Group = g.group
+
+	addRes, err := addReq.RequestWith(ctx, f.client)
+	if err != nil {
+		return err
+	}
+	if err = kerr.ErrorForCode(addRes.ErrorCode); err != nil {
+		return fmt.Errorf("failed to add offsets of consumer group %v to transaction: %w", g.group, err)
+	}
+
+	commitRes, err := newTxnOffsetCommitRequest(f.transactionalID, producerID, producerEpoch, g).RequestWith(ctx, f.client)
+	if err != nil {
+		return err
+	}
+	for _, t := range commitRes.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return fmt.Errorf("failed to commit offset of topic %v partition %v: %w", t.Topic, p.Partition, err)
+			}
+		}
+	}
+	return nil
 }
 
 func (f *franzKafkaWriter) disconnect() {
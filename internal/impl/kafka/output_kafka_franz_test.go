@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestKafkaFranzOutputTopicCreationConfig(t *testing.T) {
+	spec := franzKafkaOutputConfig()
+
+	pConf, err := spec.ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topic: foo
+`, service.NewEnvironment())
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+	assert.Nil(t, w.topicCreation)
+
+	pConf, err = spec.ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topic: foo
+topic_creation:
+  enabled: true
+  partitions: 3
+  configs:
+    cleanup.policy: compact
+`, service.NewEnvironment())
+	require.NoError(t, err)
+
+	w, err = newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &topicCreationConfig{
+		partitions:        3,
+		replicationFactor: -1,
+		configs:           map[string]string{"cleanup.policy": "compact"},
+	}, w.topicCreation)
+
+	pConf, err = spec.ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topic: foo
+topic_creation:
+  enabled: true
+  replication_factor: 0
+`, service.NewEnvironment())
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaWriterFromConfig(pConf, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid topic_creation.replication_factor")
+}
+
+func TestKafkaFranzOutputTxnWithoutRecords(t *testing.T) {
+	pConf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topic: foo
+transactional_id: footxn
+`, service.NewEnvironment())
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+
+	// The writer is not connected, and so a transaction that is not skipped
+	// would panic on the missing client.
+	require.NoError(t, w.commitTxn(context.Background(), nil, []*txnGroupOffsets{
+		{
+			txnGeneration: txnGeneration{group: "foogroup", memberID: "foomember", generation: 1},
+			offsets: map[string]map[int32]kgo.EpochOffset{
+				"foo": {0: {Epoch: 1, Offset: 10}},
+			},
+		},
+	}))
+}
@@ -0,0 +1,358 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	kafkaAdminOpConsumerGroupLag = "consumer_group_lag"
+	kafkaAdminOpListOffsets      = "list_offsets"
+)
+
+func kafkaAdminProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Integration").
+		Version("4.5.0").
+		Summary("Performs administrative queries against Kafka brokers, such as describing the lag of a consumer group or listing the offsets of topic partitions.").
+		Description(`
+For each message processed the query specified by the field `+"`operation`"+` is executed, and the message is replaced with one message per topic partition in the results. The contents of the original message are discarded, but its metadata is retained.
+
+This makes it possible to emit metrics such as consumer group lag from a stream by pairing this processor with a `+"[`generate` input](/docs/components/inputs/generate)"+` and a `+"[`metric` processor](/docs/components/processors/metric)"+`, without the need for an external exporter.
+
+### Consumer Group Lag
+
+With the operation `+"`consumer_group_lag`"+` each resulting message is a JSON document of the following form:
+
+`+"```json"+`
+{
+  "group": "benthos_group",
+  "topic": "foo",
+  "partition": 0,
+  "committed_offset": 120,
+  "end_offset": 150,
+  "lag": 30
+}
+`+"```"+`
+
+When the field `+"`topics`"+` is empty the lag is described for all topics that the consumer group has committed offsets to. When the consumer group has not committed an offset for a partition the `+"`committed_offset`"+` is `+"`-1`"+` and the lag is the number of messages that are currently retained by the partition.
+
+### List Offsets
+
+With the operation `+"`list_offsets`"+` each resulting message is a JSON document of the following form:
+
+`+"```json"+`
+{
+  "topic": "foo",
+  "partition": 0,
+  "start_offset": 100,
+  "end_offset": 150
+}
+`+"```").
+		Field(service.NewStringListField("seed_brokers").
+			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
+			Example([]string{"localhost:9092"}).
+			Example([]string{"foo:9092", "bar:9092"}).
+			Example([]string{"foo:9092,bar:9092"})).
+		Field(service.NewStringAnnotatedEnumField("operation", map[string]string{
+			kafkaAdminOpConsumerGroupLag: "Describe the lag of a consumer group for each partition of the topics it consumes.",
+			kafkaAdminOpListOffsets:      "List the earliest and latest offsets of each partition of a list of topics.",
+		}).
+			Description("The query to execute for each message.")).
+		Field(service.NewInterpolatedStringField("consumer_group").
+			Description("The consumer group to describe, required by the operation `consumer_group_lag`.").
+			Example("benthos_group").
+			Optional()).
+		Field(service.NewStringListField("topics").
+			Description("A list of topics to query. This field is required by the operation `list_offsets`, and optionally restricts the topics described by the operation `consumer_group_lag`. If an item of the list contains commas it will be expanded into multiple topics.").
+			Example([]string{"foo", "bar"}).
+			Optional()).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period of time to wait for the queries of a message to complete.").
+			Default("10s").
+			Advanced()).
+		Field(service.NewTLSToggledField("tls")).
+		Field(saslField()).
+		Example("Consumer Group Lag Metrics", `This example emits a gauge of the lag of a consumer group for each topic partition every 30 seconds, which can be scraped alongside the other metrics of Benthos.`, `
+input:
+  generate:
+    interval: 30s
+    mapping: root = {}
+  processors:
+    - kafka_admin:
+        seed_brokers: [ localhost:9092 ]
+        operation: consumer_group_lag
+        consumer_group: benthos_group
+    - metric:
+        type: gauge
+        name: kafka_consumer_group_lag
+        labels:
+          group: ${! json("group") }
+          topic: ${! json("topic") }
+          partition: ${! json("partition") }
+        value: ${! json("lag") }
+
+output:
+  drop: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"kafka_admin", kafkaAdminProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newKafkaAdminProcessorFromConfig(conf, mgr.Logger())
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type kafkaAdminProcessor struct {
+	seedBrokers []string
+	operation   string
+	group       *service.InterpolatedString
+	topics      []string
+	timeout     time.Duration
+	tlsConf     *tls.Config
+	saslConfs   []sasl.Mechanism
+
+	client *kgo.Client
+
+	log *service.Logger
+}
+
+func newKafkaAdminProcessorFromConfig(conf *service.ParsedConfig, log *service.Logger) (*kafkaAdminProcessor, error) {
+	k := kafkaAdminProcessor{
+		log: log,
+	}
+
+	brokerList, err := conf.FieldStringList("seed_brokers")
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range brokerList {
+		k.seedBrokers = append(k.seedBrokers, strings.Split(b, ",")...)
+	}
+
+	if k.operation, err = conf.FieldString("operation"); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("consumer_group") {
+		if k.group, err = conf.FieldInterpolatedString("consumer_group"); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains("topics") {
+		topicList, err := conf.FieldStringList("topics")
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range topicList {
+			for _, splitTopic := range strings.Split(t, ",") {
+				if trimmed := strings.TrimSpace(splitTopic); trimmed != "" {
+					k.topics = append(k.topics, trimmed)
+				}
+			}
+		}
+	}
+
+	switch k.operation {
+	case kafkaAdminOpConsumerGroupLag:
+		if k.group == nil {
+			return nil, errors.New("field consumer_group is required by the operation consumer_group_lag")
+		}
+	case kafkaAdminOpListOffsets:
+		if len(k.topics) == 0 {
+			return nil, errors.New("field topics is required by the operation list_offsets")
+		}
+	default:
+		return nil, fmt.Errorf("operation %v not recognised", k.operation)
+	}
+
+	if k.timeout, err = conf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		k.tlsConf = tlsConf
+	}
+	if k.saslConfs, err = saslMechanismsFromConfig(conf); err != nil {
+		return nil, err
+	}
+
+	clientOpts := []kgo.Opt{
+		kgo.SeedBrokers(k.seedBrokers...),
+		kgo.SASL(k.saslConfs...),
+		kgo.WithLogger(&kgoLogger{k.log}),
+	}
+	if k.tlsConf != nil {
+		clientOpts = append(clientOpts, kgo.DialTLSConfig(k.tlsConf))
+	}
+	if k.client, err = kgo.NewClient(clientOpts...); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (k *kafkaAdminProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	ctx, done := context.WithTimeout(ctx, k.timeout)
+	defer done()
+
+	var rows []map[string]interface{}
+	var err error
+	switch k.operation {
+	case kafkaAdminOpConsumerGroupLag:
+		rows, err = k.consumerGroupLag(ctx, k.group.String(msg))
+	case kafkaAdminOpListOffsets:
+		rows, err = k.listOffsets(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(rows))
+	for _, row := range rows {
+		part := msg.Copy()
+		part.SetStructured(row)
+		batch = append(batch, part)
+	}
+	return batch, nil
+}
+
+func (k *kafkaAdminProcessor) consumerGroupLag(ctx context.Context, group string) ([]map[string]interface{}, error) {
+	var partitions map[string][]int32
+	var committed map[string]map[int32]int64
+	var err error
+
+	if len(k.topics) > 0 {
+		if partitions, err = topicPartitions(ctx, k.client, k.topics); err != nil {
+			return nil, err
+		}
+		if committed, err = fetchGroupOffsets(ctx, k.client, group, partitions); err != nil {
+			return nil, err
+		}
+	} else {
+		if committed, err = fetchGroupOffsets(ctx, k.client, group, nil); err != nil {
+			return nil, err
+		}
+		topics := make([]string, 0, len(committed))
+		for topic := range committed {
+			topics = append(topics, topic)
+		}
+		if partitions, err = topicPartitions(ctx, k.client, topics); err != nil {
+			return nil, err
+		}
+	}
+
+	start, err := listOffsets(ctx, k.client, partitions, listOffsetsEarliest)
+	if err != nil {
+		return nil, err
+	}
+	end, err := listOffsets(ctx, k.client, partitions, listOffsetsLatest)
+	if err != nil {
+		return nil, err
+	}
+	return consumerGroupLagRows(group, partitions, committed, start, end), nil
+}
+
+func (k *kafkaAdminProcessor) listOffsets(ctx context.Context) ([]map[string]interface{}, error) {
+	partitions, err := topicPartitions(ctx, k.client, k.topics)
+	if err != nil {
+		return nil, err
+	}
+	start, err := listOffsets(ctx, k.client, partitions, listOffsetsEarliest)
+	if err != nil {
+		return nil, err
+	}
+	end, err := listOffsets(ctx, k.client, partitions, listOffsetsLatest)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	walkTopicPartitions(partitions, func(topic string, partition int32) {
+		rows = append(rows, map[string]interface{}{
+			"topic":        topic,
+			"partition":    int64(partition),
+			"start_offset": start[topic][partition],
+			"end_offset":   end[topic][partition],
+		})
+	})
+	return rows, nil
+}
+
+// consumerGroupLagRows returns the lag of a consumer group for each partition,
+// where the lag of a partition without a committed offset is the number of
+// messages retained by that partition.
+func consumerGroupLagRows(group string, partitions map[string][]int32, committed, start, end map[string]map[int32]int64) []map[string]interface{} {
+	var rows []map[string]interface{}
+	walkTopicPartitions(partitions, func(topic string, partition int32) {
+		committedOffset, exists := committed[topic][partition]
+		if !exists {
+			committedOffset = -1
+		}
+		endOffset := end[topic][partition]
+
+		lag := endOffset - committedOffset
+		if committedOffset < 0 {
+			lag = endOffset - start[topic][partition]
+		}
+		if lag < 0 {
+			lag = 0
+		}
+
+		rows = append(rows, map[string]interface{}{
+			"group":            group,
+			"topic":            topic,
+			"partition":        int64(partition),
+			"committed_offset": committedOffset,
+			"end_offset":       endOffset,
+			"lag":              lag,
+		})
+	})
+	return rows
+}
+
+// walkTopicPartitions calls fn for each topic partition ordered by topic and
+// then partition.
+func walkTopicPartitions(partitions map[string][]int32, fn func(topic string, partition int32)) {
+	topics := make([]string, 0, len(partitions))
+	for topic := range partitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	for _, topic := range topics {
+		for _, partition := range partitions[topic] {
+			fn(topic, partition)
+		}
+	}
+}
+
+func (k *kafkaAdminProcessor) Close(ctx context.Context) error {
+	k.client.Close()
+	return nil
+}
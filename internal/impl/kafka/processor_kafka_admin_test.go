@@ -0,0 +1,115 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestKafkaAdminProcessorConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		errContains string
+	}{
+		{
+			name: "consumer group lag",
+			config: `
+seed_brokers: [ localhost:9092 ]
+operation: consumer_group_lag
+consumer_group: foo
+`,
+		},
+		{
+			name: "consumer group lag without group",
+			config: `
+seed_brokers: [ localhost:9092 ]
+operation: consumer_group_lag
+`,
+			errContains: "field consumer_group is required",
+		},
+		{
+			name: "list offsets",
+			config: `
+seed_brokers: [ localhost:9092 ]
+operation: list_offsets
+topics: [ foo,bar ]
+`,
+		},
+		{
+			name: "list offsets without topics",
+			config: `
+seed_brokers: [ localhost:9092 ]
+operation: list_offsets
+`,
+			errContains: "field topics is required",
+		},
+	}
+
+	spec := kafkaAdminProcessorConfig()
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := spec.ParseYAML(test.config, service.NewEnvironment())
+			require.NoError(t, err)
+
+			proc, err := newKafkaAdminProcessorFromConfig(pConf, nil)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			proc.client.Close()
+		})
+	}
+}
+
+func TestKafkaAdminConsumerGroupLagRows(t *testing.T) {
+	partitions := map[string][]int32{
+		"foo": {0, 1},
+		"bar": {0},
+	}
+	committed := map[string]map[int32]int64{
+		"foo": {0: 10, 1: -1},
+		"bar": {0: 25},
+	}
+	start := map[string]map[int32]int64{
+		"foo": {0: 0, 1: 5},
+		"bar": {0: 0},
+	}
+	end := map[string]map[int32]int64{
+		"foo": {0: 15, 1: 12},
+		"bar": {0: 20},
+	}
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"group":            "foogroup",
+			"topic":            "bar",
+			"partition":        int64(0),
+			"committed_offset": int64(25),
+			"end_offset":       int64(20),
+			"lag":              int64(0),
+		},
+		{
+			"group":            "foogroup",
+			"topic":            "foo",
+			"partition":        int64(0),
+			"committed_offset": int64(10),
+			"end_offset":       int64(15),
+			"lag":              int64(5),
+		},
+		{
+			"group":            "foogroup",
+			"topic":            "foo",
+			"partition":        int64(1),
+			"committed_offset": int64(-1),
+			"end_offset":       int64(12),
+			"lag":              int64(7),
+		},
+	}, consumerGroupLagRows("foogroup", partitions, committed, start, end))
+}
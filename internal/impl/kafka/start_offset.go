@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The types of start offset supported by the Kafka inputs.
+const (
+	startOffsetNone             = "none"
+	startOffsetEarliest         = "earliest"
+	startOffsetLatest           = "latest"
+	startOffsetTimestamp        = "timestamp"
+	startOffsetPartitionOffsets = "partition_offsets"
+)
+
+const startOffsetFieldDescription = "Determines the offset that a consumer group starts consuming each topic partition from. By default the start offset only applies to partitions that do not have a committed offset for the consumer group, in which case it takes precedence over `start_from_oldest`."
+
+var startOffsetTypeOptions = map[string]string{
+	startOffsetNone:             "Start from the offset determined by `start_from_oldest`.",
+	startOffsetEarliest:         "Start from the oldest available offset of each partition, moved by `relative`.",
+	startOffsetLatest:           "Start from the latest offset of each partition, moved by `relative`.",
+	startOffsetTimestamp:        "Start from the first message of each partition with a timestamp equal to or later than `timestamp`.",
+	startOffsetPartitionOffsets: "Start from the offsets of `partition_offsets`, partitions that are not listed start from the offset determined by `start_from_oldest`.",
+}
+
+const (
+	startOffsetRelativeDescription         = "A number of messages to move the start offset of each partition by when the type is `earliest` or `latest`. For example, a type `latest` with a relative of `-100` starts from 100 messages before the end of each partition."
+	startOffsetTimestampDescription        = "The timestamp to start from when the type is `timestamp`, as either a unix timestamp in milliseconds or an RFC 3339 timestamp."
+	startOffsetPartitionOffsetsDescription = "A map of explicit offsets to start from when the type is `partition_offsets`, keyed by a topic and partition separated by a colon."
+	startOffsetForceDescription            = "Whether the start offset should also apply to partitions that have a committed offset for the consumer group. The start offset is only forced the first time that a partition is assigned after the input starts, and partitions assigned afterwards resume from their committed offsets."
+)
+
+// startOffset describes the offset that a consumer group starts consuming
+// topic partitions from.
+type startOffset struct {
+	kind             string
+	relative         int64
+	timestampMillis  int64
+	partitionOffsets map[string]map[int32]int64
+	force            bool
+
+	forcedMut sync.Mutex
+	forced    map[string]map[int32]struct{}
+}
+
+// newStartOffset creates a start offset from its config fields, or returns nil
+// when the type is none.
+func newStartOffset(kind string, relative int64, timestamp string, partitionOffsets map[string]int64, force bool) (*startOffset, error) {
+	s := &startOffset{
+		kind:     kind,
+		relative: relative,
+		force:    force,
+		forced:   map[string]map[int32]struct{}{},
+	}
+
+	switch kind {
+	case startOffsetNone, "":
+		return nil, nil
+	case startOffsetEarliest, startOffsetLatest:
+	case startOffsetTimestamp:
+		if timestamp == "" {
+			return nil, errors.New("a timestamp must be specified when the start offset type is timestamp")
+		}
+		var err error
+		if s.timestampMillis, err = parseStartTimestamp(timestamp); err != nil {
+			return nil, err
+		}
+	case startOffsetPartitionOffsets:
+		if len(partitionOffsets) == 0 {
+			return nil, errors.New("partition offsets must be specified when the start offset type is partition_offsets")
+		}
+		s.partitionOffsets = map[string]map[int32]int64{}
+		for k, offset := range partitionOffsets {
+			topic, partition, err := parseTopicPartition(k)
+			if err != nil {
+				return nil, err
+			}
+			if offset < 0 {
+				return nil, fmt.Errorf("start offset of %v must not be negative", k)
+			}
+			if s.partitionOffsets[topic] == nil {
+				s.partitionOffsets[topic] = map[int32]int64{}
+			}
+			s.partitionOffsets[topic][partition] = offset
+		}
+	default:
+		return nil, fmt.Errorf("start offset type %v not recognised", kind)
+	}
+	return s, nil
+}
+
+// parseStartTimestamp parses either a unix timestamp in milliseconds or an RFC
+// 3339 timestamp into a unix timestamp in milliseconds.
+func parseStartTimestamp(s string) (int64, error) {
+	if millis, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return millis, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse start offset timestamp: %w", err)
+	}
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
+
+func parseTopicPartition(s string) (string, int32, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("partition offset key '%v' is invalid, expected a topic and partition separated by a colon, e.g. foo:0", s)
+	}
+	partition, err := strconv.ParseInt(s[i+1:], 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to parse partition of partition offset key '%v': %w", s, err)
+	}
+	return s[:i], int32(partition), nil
+}
+
+// shouldApply returns whether the start offset applies to a topic partition,
+// which is the case when it has no committed offset or when the start offset
+// is forced and the partition has not yet been assigned since the input
+// started.
+func (s *startOffset) shouldApply(topic string, partition int32, hasCommitted bool) bool {
+	s.forcedMut.Lock()
+	defer s.forcedMut.Unlock()
+
+	_, alreadyForced := s.forced[topic][partition]
+	if s.force && !alreadyForced {
+		if s.forced[topic] == nil {
+			s.forced[topic] = map[int32]struct{}{}
+		}
+		s.forced[topic][partition] = struct{}{}
+		return true
+	}
+	return !hasCommitted
+}
+
+// explicitOffset returns the offset of a topic partition listed within the
+// partition offsets, if any.
+func (s *startOffset) explicitOffset(topic string, partition int32) (int64, bool) {
+	offset, exists := s.partitionOffsets[topic][partition]
+	return offset, exists
+}
@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestStartOffsetParse(t *testing.T) {
+	s, err := newStartOffset("none", 0, "", nil, false)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	s, err = newStartOffset("latest", -100, "", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, startOffsetLatest, s.kind)
+	assert.Equal(t, int64(-100), s.relative)
+
+	s, err = newStartOffset("timestamp", 0, "1659312000000", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1659312000000), s.timestampMillis)
+
+	s, err = newStartOffset("timestamp", 0, "2022-08-01T00:00:00Z", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1659312000000), s.timestampMillis)
+
+	s, err = newStartOffset("partition_offsets", 0, "", map[string]int64{
+		"foo:0":     10,
+		"foo:1":     20,
+		"bar.baz:3": 30,
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int32]int64{
+		"foo":     {0: 10, 1: 20},
+		"bar.baz": {3: 30},
+	}, s.partitionOffsets)
+
+	offset, exists := s.explicitOffset("foo", 1)
+	assert.True(t, exists)
+	assert.Equal(t, int64(20), offset)
+
+	_, exists = s.explicitOffset("foo", 2)
+	assert.False(t, exists)
+}
+
+func TestStartOffsetParseErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		kind             string
+		timestamp        string
+		partitionOffsets map[string]int64
+		errContains      string
+	}{
+		{
+			name:        "unknown type",
+			kind:        "meow",
+			errContains: "start offset type meow not recognised",
+		},
+		{
+			name:        "missing timestamp",
+			kind:        "timestamp",
+			errContains: "a timestamp must be specified",
+		},
+		{
+			name:        "bad timestamp",
+			kind:        "timestamp",
+			timestamp:   "yesterday",
+			errContains: "failed to parse start offset timestamp",
+		},
+		{
+			name:        "missing partition offsets",
+			kind:        "partition_offsets",
+			errContains: "partition offsets must be specified",
+		},
+		{
+			name:             "missing partition",
+			kind:             "partition_offsets",
+			partitionOffsets: map[string]int64{"foo": 10},
+			errContains:      "partition offset key 'foo' is invalid",
+		},
+		{
+			name:             "bad partition",
+			kind:             "partition_offsets",
+			partitionOffsets: map[string]int64{"foo:bar": 10},
+			errContains:      "failed to parse partition of partition offset key 'foo:bar'",
+		},
+		{
+			name:             "negative offset",
+			kind:             "partition_offsets",
+			partitionOffsets: map[string]int64{"foo:0": -1},
+			errContains:      "start offset of foo:0 must not be negative",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newStartOffset(test.kind, 0, test.timestamp, test.partitionOffsets, false)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestStartOffsetShouldApply(t *testing.T) {
+	s, err := newStartOffset("earliest", 0, "", nil, false)
+	require.NoError(t, err)
+
+	assert.True(t, s.shouldApply("foo", 0, false))
+	assert.False(t, s.shouldApply("foo", 0, true))
+
+	s, err = newStartOffset("earliest", 0, "", nil, true)
+	require.NoError(t, err)
+
+	// Forced only the first time that each partition is assigned.
+	assert.True(t, s.shouldApply("foo", 0, true))
+	assert.False(t, s.shouldApply("foo", 0, true))
+	assert.True(t, s.shouldApply("foo", 1, true))
+	assert.True(t, s.shouldApply("foo", 0, false))
+}
+
+func TestFranzOffsetAt(t *testing.T) {
+	assert.Equal(t, int64(10), franzOffsetAt(kgo.NewOffset().At(10)))
+	assert.Equal(t, int64(0), franzOffsetAt(kgo.NewOffset().At(0)))
+	assert.Less(t, franzOffsetAt(kgo.NewOffset().AtStart()), int64(0))
+	assert.Less(t, franzOffsetAt(kgo.NewOffset().AtEnd()), int64(0))
+	assert.Less(t, franzOffsetAt(kgo.NewOffset().AtEnd().Relative(-100)), int64(0))
+}
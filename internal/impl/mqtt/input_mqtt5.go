@@ -0,0 +1,301 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func mqtt5InputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Services").
+		Version("4.5.0").
+		Summary("Subscribe to topics on MQTT brokers using version 5 of the protocol.").
+		Description(`
+Topics can be consumed as part of a [shared subscription](https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901250) by prefixing them with ` + "`$share/<group>/`" + `, in which case messages of the topic are distributed between the consumers of each group.
+
+Messages received with a QoS of 1 or 2 are only acknowledged to the broker once they have been successfully processed. Acknowledgements are sent in the order that messages were received, as required by the protocol, and therefore a message awaiting delivery holds back the acknowledgements of messages received after it.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- mqtt_topic
+- mqtt_qos
+- mqtt_retained
+- mqtt_response_topic
+- mqtt_correlation_data
+- mqtt_content_type
+- mqtt_message_expiry
+` + "```" + `
+
+The response topic, correlation data, content type and message expiry (in seconds) are only added when present in a message. User properties of messages are also added as metadata fields, and when a key appears more than once the first value is used.
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(mqtt5URLsField()).
+		Field(service.NewStringListField("topics").
+			Description("A list of topics to consume from.").
+			Example([]string{"foo/bar", "foo/+/baz"}).
+			Example([]string{"$share/benthos/foo/#"})).
+		Field(mqtt5QoSField("The maximum level of delivery guarantee to subscribe with."))
+
+	for _, f := range mqtt5ConnectionFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewBoolField("clean_start").
+			Description("Whether to start a new session when connecting, discarding any existing session of the client ID.").
+			Default(true).
+			Advanced()).
+		Field(service.NewDurationField("session_expiry_interval").
+			Description("The amount of time for which the broker should keep the session after the connection closes, during which messages for subscriptions are queued. Set to zero in order to expire the session when the connection closes.").
+			Default("0s").
+			Example("1h").
+			Advanced())
+}
+
+func init() {
+	err := service.RegisterInput(
+		"mqtt5", mqtt5InputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			r, err := newMQTT5ReaderFromConfig(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(r), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mqtt5Reader struct {
+	connConf      mqtt5ConnConfig
+	topics        []string
+	qos           byte
+	cleanStart    bool
+	sessionExpiry uint32
+
+	log *service.Logger
+
+	connMut sync.Mutex
+	client  *paho.Client
+	msgChan chan *paho.Publish
+	// Closed when the current connection is lost.
+	connLost chan struct{}
+
+	shutSig *shutdown.Signaller
+}
+
+func newMQTT5ReaderFromConfig(conf *service.ParsedConfig, log *service.Logger) (*mqtt5Reader, error) {
+	m := &mqtt5Reader{
+		log:     log,
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if m.connConf, err = mqtt5ConnConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if m.topics, err = conf.FieldStringList("topics"); err != nil {
+		return nil, err
+	}
+	if len(m.topics) == 0 {
+		return nil, errors.New("at least one topic must be specified")
+	}
+	if m.qos, err = parseMQTT5QoS(conf, "qos"); err != nil {
+		return nil, err
+	}
+	if m.cleanStart, err = conf.FieldBool("clean_start"); err != nil {
+		return nil, err
+	}
+
+	sessionExpiry, err := conf.FieldDuration("session_expiry_interval")
+	if err != nil {
+		return nil, err
+	}
+	m.sessionExpiry = uint32(sessionExpiry.Seconds())
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mqtt5Reader) Connect(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	conn, err := m.connConf.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	msgChan := make(chan *paho.Publish)
+	connLost := make(chan struct{})
+
+	var lostOnce sync.Once
+	onLost := func(reason string) {
+		lostOnce.Do(func() {
+			m.log.Errorf("Connection lost due to: %v", reason)
+			close(connLost)
+		})
+	}
+
+	// Inbound topic aliases are scoped to the connection.
+	aliases := map[uint16]string{}
+
+	client := paho.NewClient(paho.ClientConfig{
+		Conn:                       conn,
+		EnableManualAcknowledgment: true,
+		Router: paho.NewSingleHandlerRouter(func(p *paho.Publish) {
+			if p.Properties != nil && p.Properties.TopicAlias != nil {
+				if p.Topic != "" {
+					aliases[*p.Properties.TopicAlias] = p.Topic
+				} else {
+					p.Topic = aliases[*p.Properties.TopicAlias]
+				}
+			}
+			select {
+			case msgChan <- p:
+			case <-connLost:
+			case <-m.shutSig.CloseAtLeisureChan():
+			}
+		}),
+		OnClientError: func(err error) {
+			onLost(err.Error())
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			onLost(fmt.Sprintf("disconnected by broker with reason code %v", d.ReasonCode))
+		},
+	})
+
+	connectCtx, done := context.WithTimeout(ctx, m.connConf.connectTimeout)
+	defer done()
+
+	if _, err = client.Connect(connectCtx, m.connConf.connectPacket(m.cleanStart, m.sessionExpiry)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	subs := map[string]paho.SubscribeOptions{}
+	for _, topic := range m.topics {
+		subs[topic] = paho.SubscribeOptions{QoS: m.qos}
+	}
+
+	suback, err := client.Subscribe(connectCtx, &paho.Subscribe{Subscriptions: subs})
+	if err == nil {
+		for _, reason := range suback.Reasons {
+			if reason >= 0x80 {
+				err = fmt.Errorf("subscription rejected with reason code %v", reason)
+				break
+			}
+		}
+	}
+	if err != nil {
+		_ = client.Disconnect(&paho.Disconnect{})
+		return fmt.Errorf("failed to subscribe to topics '%v': %w", m.topics, err)
+	}
+
+	m.log.Infof("Receiving MQTT 5 messages from topics: %v", m.topics)
+
+	m.client = client
+	m.msgChan = msgChan
+	m.connLost = connLost
+	return nil
+}
+
+func (m *mqtt5Reader) disconnect() {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		_ = m.client.Disconnect(&paho.Disconnect{})
+		m.client = nil
+	}
+	m.msgChan = nil
+	m.connLost = nil
+}
+
+func (m *mqtt5Reader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m.connMut.Lock()
+	client, msgChan, connLost := m.client, m.msgChan, m.connLost
+	m.connMut.Unlock()
+
+	if msgChan == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case p := <-msgChan:
+		return newMessageFromMQTT5Publish(p), func(ctx context.Context, res error) error {
+			// Nacks are handled by AutoRetryNacks, and therefore a message is
+			// only ever acknowledged once it has been delivered.
+			if res == nil {
+				return client.Ack(p)
+			}
+			return nil
+		}, nil
+	case <-connLost:
+		m.disconnect()
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (m *mqtt5Reader) Close(ctx context.Context) error {
+	m.shutSig.CloseAtLeisure()
+	go func() {
+		m.disconnect()
+		m.shutSig.ShutdownComplete()
+	}()
+	select {
+	case <-m.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func newMessageFromMQTT5Publish(p *paho.Publish) *service.Message {
+	msg := service.NewMessage(p.Payload)
+	msg.MetaSet("mqtt_topic", p.Topic)
+	msg.MetaSet("mqtt_qos", strconv.Itoa(int(p.QoS)))
+	msg.MetaSet("mqtt_retained", strconv.FormatBool(p.Retain))
+
+	if props := p.Properties; props != nil {
+		// Walk in reverse so that the first value of repeated keys is kept.
+		for i := len(props.User) - 1; i >= 0; i-- {
+			msg.MetaSet(props.User[i].Key, props.User[i].Value)
+		}
+		if props.ResponseTopic != "" {
+			msg.MetaSet("mqtt_response_topic", props.ResponseTopic)
+		}
+		if len(props.CorrelationData) > 0 {
+			msg.MetaSet("mqtt_correlation_data", string(props.CorrelationData))
+		}
+		if props.ContentType != "" {
+			msg.MetaSet("mqtt_content_type", props.ContentType)
+		}
+		if props.MessageExpiry != nil {
+			msg.MetaSet("mqtt_message_expiry", strconv.FormatUint(uint64(*props.MessageExpiry), 10))
+		}
+	}
+	return msg
+}
@@ -0,0 +1,179 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMQTT5Broker is a minimal MQTT 5 broker that accepts connections and
+// subscriptions, and records the acknowledgements of the messages it publishes
+// to subscribers.
+type fakeMQTT5Broker struct {
+	t        *testing.T
+	listener net.Listener
+
+	mut         sync.Mutex
+	subscribers []*fakeMQTT5Conn
+	nextID      uint16
+
+	pubacks chan uint16
+}
+
+type fakeMQTT5Conn struct {
+	mut  sync.Mutex
+	conn net.Conn
+}
+
+func startFakeMQTT5Broker(t *testing.T) *fakeMQTT5Broker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	b := &fakeMQTT5Broker{
+		t:        t,
+		listener: listener,
+		pubacks:  make(chan uint16, 10),
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(&fakeMQTT5Conn{conn: conn})
+		}
+	}()
+	return b
+}
+
+func (b *fakeMQTT5Broker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *fakeMQTT5Broker) serve(c *fakeMQTT5Conn) {
+	defer c.conn.Close()
+
+	for {
+		cp, err := packets.ReadPacket(c.conn)
+		if err != nil {
+			return
+		}
+
+		c.mut.Lock()
+		switch p := cp.Content.(type) {
+		case *packets.Connect:
+			_, err = (&packets.Connack{Properties: &packets.Properties{}}).WriteTo(c.conn)
+		case *packets.Subscribe:
+			suback := &packets.Suback{Properties: &packets.Properties{}, PacketID: p.PacketID}
+			for range p.Subscriptions {
+				suback.Reasons = append(suback.Reasons, 1)
+			}
+			b.mut.Lock()
+			b.subscribers = append(b.subscribers, c)
+			b.mut.Unlock()
+			_, err = suback.WriteTo(c.conn)
+		case *packets.Puback:
+			b.pubacks <- p.PacketID
+		case *packets.Pingreq:
+			_, err = (&packets.Pingresp{}).WriteTo(c.conn)
+		case *packets.Disconnect:
+			c.mut.Unlock()
+			return
+		}
+		c.mut.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// publish sends a message at QoS 1 to all subscribers and returns its packet
+// identifier.
+func (b *fakeMQTT5Broker) publish(topic, payload string) uint16 {
+	b.mut.Lock()
+	b.nextID++
+	id, subscribers := b.nextID, b.subscribers
+	b.mut.Unlock()
+
+	for _, c := range subscribers {
+		c.mut.Lock()
+		_, err := (&packets.Publish{
+			Topic:      topic,
+			Payload:    []byte(payload),
+			Properties: &packets.Properties{},
+			PacketID:   id,
+			QoS:        1,
+		}).WriteTo(c.conn)
+		c.mut.Unlock()
+		require.NoError(b.t, err)
+	}
+	return id
+}
+
+func (b *fakeMQTT5Broker) expectNoPuback(wait time.Duration) {
+	select {
+	case id := <-b.pubacks:
+		b.t.Errorf("unexpected acknowledgement of packet %v", id)
+	case <-time.After(wait):
+	}
+}
+
+func (b *fakeMQTT5Broker) expectPuback(id uint16) {
+	select {
+	case got := <-b.pubacks:
+		assert.Equal(b.t, id, got)
+	case <-time.After(time.Second * 5):
+		b.t.Errorf("timed out waiting for acknowledgement of packet %v", id)
+	}
+}
+
+func TestMQTT5InputAcknowledgements(t *testing.T) {
+	broker := startFakeMQTT5Broker(t)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	r := newMQTT5TestReader(ctx, t, `
+urls: [ `+broker.url()+` ]
+topics: [ foo ]
+client_id: test_reader
+`)
+
+	firstID := broker.publish("foo", "first")
+	secondID := broker.publish("foo", "second")
+
+	var ackFns []func(context.Context, error) error
+	for _, exp := range []string{"first", "second"} {
+		msg, ackFn, err := r.Read(ctx)
+		require.NoError(t, err)
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+
+		ackFns = append(ackFns, ackFn)
+	}
+
+	// Nothing is acknowledged until delivered.
+	broker.expectNoPuback(time.Millisecond * 200)
+
+	// Acknowledgements are sent in the order messages were received.
+	require.NoError(t, ackFns[1](ctx, nil))
+	broker.expectNoPuback(time.Millisecond * 200)
+
+	require.NoError(t, ackFns[0](ctx, nil))
+	broker.expectPuback(firstID)
+	broker.expectPuback(secondID)
+}
@@ -0,0 +1,196 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/integration"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func startMQTT5Broker(t *testing.T) string {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "eclipse-mosquitto",
+		Tag:        "2.0",
+		Cmd:        []string{"mosquitto", "-c", "/mosquitto-no-auth.conf"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	url := fmt.Sprintf("tcp://localhost:%v", resource.GetPort("1883/tcp"))
+
+	_ = resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		client := mqtt.NewClient(mqtt.NewClientOptions().SetClientID("UNIT_TEST").AddBroker(url))
+		tok := client.Connect()
+		tok.Wait()
+		if cErr := tok.Error(); cErr != nil {
+			return cErr
+		}
+		client.Disconnect(0)
+		return nil
+	}))
+	return url
+}
+
+func newMQTT5TestReader(ctx context.Context, t *testing.T, conf string) *mqtt5Reader {
+	t.Helper()
+
+	pConf, err := mqtt5InputConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	r, err := newMQTT5ReaderFromConfig(pConf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(context.Background()))
+	})
+	return r
+}
+
+func newMQTT5TestWriter(ctx context.Context, t *testing.T, conf string) *mqtt5Writer {
+	t.Helper()
+
+	pConf, err := mqtt5OutputConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newMQTT5WriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, w.Close(context.Background()))
+	})
+	return w
+}
+
+func TestIntegrationMQTT5(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	url := startMQTT5Broker(t)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	r := newMQTT5TestReader(ctx, t, `
+urls: [ `+url+` ]
+topics: [ devices/+ ]
+client_id: test_reader
+topic_alias_maximum: 10
+`)
+
+	w := newMQTT5TestWriter(ctx, t, `
+urls: [ `+url+` ]
+topic: devices/${! meta("device") }
+client_id: test_writer
+user_properties:
+  include_prefixes: [ device ]
+response_topic: replies/${! meta("device") }
+correlation_data: req-${! content() }
+content_type: text/plain
+message_expiry: 60s
+topic_alias_maximum: 10
+`)
+
+	for _, payload := range []string{"first", "second", "third"} {
+		msg := service.NewMessage([]byte(payload))
+		msg.MetaSet("device", "a")
+		require.NoError(t, w.Write(ctx, msg))
+	}
+
+	for _, payload := range []string{"first", "second", "third"} {
+		msg, ackFn, err := r.Read(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(b))
+
+		for k, exp := range map[string]string{
+			"mqtt_topic":            "devices/a",
+			"mqtt_qos":              "1",
+			"mqtt_retained":         "false",
+			"device":                "a",
+			"mqtt_response_topic":   "replies/a",
+			"mqtt_correlation_data": "req-" + payload,
+			"mqtt_content_type":     "text/plain",
+		} {
+			v, _ := msg.MetaGet(k)
+			assert.Equal(t, exp, v, k)
+		}
+
+		expiry, exists := msg.MetaGet("mqtt_message_expiry")
+		assert.True(t, exists)
+		assert.NotEmpty(t, expiry)
+	}
+}
+
+func TestIntegrationMQTT5SharedSubscription(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	url := startMQTT5Broker(t)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	var readers []*mqtt5Reader
+	for _, id := range []string{"a", "b"} {
+		readers = append(readers, newMQTT5TestReader(ctx, t, `
+urls: [ `+url+` ]
+topics: [ $share/group/shared ]
+client_id: test_reader_`+id+`
+`))
+	}
+
+	w := newMQTT5TestWriter(ctx, t, `
+urls: [ `+url+` ]
+topic: shared
+client_id: test_writer
+`)
+
+	var expected []string
+	for _, payload := range []string{"a", "b", "c", "d", "e", "f"} {
+		require.NoError(t, w.Write(ctx, service.NewMessage([]byte(payload))))
+		expected = append(expected, payload)
+	}
+
+	var received []string
+	for len(received) < len(expected) {
+		for _, r := range readers {
+			readCtx, readDone := context.WithTimeout(ctx, time.Millisecond*100)
+			msg, _, err := r.Read(readCtx)
+			readDone()
+			if err != nil {
+				require.Equal(t, context.DeadlineExceeded, err)
+				continue
+			}
+			b, err := msg.AsBytes()
+			require.NoError(t, err)
+			received = append(received, string(b))
+		}
+		require.NoError(t, ctx.Err())
+	}
+
+	sort.Strings(received)
+	assert.Equal(t, expected, received)
+}
@@ -0,0 +1,346 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func mqtt5URLsField() *service.ConfigField {
+	return service.NewStringListField("urls").
+		Description("A list of URLs to connect to. The first URL to successfully establish a connection will be used. If an item of the list contains commas it will be expanded into multiple URLs.").
+		Example([]string{"tcp://localhost:1883"}).
+		Example([]string{"ssl://localhost:8883"})
+}
+
+func mqtt5ConnectionFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField("client_id").
+			Description("An identifier for the client connection. When empty the broker assigns an identifier.").
+			Default(""),
+		service.NewStringAnnotatedEnumField("dynamic_client_id_suffix", map[string]string{
+			"nanoid": "append a nanoid of length 21 characters",
+		}).
+			Description("Append a dynamically generated suffix to the specified `client_id` on each run of the pipeline. This can be useful when clustering Benthos producers.").
+			Optional().
+			Advanced(),
+		service.NewDurationField("connect_timeout").
+			Description("The maximum amount of time to wait in order to establish a connection before the attempt is abandoned.").
+			Default("30s").
+			Example("1s").
+			Example("500ms"),
+		service.NewIntField("keepalive").
+			Description("Max seconds of inactivity before a keepalive message is sent.").
+			Default(30).
+			Advanced(),
+		service.NewIntField("topic_alias_maximum").
+			Description("The maximum number of topic aliases to use for the connection, which reduces the size of messages by replacing topics with an integer once they have been sent. Set to zero in order to disable topic aliases.").
+			Default(0).
+			Advanced(),
+		service.NewStringField("user").
+			Description("A username to connect with.").
+			Default("").
+			Advanced(),
+		service.NewStringField("password").
+			Description("A password to connect with.").
+			Default("").
+			Advanced(),
+		service.NewObjectField("will",
+			service.NewBoolField("enabled").
+				Description("Whether to enable last will messages.").
+				Default(false),
+			service.NewIntField("qos").
+				Description("Set QoS for last will message.").
+				Default(0),
+			service.NewBoolField("retained").
+				Description("Set retained for last will message.").
+				Default(false),
+			service.NewStringField("topic").
+				Description("Set topic for last will message.").
+				Default(""),
+			service.NewStringField("payload").
+				Description("Set payload for last will message.").
+				Default(""),
+		).
+			Description("Set last will message in case of Benthos failure").
+			Advanced(),
+		service.NewTLSToggledField("tls"),
+	}
+}
+
+func mqtt5QoSField(description string) *service.ConfigField {
+	return service.NewIntField("qos").
+		Description(description).
+		Default(1)
+}
+
+func parseMQTT5QoS(conf *service.ParsedConfig, path ...string) (byte, error) {
+	qos, err := conf.FieldInt(path...)
+	if err != nil {
+		return 0, err
+	}
+	if qos < 0 || qos > 2 {
+		return 0, fmt.Errorf("qos must be 0, 1 or 2, got %v", qos)
+	}
+	return byte(qos), nil
+}
+
+//------------------------------------------------------------------------------
+
+type mqtt5ConnConfig struct {
+	urls              []string
+	clientID          string
+	connectTimeout    time.Duration
+	keepAlive         uint16
+	topicAliasMaximum uint16
+	user              string
+	password          string
+	will              *paho.WillMessage
+	tlsConf           *tls.Config
+}
+
+func mqtt5ConnConfigFromParsed(conf *service.ParsedConfig) (c mqtt5ConnConfig, err error) {
+	var urls []string
+	if urls, err = conf.FieldStringList("urls"); err != nil {
+		return
+	}
+	for _, u := range urls {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				c.urls = append(c.urls, splitURL)
+			}
+		}
+	}
+	if len(c.urls) == 0 {
+		err = errors.New("at least one url must be specified")
+		return
+	}
+
+	if c.clientID, err = conf.FieldString("client_id"); err != nil {
+		return
+	}
+	if conf.Contains("dynamic_client_id_suffix") {
+		var suffix string
+		if suffix, err = conf.FieldString("dynamic_client_id_suffix"); err != nil {
+			return
+		}
+		switch suffix {
+		case "nanoid":
+			var nid string
+			if nid, err = gonanoid.New(); err != nil {
+				err = fmt.Errorf("failed to generate nanoid: %w", err)
+				return
+			}
+			c.clientID += nid
+		default:
+			err = fmt.Errorf("unknown dynamic_client_id_suffix: %v", suffix)
+			return
+		}
+	}
+
+	if c.connectTimeout, err = conf.FieldDuration("connect_timeout"); err != nil {
+		return
+	}
+
+	var keepAlive int
+	if keepAlive, err = conf.FieldInt("keepalive"); err != nil {
+		return
+	}
+	if keepAlive < 0 || keepAlive > 65535 {
+		err = fmt.Errorf("keepalive must be between 0 and 65535, got %v", keepAlive)
+		return
+	}
+	c.keepAlive = uint16(keepAlive)
+
+	var aliasMax int
+	if aliasMax, err = conf.FieldInt("topic_alias_maximum"); err != nil {
+		return
+	}
+	if aliasMax < 0 || aliasMax > 65535 {
+		err = fmt.Errorf("topic_alias_maximum must be between 0 and 65535, got %v", aliasMax)
+		return
+	}
+	c.topicAliasMaximum = uint16(aliasMax)
+
+	if c.user, err = conf.FieldString("user"); err != nil {
+		return
+	}
+	if c.password, err = conf.FieldString("password"); err != nil {
+		return
+	}
+
+	var willEnabled bool
+	if willEnabled, err = conf.FieldBool("will", "enabled"); err != nil {
+		return
+	}
+	if willEnabled {
+		c.will = &paho.WillMessage{}
+		if c.will.QoS, err = parseMQTT5QoS(conf, "will", "qos"); err != nil {
+			return
+		}
+		if c.will.Retain, err = conf.FieldBool("will", "retained"); err != nil {
+			return
+		}
+		if c.will.Topic, err = conf.FieldString("will", "topic"); err != nil {
+			return
+		}
+		if c.will.Topic == "" {
+			err = errors.New("include topic to register a last will")
+			return
+		}
+		var payload string
+		if payload, err = conf.FieldString("will", "payload"); err != nil {
+			return
+		}
+		c.will.Payload = []byte(payload)
+	}
+
+	var tlsEnabled bool
+	if c.tlsConf, tlsEnabled, err = conf.FieldTLSToggled("tls"); err != nil {
+		return
+	}
+	if !tlsEnabled {
+		c.tlsConf = nil
+	}
+	return
+}
+
+// dial attempts to open a network connection to each URL in turn, returning
+// the first that succeeds.
+func (c *mqtt5ConnConfig) dial(ctx context.Context) (net.Conn, error) {
+	ctx, done := context.WithTimeout(ctx, c.connectTimeout)
+	defer done()
+
+	var errs []string
+	for _, u := range c.urls {
+		conn, err := c.dialURL(ctx, u)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, fmt.Sprintf("%v: %v", u, err))
+	}
+	return nil, fmt.Errorf("failed to connect to brokers: %v", strings.Join(errs, ", "))
+}
+
+func (c *mqtt5ConnConfig) dialURL(ctx context.Context, rawURL string) (net.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		if c.tlsConf == nil {
+			return dialer.DialContext(ctx, "tcp", u.Host)
+		}
+	case "ssl", "tls", "mqtts", "tcps":
+	default:
+		return nil, fmt.Errorf("url scheme %v is not supported", u.Scheme)
+	}
+
+	tlsConf := c.tlsConf
+	if tlsConf == nil {
+		tlsConf = &tls.Config{}
+	}
+	tlsDialer := tls.Dialer{NetDialer: &dialer, Config: tlsConf}
+	return tlsDialer.DialContext(ctx, "tcp", u.Host)
+}
+
+// connectPacket returns the packet used to open a session, where the session
+// options are only relevant to consumers and are therefore provided by them.
+func (c *mqtt5ConnConfig) connectPacket(cleanStart bool, sessionExpiry uint32) *paho.Connect {
+	cp := &paho.Connect{
+		ClientID:    c.clientID,
+		KeepAlive:   c.keepAlive,
+		CleanStart:  cleanStart,
+		WillMessage: c.will,
+		Properties:  &paho.ConnectProperties{},
+	}
+	if sessionExpiry > 0 {
+		cp.Properties.SessionExpiryInterval = &sessionExpiry
+	}
+	if c.topicAliasMaximum > 0 {
+		aliasMax := c.topicAliasMaximum
+		cp.Properties.TopicAliasMaximum = &aliasMax
+	}
+	if c.user != "" {
+		cp.Username = c.user
+		cp.UsernameFlag = true
+	}
+	if c.password != "" {
+		cp.Password = []byte(c.password)
+		cp.PasswordFlag = true
+	}
+	return cp
+}
+
+//------------------------------------------------------------------------------
+
+type mqtt5TopicAlias struct {
+	alias uint16
+	// Set once a message carrying both the topic and its alias has been sent,
+	// after which the topic can be omitted.
+	established bool
+}
+
+// mqtt5TopicAliases assigns topic aliases to outbound messages, up to a
+// maximum negotiated with the broker. Aliases are only valid for the lifetime
+// of a connection.
+type mqtt5TopicAliases struct {
+	mut     sync.Mutex
+	max     uint16
+	aliases map[string]*mqtt5TopicAlias
+}
+
+func newMQTT5TopicAliases(max uint16) *mqtt5TopicAliases {
+	return &mqtt5TopicAliases{
+		max:     max,
+		aliases: map[string]*mqtt5TopicAlias{},
+	}
+}
+
+// get returns the alias of a topic and whether the topic must still be sent
+// along with it. An alias of zero indicates that no alias is available.
+func (t *mqtt5TopicAliases) get(topic string) (alias uint16, sendTopic bool) {
+	if t == nil || t.max == 0 {
+		return 0, true
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	a, exists := t.aliases[topic]
+	if !exists {
+		if len(t.aliases) >= int(t.max) {
+			return 0, true
+		}
+		a = &mqtt5TopicAlias{alias: uint16(len(t.aliases) + 1)}
+		t.aliases[topic] = a
+	}
+	return a.alias, !a.established
+}
+
+// establish marks the alias of a topic as known by the broker.
+func (t *mqtt5TopicAliases) establish(topic string) {
+	if t == nil {
+		return
+	}
+
+	t.mut.Lock()
+	if a, exists := t.aliases[topic]; exists {
+		a.established = true
+	}
+	t.mut.Unlock()
+}
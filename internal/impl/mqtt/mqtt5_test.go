@@ -0,0 +1,125 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestMQTT5TopicAliases(t *testing.T) {
+	aliases := newMQTT5TopicAliases(2)
+
+	alias, sendTopic := aliases.get("foo")
+	assert.Equal(t, uint16(1), alias)
+	assert.True(t, sendTopic)
+
+	// Until established the topic must still be sent.
+	alias, sendTopic = aliases.get("foo")
+	assert.Equal(t, uint16(1), alias)
+	assert.True(t, sendTopic)
+
+	aliases.establish("foo")
+	alias, sendTopic = aliases.get("foo")
+	assert.Equal(t, uint16(1), alias)
+	assert.False(t, sendTopic)
+
+	alias, sendTopic = aliases.get("bar")
+	assert.Equal(t, uint16(2), alias)
+	assert.True(t, sendTopic)
+
+	// The maximum has been reached.
+	alias, sendTopic = aliases.get("baz")
+	assert.Equal(t, uint16(0), alias)
+	assert.True(t, sendTopic)
+
+	alias, sendTopic = newMQTT5TopicAliases(0).get("foo")
+	assert.Equal(t, uint16(0), alias)
+	assert.True(t, sendTopic)
+}
+
+func TestMQTT5ConfigParse(t *testing.T) {
+	env := service.NewEnvironment()
+
+	inConf, err := mqtt5InputConfig().ParseYAML(`
+urls: [ tcp://foo:1883,tcp://bar:1883 ]
+topics: [ $share/group/foo ]
+client_id: baz
+qos: 2
+clean_start: false
+session_expiry_interval: 1h
+will:
+  enabled: true
+  topic: lwt
+  payload: gone
+`, env)
+	require.NoError(t, err)
+
+	r, err := newMQTT5ReaderFromConfig(inConf, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tcp://foo:1883", "tcp://bar:1883"}, r.connConf.urls)
+	assert.Equal(t, []string{"$share/group/foo"}, r.topics)
+	assert.Equal(t, byte(2), r.qos)
+	assert.False(t, r.cleanStart)
+	assert.Equal(t, uint32(3600), r.sessionExpiry)
+
+	cp := r.connConf.connectPacket(r.cleanStart, r.sessionExpiry)
+	assert.Equal(t, "baz", cp.ClientID)
+	assert.Equal(t, uint16(30), cp.KeepAlive)
+	assert.False(t, cp.CleanStart)
+	require.NotNil(t, cp.Properties.SessionExpiryInterval)
+	assert.Equal(t, uint32(3600), *cp.Properties.SessionExpiryInterval)
+	assert.Nil(t, cp.Properties.TopicAliasMaximum)
+	require.NotNil(t, cp.WillMessage)
+	assert.Equal(t, "lwt", cp.WillMessage.Topic)
+	assert.Equal(t, "gone", string(cp.WillMessage.Payload))
+
+	inConf, err = mqtt5InputConfig().ParseYAML(`
+urls: [ tcp://foo:1883 ]
+topics: [ foo ]
+qos: 3
+`, env)
+	require.NoError(t, err)
+
+	_, err = newMQTT5ReaderFromConfig(inConf, nil)
+	require.Error(t, err)
+
+	outConf, err := mqtt5OutputConfig().ParseYAML(`
+urls: [ tcp://foo:1883 ]
+topic: foo/${! meta("id") }
+user_properties:
+  include_prefixes: [ prop_ ]
+response_topic: replies
+correlation_data: ${! meta("id") }
+message_expiry: 90s
+topic_alias_maximum: 10
+`, env)
+	require.NoError(t, err)
+
+	w, err := newMQTT5WriterFromConfig(outConf, nil)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte("hello"))
+	msg.MetaSet("id", "bar")
+	msg.MetaSet("prop_a", "a")
+	msg.MetaSet("other", "b")
+
+	p, err := w.publishFromMessage(msg, w.topic.String(msg), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo/bar", p.Topic)
+	assert.Equal(t, byte(1), p.QoS)
+	assert.Equal(t, "hello", string(p.Payload))
+	assert.Equal(t, "replies", p.Properties.ResponseTopic)
+	assert.Equal(t, "bar", string(p.Properties.CorrelationData))
+	require.NotNil(t, p.Properties.MessageExpiry)
+	assert.Equal(t, uint32(90), *p.Properties.MessageExpiry)
+	assert.Nil(t, p.Properties.TopicAlias)
+	require.Len(t, p.Properties.User, 1)
+	assert.Equal(t, "prop_a", p.Properties.User[0].Key)
+	assert.Equal(t, "a", p.Properties.User[0].Value)
+	assert.Equal(t, uint16(10), w.connConf.topicAliasMaximum)
+}
@@ -0,0 +1,332 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func mqtt5OutputConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Services").
+		Version("4.5.0").
+		Summary("Pushes messages to an MQTT broker using version 5 of the protocol.").
+		Description(`
+Metadata values can be sent as user properties of messages with the ` + "`user_properties`" + ` field, and the ` + "`response_topic` and `correlation_data`" + ` fields can be used in order to implement request/reply patterns with consumers that support them.`).
+		Field(mqtt5URLsField()).
+		Field(service.NewInterpolatedStringField("topic").
+			Description("The topic to publish messages to.").
+			Example("foo/bar").
+			Example(`foo/${! meta("kafka_key") }`)).
+		Field(mqtt5QoSField("The QoS value to set for each message.")).
+		Field(service.NewBoolField("retained").
+			Description("Set message as retained on the topic.").
+			Default(false)).
+		Field(service.NewMetadataFilterField("user_properties").
+			Description("Determine which (if any) metadata values should be added to messages as user properties.").
+			Optional()).
+		Field(service.NewInterpolatedStringField("response_topic").
+			Description("An optional topic that consumers of messages should send responses to.").
+			Example(`replies/${! meta("client") }`).
+			Optional().
+			Advanced()).
+		Field(service.NewInterpolatedStringField("correlation_data").
+			Description("Optional data used by the sender of a request to identify which request a response is for.").
+			Example(`${! uuid_v4() }`).
+			Optional().
+			Advanced()).
+		Field(service.NewInterpolatedStringField("content_type").
+			Description("An optional content type describing the payload of messages.").
+			Example("application/json").
+			Optional().
+			Advanced()).
+		Field(service.NewDurationField("message_expiry").
+			Description("An optional lifetime of messages, after which the broker discards messages that have not yet been delivered to consumers. The lifetime is sent with a precision of seconds.").
+			Example("60s").
+			Optional().
+			Advanced()).
+		Field(service.NewDurationField("write_timeout").
+			Description("The maximum amount of time to wait to write data before the attempt is abandoned.").
+			Default("3s").
+			Example("1s").
+			Example("500ms"))
+
+	for _, f := range mqtt5ConnectionFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(64))
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"mqtt5", mqtt5OutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
+			maxInFlight, err := conf.FieldInt("max_in_flight")
+			if err != nil {
+				return nil, 0, err
+			}
+			w, err := newMQTT5WriterFromConfig(conf, mgr.Logger())
+			return w, maxInFlight, err
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mqtt5Writer struct {
+	connConf        mqtt5ConnConfig
+	topicRaw        string
+	topic           *service.InterpolatedString
+	qos             byte
+	retained        bool
+	userProps       *service.MetadataFilter
+	responseTopic   *service.InterpolatedString
+	correlationData *service.InterpolatedString
+	contentType     *service.InterpolatedString
+	messageExpiry   *uint32
+	writeTimeout    time.Duration
+
+	log *service.Logger
+
+	connMut  sync.Mutex
+	client   *paho.Client
+	aliases  *mqtt5TopicAliases
+	connLost chan struct{}
+
+	shutSig *shutdown.Signaller
+}
+
+func newMQTT5WriterFromConfig(conf *service.ParsedConfig, log *service.Logger) (*mqtt5Writer, error) {
+	m := &mqtt5Writer{
+		log:     log,
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if m.connConf, err = mqtt5ConnConfigFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if m.topicRaw, err = conf.FieldString("topic"); err != nil {
+		return nil, err
+	}
+	if m.topic, err = conf.FieldInterpolatedString("topic"); err != nil {
+		return nil, err
+	}
+	if m.qos, err = parseMQTT5QoS(conf, "qos"); err != nil {
+		return nil, err
+	}
+	if m.retained, err = conf.FieldBool("retained"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("user_properties") {
+		if m.userProps, err = conf.FieldMetadataFilter("user_properties"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("response_topic") {
+		if m.responseTopic, err = conf.FieldInterpolatedString("response_topic"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("correlation_data") {
+		if m.correlationData, err = conf.FieldInterpolatedString("correlation_data"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("content_type") {
+		if m.contentType, err = conf.FieldInterpolatedString("content_type"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("message_expiry") {
+		expiry, err := conf.FieldDuration("message_expiry")
+		if err != nil {
+			return nil, err
+		}
+		expirySecs := uint32(expiry.Seconds())
+		m.messageExpiry = &expirySecs
+	}
+	if m.writeTimeout, err = conf.FieldDuration("write_timeout"); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mqtt5Writer) Connect(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	conn, err := m.connConf.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	connLost := make(chan struct{})
+
+	var lostOnce sync.Once
+	onLost := func(reason string) {
+		lostOnce.Do(func() {
+			m.log.Errorf("Connection lost due to: %v", reason)
+			close(connLost)
+		})
+	}
+
+	client := paho.NewClient(paho.ClientConfig{
+		Conn: conn,
+		OnClientError: func(err error) {
+			onLost(err.Error())
+		},
+		OnServerDisconnect: func(d *paho.Disconnect) {
+			onLost(fmt.Sprintf("disconnected by broker with reason code %v", d.ReasonCode))
+		},
+	})
+
+	connectCtx, done := context.WithTimeout(ctx, m.connConf.connectTimeout)
+	defer done()
+
+	connack, err := client.Connect(connectCtx, m.connConf.connectPacket(true, 0))
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	// We can only use as many aliases as the broker allows.
+	var aliasMax uint16
+	if connack.Properties != nil && connack.Properties.TopicAliasMaximum != nil {
+		aliasMax = *connack.Properties.TopicAliasMaximum
+	}
+	if aliasMax > m.connConf.topicAliasMaximum {
+		aliasMax = m.connConf.topicAliasMaximum
+	}
+
+	m.log.Infof("Writing MQTT 5 messages to topic: %v", m.topicRaw)
+
+	m.client = client
+	m.aliases = newMQTT5TopicAliases(aliasMax)
+	m.connLost = connLost
+	return nil
+}
+
+func (m *mqtt5Writer) disconnect() {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		_ = m.client.Disconnect(&paho.Disconnect{})
+		m.client = nil
+	}
+	m.aliases = nil
+	m.connLost = nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *mqtt5Writer) publishFromMessage(msg *service.Message, topic string, aliases *mqtt5TopicAliases) (*paho.Publish, error) {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &paho.Publish{
+		QoS:        m.qos,
+		Retain:     m.retained,
+		Topic:      topic,
+		Payload:    payload,
+		Properties: &paho.PublishProperties{},
+	}
+
+	_ = m.userProps.Walk(msg, func(key, value string) error {
+		p.Properties.User = append(p.Properties.User, paho.UserProperty{Key: key, Value: value})
+		return nil
+	})
+	if m.responseTopic != nil {
+		p.Properties.ResponseTopic = m.responseTopic.String(msg)
+	}
+	if m.correlationData != nil {
+		p.Properties.CorrelationData = m.correlationData.Bytes(msg)
+	}
+	if m.contentType != nil {
+		p.Properties.ContentType = m.contentType.String(msg)
+	}
+	if m.messageExpiry != nil {
+		expiry := *m.messageExpiry
+		p.Properties.MessageExpiry = &expiry
+	}
+
+	if alias, sendTopic := aliases.get(topic); alias > 0 {
+		p.Properties.TopicAlias = &alias
+		if !sendTopic {
+			p.Topic = ""
+		}
+	}
+	return p, nil
+}
+
+func (m *mqtt5Writer) Write(ctx context.Context, msg *service.Message) error {
+	m.connMut.Lock()
+	client, aliases, connLost := m.client, m.aliases, m.connLost
+	m.connMut.Unlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	select {
+	case <-connLost:
+		m.disconnect()
+		return service.ErrNotConnected
+	default:
+	}
+
+	topic := m.topic.String(msg)
+	p, err := m.publishFromMessage(msg, topic, aliases)
+	if err != nil {
+		return err
+	}
+
+	ctx, done := context.WithTimeout(ctx, m.writeTimeout)
+	defer done()
+
+	res, err := client.Publish(ctx, p)
+	if err != nil {
+		return err
+	}
+	if res != nil && res.ReasonCode >= 0x80 {
+		return fmt.Errorf("message rejected with reason code %v", res.ReasonCode)
+	}
+
+	aliases.establish(topic)
+	return nil
+}
+
+func (m *mqtt5Writer) Close(ctx context.Context) error {
+	go func() {
+		m.disconnect()
+		m.shutSig.ShutdownComplete()
+	}()
+	select {
+	case <-m.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func startEmbeddedJetStream(t testing.TB) string {
+	t.Helper()
+
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		t.Fatal("embedded NATS server failed to start")
+	}
+	t.Cleanup(s.Shutdown)
+	return s.ClientURL()
+}
+
+func TestCacheKVConfigParse(t *testing.T) {
+	spec := natsKVCacheConfig()
+	env := service.NewEnvironment()
+
+	conf, err := spec.ParseYAML(`
+urls: [ url1, url2 ]
+bucket: foo
+create_bucket: true
+ttl: 1h
+history: 5
+auth:
+  nkey_file: test auth n key file
+`, env)
+	require.NoError(t, err)
+
+	c, err := newKVCacheFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "url1,url2", c.bucket.urls)
+	assert.Equal(t, "foo", c.bucket.bucket)
+	assert.Equal(t, "test auth n key file", c.bucket.authConf.NKeyFile)
+	require.NotNil(t, c.bucket.createConf)
+	assert.Equal(t, "foo", c.bucket.createConf.Bucket)
+	assert.Equal(t, time.Hour, c.bucket.createConf.TTL)
+	assert.Equal(t, uint8(5), c.bucket.createConf.History)
+
+	conf, err = spec.ParseYAML(`
+urls: [ url1 ]
+bucket: foo
+`, env)
+	require.NoError(t, err)
+
+	c, err = newKVCacheFromConfig(conf, nil)
+	require.NoError(t, err)
+	assert.Nil(t, c.bucket.createConf)
+
+	conf, err = spec.ParseYAML(`
+urls: [ url1 ]
+bucket: foo
+create_bucket: true
+history: 65
+`, env)
+	require.NoError(t, err)
+
+	_, err = newKVCacheFromConfig(conf, nil)
+	require.Error(t, err)
+}
+
+func TestCacheKVEmbedded(t *testing.T) {
+	url := startEmbeddedJetStream(t)
+
+	conf, err := natsKVCacheConfig().ParseYAML(`
+urls: [ `+url+` ]
+bucket: cache_test
+create_bucket: true
+history: 3
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newKVCacheFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	t.Cleanup(func() {
+		require.NoError(t, c.Close(context.Background()))
+	})
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("first"), nil))
+
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(v))
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("second"), nil))
+	require.NoError(t, c.Add(ctx, "bar", []byte("second"), nil))
+
+	v, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(v))
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	// Adding a key that was deleted is permitted.
+	require.NoError(t, c.Add(ctx, "foo", []byte("third"), nil))
+
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "third", string(v))
+}
+
+func TestCacheKVMissingBucket(t *testing.T) {
+	url := startEmbeddedJetStream(t)
+
+	conf, err := natsKVCacheConfig().ParseYAML(`
+urls: [ `+url+` ]
+bucket: does_not_exist
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newKVCacheFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	_, err = c.Get(context.Background(), "foo")
+	require.Error(t, err)
+	assert.NotEqual(t, service.ErrKeyNotFound, err)
+}
@@ -0,0 +1,168 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestInputKVConfigParse(t *testing.T) {
+	spec := natsKVInputConfig()
+	env := service.NewEnvironment()
+
+	conf, err := spec.ParseYAML(`
+urls: [ url1, url2 ]
+bucket: foo
+key: bar.*
+ignore_deletes: true
+updates_only: true
+`, env)
+	require.NoError(t, err)
+
+	r, err := newKVReaderFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "url1,url2", r.bucket.urls)
+	assert.Equal(t, "foo", r.bucket.bucket)
+	assert.Nil(t, r.bucket.createConf)
+	assert.Equal(t, "bar.*", r.key)
+	assert.False(t, r.includeHistory)
+	assert.True(t, r.ignoreDeletes)
+	assert.True(t, r.updatesOnly)
+}
+
+func setupEmbeddedKVBucket(t *testing.T) (string, nats.KeyValue) {
+	t.Helper()
+
+	url := startEmbeddedJetStream(t)
+
+	natsConn, err := nats.Connect(url)
+	require.NoError(t, err)
+	t.Cleanup(natsConn.Close)
+
+	jCtx, err := natsConn.JetStream()
+	require.NoError(t, err)
+
+	kv, err := jCtx.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:  "watch_test",
+		History: 5,
+	})
+	require.NoError(t, err)
+	return url, kv
+}
+
+func newEmbeddedKVReader(t *testing.T, url, extra string) *kvReader {
+	t.Helper()
+
+	conf, err := natsKVInputConfig().ParseYAML(`
+urls: [ `+url+` ]
+bucket: watch_test
+`+extra, nil)
+	require.NoError(t, err)
+
+	r, err := newKVReaderFromConfig(conf, nil)
+	require.NoError(t, err)
+	return r
+}
+
+func readKVMessage(ctx context.Context, t *testing.T, r *kvReader) (key, value, op string) {
+	t.Helper()
+
+	msg, ackFn, err := r.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+
+	key, _ = msg.MetaGet("nats_kv_key")
+	op, _ = msg.MetaGet("nats_kv_operation")
+	return key, string(b), op
+}
+
+func TestInputKVEmbedded(t *testing.T) {
+	url, kv := setupEmbeddedKVBucket(t)
+
+	_, err := kv.Put("foo.a", []byte("a1"))
+	require.NoError(t, err)
+	_, err = kv.Put("foo.a", []byte("a2"))
+	require.NoError(t, err)
+	_, err = kv.Put("bar.b", []byte("b1"))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	r := newEmbeddedKVReader(t, url, "key: foo.>\n")
+	require.NoError(t, r.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(context.Background()))
+	})
+
+	key, value, op := readKVMessage(ctx, t, r)
+	assert.Equal(t, "foo.a", key)
+	assert.Equal(t, "a2", value)
+	assert.Equal(t, "put", op)
+
+	_, err = kv.Put("bar.b", []byte("b2"))
+	require.NoError(t, err)
+	_, err = kv.Put("foo.c", []byte("c1"))
+	require.NoError(t, err)
+	require.NoError(t, kv.Delete("foo.a"))
+
+	key, value, op = readKVMessage(ctx, t, r)
+	assert.Equal(t, "foo.c", key)
+	assert.Equal(t, "c1", value)
+	assert.Equal(t, "put", op)
+
+	key, value, op = readKVMessage(ctx, t, r)
+	assert.Equal(t, "foo.a", key)
+	assert.Equal(t, "", value)
+	assert.Equal(t, "delete", op)
+}
+
+func TestInputKVEmbeddedHistoryAndUpdatesOnly(t *testing.T) {
+	url, kv := setupEmbeddedKVBucket(t)
+
+	_, err := kv.Put("foo", []byte("v1"))
+	require.NoError(t, err)
+	_, err = kv.Put("foo", []byte("v2"))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	historyReader := newEmbeddedKVReader(t, url, "include_history: true\n")
+	require.NoError(t, historyReader.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, historyReader.Close(context.Background()))
+	})
+
+	_, value, _ := readKVMessage(ctx, t, historyReader)
+	assert.Equal(t, "v1", value)
+	_, value, _ = readKVMessage(ctx, t, historyReader)
+	assert.Equal(t, "v2", value)
+
+	updatesReader := newEmbeddedKVReader(t, url, "updates_only: true\nignore_deletes: true\n")
+	require.NoError(t, updatesReader.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, updatesReader.Close(context.Background()))
+	})
+
+	// Wait for the watcher to deliver the existing values before updating.
+	time.Sleep(time.Millisecond * 500)
+
+	require.NoError(t, kv.Delete("foo"))
+	_, err = kv.Put("foo", []byte("v3"))
+	require.NoError(t, err)
+
+	_, value, op := readKVMessage(ctx, t, updatesReader)
+	assert.Equal(t, "v3", value)
+	assert.Equal(t, "put", op)
+}
@@ -0,0 +1,107 @@
+package nats
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestOutputObjectStoreConfigParse(t *testing.T) {
+	spec := natsObjectStoreOutputConfig()
+	env := service.NewEnvironment()
+
+	conf, err := spec.ParseYAML(`
+urls: [ url1, url2 ]
+bucket: foo
+object_name: ${! meta("id") }.json
+auth:
+  user_credentials_file: test auth user creds file
+`, env)
+	require.NoError(t, err)
+
+	o, err := newObjectStoreWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	msg := service.NewMessage(nil)
+	msg.MetaSet("id", "bar")
+	assert.Equal(t, "url1,url2", o.urls)
+	assert.Equal(t, "foo", o.bucket)
+	assert.Equal(t, "bar.json", o.objectName.String(msg))
+	assert.False(t, o.createBucket)
+	assert.Equal(t, "test auth user creds file", o.authConf.UserCredentialsFile)
+}
+
+func TestOutputObjectStoreEmbedded(t *testing.T) {
+	url := startEmbeddedJetStream(t)
+
+	conf, err := natsObjectStoreOutputConfig().ParseYAML(`
+urls: [ `+url+` ]
+bucket: objects_test
+object_name: ${! meta("id") }
+create_bucket: true
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newObjectStoreWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	require.NoError(t, o.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, o.Close(context.Background()))
+	})
+
+	// Larger than the default maximum payload of the server.
+	large := strings.Repeat("hello world ", 200000)
+
+	msg := service.NewMessage([]byte(large))
+	msg.MetaSet("id", "large")
+	require.NoError(t, o.Write(ctx, msg))
+
+	msg = service.NewMessage([]byte("small"))
+	msg.MetaSet("id", "small")
+	require.NoError(t, o.Write(ctx, msg))
+
+	natsConn, err := nats.Connect(url)
+	require.NoError(t, err)
+	t.Cleanup(natsConn.Close)
+
+	jCtx, err := natsConn.JetStream()
+	require.NoError(t, err)
+
+	obs, err := jCtx.ObjectStore("objects_test")
+	require.NoError(t, err)
+
+	b, err := obs.GetBytes("large")
+	require.NoError(t, err)
+	assert.Equal(t, large, string(b))
+
+	b, err = obs.GetBytes("small")
+	require.NoError(t, err)
+	assert.Equal(t, "small", string(b))
+}
+
+func TestOutputObjectStoreMissingBucket(t *testing.T) {
+	url := startEmbeddedJetStream(t)
+
+	conf, err := natsObjectStoreOutputConfig().ParseYAML(`
+urls: [ `+url+` ]
+bucket: does_not_exist
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newObjectStoreWriterFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	require.Error(t, o.Connect(context.Background()))
+	require.NoError(t, o.Close(context.Background()))
+}
@@ -0,0 +1,82 @@
+package otlp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	otlpFieldHTTP    = "http"
+	otlpFieldGRPC    = "grpc"
+	otlpFieldURL     = "url"
+	otlpFieldHeaders = "headers"
+	otlpFieldTLS     = "tls"
+)
+
+func collectorFields(defaultURL string) []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(otlpFieldURL).
+			Description("The address of a collector, in the form `host:port`. A URL containing a scheme and path may also be specified, in which case the scheme is ignored in favour of the `tls` settings.").
+			Default(defaultURL),
+		service.NewStringMapField(otlpFieldHeaders).
+			Description("A map of headers to add to each request sent to the collector.").
+			Default(map[string]interface{}{}).
+			Advanced(),
+		service.NewTLSToggledField(otlpFieldTLS),
+	}
+}
+
+type collector struct {
+	host       string
+	path       string
+	headers    map[string]string
+	tlsConf    *tls.Config
+	tlsEnabled bool
+}
+
+func collectorsFromParsed(conf *service.ParsedConfig, name string) ([]collector, error) {
+	list, err := conf.FieldObjectList(name)
+	if err != nil {
+		return nil, err
+	}
+
+	collectors := make([]collector, 0, len(list))
+	for _, pc := range list {
+		u, err := pc.FieldString(otlpFieldURL)
+		if err != nil {
+			return nil, err
+		}
+		c := collector{}
+		if c.host, c.path, err = splitCollectorURL(u); err != nil {
+			return nil, err
+		}
+		if c.headers, err = pc.FieldStringMap(otlpFieldHeaders); err != nil {
+			return nil, err
+		}
+		if c.tlsConf, c.tlsEnabled, err = pc.FieldTLSToggled(otlpFieldTLS); err != nil {
+			return nil, err
+		}
+		collectors = append(collectors, c)
+	}
+	return collectors, nil
+}
+
+// splitCollectorURL extracts the host and optional path from a collector
+// address, which may be either a plain host:port or a full URL.
+func splitCollectorURL(u string) (host, path string, err error) {
+	if !strings.Contains(u, "://") {
+		return u, "", nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse collector url '%v': %w", u, err)
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		path = parsed.Path
+	}
+	return parsed.Host, path, nil
+}
@@ -0,0 +1,326 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/asyncint64"
+	"go.opentelemetry.io/otel/metric/instrument/syncint64"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	otlpFieldPushInterval = "push_interval"
+)
+
+func otlpMetricsSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("4.5.0").
+		Summary("Pushes metrics to an [Open Telemetry collector](https://opentelemetry.io/docs/collector/) over OTLP.").
+		Description(`
+Metrics can be pushed to any number of collectors over both gRPC and HTTP, where each collector receives every metric. Counters are exported as monotonic sums, gauges as asynchronous gauges and timers as histograms of nanosecond values.
+
+Metric names and labels are subject to the ` + "[`mapping`](/docs/components/metrics/about#metric-mapping)" + ` of the metrics configuration in the same way as all other metrics types.`).
+		Field(service.NewObjectListField(otlpFieldHTTP, collectorFields("localhost:4318")...).
+			Description("A list of collectors to push metrics to over OTLP/HTTP.").
+			Default([]interface{}{})).
+		Field(service.NewObjectListField(otlpFieldGRPC, collectorFields("localhost:4317")...).
+			Description("A list of collectors to push metrics to over OTLP/gRPC.").
+			Default([]interface{}{})).
+		Field(service.NewDurationField(otlpFieldPushInterval).
+			Description("The period of time between each push of metrics to the collectors.").
+			Default("10s")).
+		Field(service.NewStringMapField(otlpFieldTags).
+			Description("A map of tags to add to the resource attributes of all metrics.").
+			Default(map[string]interface{}{}).
+			Advanced())
+}
+
+func init() {
+	err := service.RegisterMetricsExporter(
+		"open_telemetry", otlpMetricsSpec(),
+		func(conf *service.ParsedConfig, log *service.Logger) (service.MetricsExporter, error) {
+			c, err := otlpMetricsConfigFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return newOtlpMetrics(c, log)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type otlpMetricsConfig struct {
+	http         []collector
+	grpc         []collector
+	pushInterval time.Duration
+	tags         map[string]string
+}
+
+func otlpMetricsConfigFromParsed(conf *service.ParsedConfig) (c otlpMetricsConfig, err error) {
+	if c.http, err = collectorsFromParsed(conf, otlpFieldHTTP); err != nil {
+		return
+	}
+	if c.grpc, err = collectorsFromParsed(conf, otlpFieldGRPC); err != nil {
+		return
+	}
+	if c.pushInterval, err = conf.FieldDuration(otlpFieldPushInterval); err != nil {
+		return
+	}
+	c.tags, err = conf.FieldStringMap(otlpFieldTags)
+	return
+}
+
+//------------------------------------------------------------------------------
+
+type otlpMetrics struct {
+	log         *service.Logger
+	controllers []*controller.Controller
+	meters      []metric.Meter
+}
+
+func newOtlpMetrics(config otlpMetricsConfig, log *service.Logger) (*otlpMetrics, error) {
+	if config.pushInterval <= 0 {
+		return nil, fmt.Errorf("push interval must be greater than zero, got %v", config.pushInterval)
+	}
+
+	ctx := context.Background()
+
+	var attrs []attribute.KeyValue
+	for k, v := range config.tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+
+	var exporters []*otlpmetric.Exporter
+	for _, c := range config.grpc {
+		exp, err := newGRPCMetricExporter(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exp)
+	}
+	for _, c := range config.http {
+		exp, err := newHTTPMetricExporter(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exp)
+	}
+
+	m := &otlpMetrics{log: log}
+	for _, exp := range exporters {
+		cont := controller.New(
+			processor.NewFactory(simple.NewWithHistogramDistribution(), exp),
+			controller.WithExporter(exp),
+			controller.WithCollectPeriod(config.pushInterval),
+			controller.WithResource(res),
+		)
+		if err := cont.Start(ctx); err != nil {
+			_ = m.Close(ctx)
+			return nil, fmt.Errorf("failed to start metrics controller: %w", err)
+		}
+		m.controllers = append(m.controllers, cont)
+		m.meters = append(m.meters, cont.Meter("benthos"))
+	}
+	return m, nil
+}
+
+func newGRPCMetricExporter(ctx context.Context, c collector) (*otlpmetric.Exporter, error) {
+	clientOpts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpoint(c.host),
+	}
+	if len(c.headers) > 0 {
+		clientOpts = append(clientOpts, otlpmetricgrpc.WithHeaders(c.headers))
+	}
+	if c.tlsEnabled {
+		clientOpts = append(clientOpts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(c.tlsConf)))
+	} else {
+		clientOpts = append(clientOpts, otlpmetricgrpc.WithInsecure())
+	}
+
+	exp, err := otlpmetric.New(ctx, otlpmetricgrpc.NewClient(clientOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc metrics exporter for '%v': %w", c.host, err)
+	}
+	return exp, nil
+}
+
+func newHTTPMetricExporter(ctx context.Context, c collector) (*otlpmetric.Exporter, error) {
+	clientOpts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(c.host),
+	}
+	if c.path != "" {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithURLPath(c.path))
+	}
+	if len(c.headers) > 0 {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithHeaders(c.headers))
+	}
+	if c.tlsEnabled {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithTLSClientConfig(c.tlsConf))
+	} else {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	exp, err := otlpmetric.New(ctx, otlpmetrichttp.NewClient(clientOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http metrics exporter for '%v': %w", c.host, err)
+	}
+	return exp, nil
+}
+
+func labelAttributes(labelKeys, labelValues []string) []attribute.KeyValue {
+	if len(labelKeys) != len(labelValues) {
+		return nil
+	}
+	attrs := make([]attribute.KeyValue, len(labelKeys))
+	for i := range labelKeys {
+		attrs[i] = attribute.String(labelKeys[i], labelValues[i])
+	}
+	return attrs
+}
+
+//------------------------------------------------------------------------------
+
+type otlpCounter struct {
+	counters []syncint64.Counter
+	attrs    []attribute.KeyValue
+}
+
+func (c *otlpCounter) Incr(count int64) {
+	for _, ctr := range c.counters {
+		ctr.Add(context.Background(), count, c.attrs...)
+	}
+}
+
+func (m *otlpMetrics) NewCounterCtor(name string, labelKeys ...string) service.MetricsExporterCounterCtor {
+	var counters []syncint64.Counter
+	for _, meter := range m.meters {
+		ctr, err := meter.SyncInt64().Counter(name)
+		if err != nil {
+			m.log.Errorf("Failed to create counter '%v': %v", name, err)
+			continue
+		}
+		counters = append(counters, ctr)
+	}
+	return func(labelValues ...string) service.MetricsExporterCounter {
+		return &otlpCounter{
+			counters: counters,
+			attrs:    labelAttributes(labelKeys, labelValues),
+		}
+	}
+}
+
+type otlpTimer struct {
+	histograms []syncint64.Histogram
+	attrs      []attribute.KeyValue
+}
+
+func (t *otlpTimer) Timing(delta int64) {
+	for _, h := range t.histograms {
+		h.Record(context.Background(), delta, t.attrs...)
+	}
+}
+
+func (m *otlpMetrics) NewTimerCtor(name string, labelKeys ...string) service.MetricsExporterTimerCtor {
+	var histograms []syncint64.Histogram
+	for _, meter := range m.meters {
+		h, err := meter.SyncInt64().Histogram(name)
+		if err != nil {
+			m.log.Errorf("Failed to create timer '%v': %v", name, err)
+			continue
+		}
+		histograms = append(histograms, h)
+	}
+	return func(labelValues ...string) service.MetricsExporterTimer {
+		return &otlpTimer{
+			histograms: histograms,
+			attrs:      labelAttributes(labelKeys, labelValues),
+		}
+	}
+}
+
+// otlpGaugeFamily holds the latest value of each labelled variant of a gauge,
+// which are observed by the asynchronous gauge instruments on each collection.
+type otlpGaugeFamily struct {
+	mut    sync.Mutex
+	values map[string]*otlpGauge
+}
+
+type otlpGauge struct {
+	v     int64
+	attrs []attribute.KeyValue
+}
+
+func (g *otlpGauge) Set(value int64) {
+	atomic.StoreInt64(&g.v, value)
+}
+
+func (f *otlpGaugeFamily) observe(ctx context.Context, inst asyncint64.Gauge) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	for _, g := range f.values {
+		inst.Observe(ctx, atomic.LoadInt64(&g.v), g.attrs...)
+	}
+}
+
+func (m *otlpMetrics) NewGaugeCtor(name string, labelKeys ...string) service.MetricsExporterGaugeCtor {
+	family := &otlpGaugeFamily{
+		values: map[string]*otlpGauge{},
+	}
+	for _, meter := range m.meters {
+		inst, err := meter.AsyncInt64().Gauge(name)
+		if err != nil {
+			m.log.Errorf("Failed to create gauge '%v': %v", name, err)
+			continue
+		}
+		if err = meter.RegisterCallback([]instrument.Asynchronous{inst}, func(ctx context.Context) {
+			family.observe(ctx, inst)
+		}); err != nil {
+			m.log.Errorf("Failed to register gauge '%v': %v", name, err)
+		}
+	}
+	return func(labelValues ...string) service.MetricsExporterGauge {
+		key := strings.Join(labelValues, "\x00")
+
+		family.mut.Lock()
+		defer family.mut.Unlock()
+
+		g, exists := family.values[key]
+		if !exists {
+			g = &otlpGauge{attrs: labelAttributes(labelKeys, labelValues)}
+			family.values[key] = g
+		}
+		return g
+	}
+}
+
+func (m *otlpMetrics) Close(ctx context.Context) error {
+	var firstErr error
+	for _, cont := range m.controllers {
+		if err := cont.Stop(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,88 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestOTLPMetricsConfigParse(t *testing.T) {
+	pConf, err := otlpMetricsSpec().ParseYAML(`
+grpc:
+  - url: localhost:4317
+    headers:
+      x-api-key: foo
+push_interval: 2s
+tags:
+  service.name: benthos
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := otlpMetricsConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	assert.Empty(t, conf.http)
+	require.Len(t, conf.grpc, 1)
+	assert.Equal(t, "localhost:4317", conf.grpc[0].host)
+	assert.Equal(t, map[string]string{"x-api-key": "foo"}, conf.grpc[0].headers)
+	assert.Equal(t, "2s", conf.pushInterval.String())
+	assert.Equal(t, map[string]string{"service.name": "benthos"}, conf.tags)
+}
+
+func TestOTLPMetricsHTTP(t *testing.T) {
+	var mut sync.Mutex
+	var paths []string
+	var bodySize int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mut.Lock()
+		paths = append(paths, r.URL.Path)
+		bodySize += len(body)
+		mut.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	pConf, err := otlpMetricsSpec().ParseYAML(`
+http:
+  - url: `+strings.TrimPrefix(srv.URL, "http://")+`
+push_interval: 1h
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := otlpMetricsConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	m, err := newOtlpMetrics(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	m.NewCounterCtor("counter_foo", "label_a")("a").Incr(5)
+	m.NewTimerCtor("timer_foo")().Timing(100)
+
+	gauge := m.NewGaugeCtor("gauge_foo", "label_b")
+	gauge("b").Set(10)
+	gauge("c").Set(20)
+
+	// Stopping the controllers forces a final collection and push.
+	require.NoError(t, m.Close(context.Background()))
+
+	mut.Lock()
+	defer mut.Unlock()
+
+	require.NotEmpty(t, paths)
+	assert.Equal(t, "/v1/metrics", paths[0])
+	assert.Greater(t, bodySize, 0)
+}
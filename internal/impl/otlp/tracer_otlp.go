@@ -0,0 +1,177 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	otlpFieldSamplingRatio = "sampling_ratio"
+	otlpFieldTags          = "tags"
+	otlpFieldFlushInterval = "flush_interval"
+)
+
+func otlpTracerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("4.5.0").
+		Summary("Send tracing events to an [Open Telemetry collector](https://opentelemetry.io/docs/collector/) over OTLP.").
+		Description("Spans can be exported to any number of collectors over both gRPC and HTTP, where each collector receives every span.").
+		Field(service.NewObjectListField(otlpFieldHTTP, collectorFields("localhost:4318")...).
+			Description("A list of collectors to send tracing events to over OTLP/HTTP.").
+			Default([]interface{}{})).
+		Field(service.NewObjectListField(otlpFieldGRPC, collectorFields("localhost:4317")...).
+			Description("A list of collectors to send tracing events to over OTLP/gRPC.").
+			Default([]interface{}{})).
+		Field(service.NewFloatField(otlpFieldSamplingRatio).
+			Description("Sets the ratio of traces to sample. Tuning the sampling ratio is recommended for high-volume production workloads.").
+			Example(1.0).
+			Default(1.0)).
+		Field(service.NewStringMapField(otlpFieldTags).
+			Description("A map of tags to add to the resource attributes of all tracing spans.").
+			Default(map[string]interface{}{}).
+			Advanced()).
+		Field(service.NewStringField(otlpFieldFlushInterval).
+			Description("The period of time between each flush of tracing spans.").
+			Default(""))
+}
+
+func init() {
+	err := service.RegisterOtelTracerProvider(
+		"open_telemetry_collector", otlpTracerSpec(),
+		func(conf *service.ParsedConfig) (trace.TracerProvider, error) {
+			c, err := otlpTracerConfigFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return newOtlpTracer(c)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type otlpTracerConfig struct {
+	http          []collector
+	grpc          []collector
+	samplingRatio float64
+	tags          map[string]string
+	flushInterval string
+}
+
+func otlpTracerConfigFromParsed(conf *service.ParsedConfig) (c otlpTracerConfig, err error) {
+	if c.http, err = collectorsFromParsed(conf, otlpFieldHTTP); err != nil {
+		return
+	}
+	if c.grpc, err = collectorsFromParsed(conf, otlpFieldGRPC); err != nil {
+		return
+	}
+	if c.samplingRatio, err = conf.FieldFloat(otlpFieldSamplingRatio); err != nil {
+		return
+	}
+	if c.tags, err = conf.FieldStringMap(otlpFieldTags); err != nil {
+		return
+	}
+	c.flushInterval, err = conf.FieldString(otlpFieldFlushInterval)
+	return
+}
+
+//------------------------------------------------------------------------------
+
+func newOtlpTracer(config otlpTracerConfig) (trace.TracerProvider, error) {
+	ctx := context.Background()
+
+	var batchOpts []tracesdk.BatchSpanProcessorOption
+	if i := config.flushInterval; len(i) > 0 {
+		flushInterval, err := time.ParseDuration(i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse flush interval '%s': %v", i, err)
+		}
+		batchOpts = append(batchOpts, tracesdk.WithBatchTimeout(flushInterval))
+	}
+
+	var attrs []attribute.KeyValue
+	for k, v := range config.tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	opts := []tracesdk.TracerProviderOption{
+		tracesdk.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+		tracesdk.WithSampler(tracesdk.ParentBased(tracesdk.TraceIDRatioBased(config.samplingRatio))),
+	}
+
+	for _, c := range config.grpc {
+		exp, err := newGRPCTraceExporter(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tracesdk.WithBatcher(exp, batchOpts...))
+	}
+
+	for _, c := range config.http {
+		exp, err := newHTTPTraceExporter(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tracesdk.WithBatcher(exp, batchOpts...))
+	}
+
+	return tracesdk.NewTracerProvider(opts...), nil
+}
+
+func newGRPCTraceExporter(ctx context.Context, c collector) (*otlptrace.Exporter, error) {
+	clientOpts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(c.host),
+	}
+	if len(c.headers) > 0 {
+		clientOpts = append(clientOpts, otlptracegrpc.WithHeaders(c.headers))
+	}
+	if c.tlsEnabled {
+		clientOpts = append(clientOpts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(c.tlsConf)))
+	} else {
+		clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+	}
+
+	exp, err := otlptrace.New(ctx, otlptracegrpc.NewClient(clientOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc trace exporter for '%v': %w", c.host, err)
+	}
+	return exp, nil
+}
+
+func newHTTPTraceExporter(ctx context.Context, c collector) (*otlptrace.Exporter, error) {
+	clientOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(c.host),
+	}
+	if c.path != "" {
+		clientOpts = append(clientOpts, otlptracehttp.WithURLPath(c.path))
+	}
+	if len(c.headers) > 0 {
+		clientOpts = append(clientOpts, otlptracehttp.WithHeaders(c.headers))
+	}
+	if c.tlsEnabled {
+		clientOpts = append(clientOpts, otlptracehttp.WithTLSClientConfig(c.tlsConf))
+	} else {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+
+	exp, err := otlptrace.New(ctx, otlptracehttp.NewClient(clientOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http trace exporter for '%v': %w", c.host, err)
+	}
+	return exp, nil
+}
@@ -0,0 +1,174 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOTLPTracerConfigParse(t *testing.T) {
+	pConf, err := otlpTracerSpec().ParseYAML(`
+http:
+  - url: https://collector.example.com:4318/custom/traces
+    headers:
+      x-api-key: foo
+    tls:
+      enabled: true
+grpc:
+  - url: localhost:4317
+sampling_ratio: 0.5
+tags:
+  service.name: benthos
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := otlpTracerConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	require.Len(t, conf.http, 1)
+	assert.Equal(t, "collector.example.com:4318", conf.http[0].host)
+	assert.Equal(t, "/custom/traces", conf.http[0].path)
+	assert.Equal(t, map[string]string{"x-api-key": "foo"}, conf.http[0].headers)
+	assert.True(t, conf.http[0].tlsEnabled)
+
+	require.Len(t, conf.grpc, 1)
+	assert.Equal(t, "localhost:4317", conf.grpc[0].host)
+	assert.Equal(t, "", conf.grpc[0].path)
+	assert.False(t, conf.grpc[0].tlsEnabled)
+
+	assert.Equal(t, 0.5, conf.samplingRatio)
+	assert.Equal(t, map[string]string{"service.name": "benthos"}, conf.tags)
+}
+
+func TestOTLPTracerHTTP(t *testing.T) {
+	var mut sync.Mutex
+	var paths, apiKeys []string
+	var bodySize int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mut.Lock()
+		paths = append(paths, r.URL.Path)
+		apiKeys = append(apiKeys, r.Header.Get("x-api-key"))
+		bodySize += len(body)
+		mut.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	pConf, err := otlpTracerSpec().ParseYAML(`
+http:
+  - url: `+strings.TrimPrefix(srv.URL, "http://")+`
+    headers:
+      x-api-key: foo
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := otlpTracerConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	tp, err := newOtlpTracer(conf)
+	require.NoError(t, err)
+
+	sdkTP, ok := tp.(*tracesdk.TracerProvider)
+	require.True(t, ok)
+
+	ctx := context.Background()
+	_, span := tp.Tracer("benthos").Start(ctx, "test span")
+	span.End()
+
+	require.NoError(t, sdkTP.ForceFlush(ctx))
+	require.NoError(t, sdkTP.Shutdown(ctx))
+
+	mut.Lock()
+	defer mut.Unlock()
+
+	require.NotEmpty(t, paths)
+	assert.Equal(t, "/v1/traces", paths[0])
+	assert.Equal(t, "foo", apiKeys[0])
+	assert.Greater(t, bodySize, 0)
+}
+
+type testTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mut       sync.Mutex
+	apiKeys   []string
+	spanNames []string
+}
+
+func (s *testTraceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.apiKeys = append(s.apiKeys, md.Get("x-api-key")...)
+	for _, rs := range req.ResourceSpans {
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			for _, span := range ils.Spans {
+				s.spanNames = append(s.spanNames, span.Name)
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestOTLPTracerGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	svc := &testTraceService{}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, svc)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	pConf, err := otlpTracerSpec().ParseYAML(`
+grpc:
+  - url: `+lis.Addr().String()+`
+    headers:
+      x-api-key: foo
+`, nil)
+	require.NoError(t, err)
+
+	conf, err := otlpTracerConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	tp, err := newOtlpTracer(conf)
+	require.NoError(t, err)
+
+	sdkTP, ok := tp.(*tracesdk.TracerProvider)
+	require.True(t, ok)
+
+	ctx := context.Background()
+	_, span := tp.Tracer("benthos").Start(ctx, "test span")
+	span.End()
+
+	require.NoError(t, sdkTP.ForceFlush(ctx))
+	require.NoError(t, sdkTP.Shutdown(ctx))
+
+	svc.mut.Lock()
+	defer svc.mut.Unlock()
+
+	assert.Equal(t, []string{"test span"}, svc.spanNames)
+	require.NotEmpty(t, svc.apiKeys)
+	assert.Equal(t, "foo", svc.apiKeys[0])
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"sync"
 	"time"
@@ -18,19 +19,17 @@ const (
 	defaultSubscriptionType = "shared"
 )
 
-func init() {
-	err := service.RegisterInput(
-		"pulsar",
-		service.NewConfigSpec().
-			Version("3.43.0").
-			Categories("Services").
-			Summary("Reads messages from an Apache Pulsar server.").
-			Description(`
+func pulsarInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("3.43.0").
+		Categories("Services").
+		Summary("Reads messages from an Apache Pulsar server.").
+		Description(`
 ### Metadata
 
 This input adds the following metadata fields to each message:
 
-`+"```text"+`
+` + "```text" + `
 - pulsar_message_id
 - pulsar_key
 - pulsar_ordering_key
@@ -40,26 +39,57 @@ This input adds the following metadata fields to each message:
 - pulsar_producer_name
 - pulsar_redelivery_count
 - All properties of the message
-`+"```"+`
+` + "```" + `
 
 You can access these metadata fields using
 [function interpolation](/docs/configuration/interpolation#metadata).
+
+### Dead Letter Topics
+
+Messages that fail to be delivered are negatively acknowledged, and are redelivered by the broker after ` + "`nack_redelivery_delay`" + `. When a ` + "`dead_letter_policy`" + ` is configured messages that have been delivered ` + "`max_deliveries`" + ` times are sent to a dead letter topic instead of being redelivered, which is only supported by the ` + "`shared` and `key_shared`" + ` subscription types.
 `).
-			Field(service.NewStringField("url").
-				Description("A URL to connect to.").
-				Example("pulsar://localhost:6650").
-				Example("pulsar://pulsar.us-west.example.com:6650").
-				Example("pulsar+ssl://pulsar.us-west.example.com:6651")).
-			Field(service.NewStringListField("topics").
-				Description("A list of topics to subscribe to.")).
-			Field(service.NewStringField("subscription_name").
-				Description("Specify the subscription name for this consumer.")).
-			Field(service.NewStringEnumField("subscription_type", "shared", "key_shared", "failover", "exclusive").
-				Description("Specify the subscription type for this consumer.\n\n> NOTE: Using a `key_shared` subscription type will __allow out-of-order delivery__ since nack-ing messages sets non-zero nack delivery delay - this can potentially cause consumers to stall. See [Pulsar documentation](https://pulsar.apache.org/docs/en/2.8.1/concepts-messaging/#negative-acknowledgement) and [this Github issue](https://github.com/apache/pulsar/issues/12208) for more details.").
-				Default(defaultSubscriptionType)).
-			Field(service.NewObjectField("tls", service.NewStringField("root_cas_file")).
-				Description("Specify the path to a custom CA certificate to trust broker TLS service.")).
-			Field(authField()),
+		Field(service.NewStringField("url").
+			Description("A URL to connect to.").
+			Example("pulsar://localhost:6650").
+			Example("pulsar://pulsar.us-west.example.com:6650").
+			Example("pulsar+ssl://pulsar.us-west.example.com:6651")).
+		Field(service.NewStringListField("topics").
+			Description("A list of topics to subscribe to. Either this field or `topics_pattern` must be set.").
+			Optional()).
+		Field(service.NewStringField("topics_pattern").
+			Description("A regular expression matching the topics to subscribe to, topics created after the consumer starts are discovered periodically. Either this field or `topics` must be set.").
+			Example("persistent://public/default/events-.*").
+			Version("4.5.0").
+			Optional()).
+		Field(service.NewStringField("subscription_name").
+			Description("Specify the subscription name for this consumer.")).
+		Field(service.NewStringEnumField("subscription_type", "shared", "key_shared", "failover", "exclusive").
+			Description("Specify the subscription type for this consumer.\n\n> NOTE: Using a `key_shared` subscription type will __allow out-of-order delivery__ since nack-ing messages sets non-zero nack delivery delay - this can potentially cause consumers to stall. See [Pulsar documentation](https://pulsar.apache.org/docs/en/2.8.1/concepts-messaging/#negative-acknowledgement) and [this Github issue](https://github.com/apache/pulsar/issues/12208) for more details.").
+			Default(defaultSubscriptionType)).
+		Field(service.NewDurationField("nack_redelivery_delay").
+			Description("The delay after which messages that failed to be delivered are redelivered.").
+			Default("1m").
+			Version("4.5.0").
+			Advanced()).
+		Field(service.NewObjectField("dead_letter_policy",
+			service.NewIntField("max_deliveries").
+				Description("The maximum number of times that a message is delivered before it is sent to the dead letter topic."),
+			service.NewStringField("dead_letter_topic").
+				Description("The topic to send messages to once they exceed the maximum number of deliveries, by default the topic `<topic>-<subscription_name>-DLQ` is used.").
+				Default(""),
+		).
+			Description("An optional policy for sending messages that repeatedly fail to be delivered to a dead letter topic.").
+			Version("4.5.0").
+			Optional().
+			Advanced()).
+		Field(service.NewObjectField("tls", service.NewStringField("root_cas_file").Default("")).
+			Description("Specify the path to a custom CA certificate to trust broker TLS service.")).
+		Field(authField())
+}
+
+func init() {
+	err := service.RegisterInput(
+		"pulsar", pulsarInputConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
 			return newPulsarReaderFromParsed(conf, mgr.Logger())
 		})
@@ -80,9 +110,12 @@ type pulsarReader struct {
 	authConf    authConfig
 	url         string
 	topics      []string
+	topicsRegex string
 	subName     string
 	subType     string
 	rootCasFile string
+	nackDelay   time.Duration
+	dlqPolicy   *pulsar.DLQPolicy
 }
 
 func newPulsarReaderFromParsed(conf *service.ParsedConfig, log *service.Logger) (p *pulsarReader, err error) {
@@ -97,8 +130,19 @@ func newPulsarReaderFromParsed(conf *service.ParsedConfig, log *service.Logger)
 	if p.url, err = conf.FieldString("url"); err != nil {
 		return
 	}
-	if p.topics, err = conf.FieldStringList("topics"); err != nil {
-		return
+	if conf.Contains("topics") {
+		if p.topics, err = conf.FieldStringList("topics"); err != nil {
+			return
+		}
+	}
+	if conf.Contains("topics_pattern") {
+		if p.topicsRegex, err = conf.FieldString("topics_pattern"); err != nil {
+			return
+		}
+		if _, err = regexp.Compile(p.topicsRegex); err != nil {
+			err = fmt.Errorf("field topics_pattern is invalid: %v", err)
+			return
+		}
 	}
 	if p.subName, err = conf.FieldString("subscription_name"); err != nil {
 		return
@@ -109,13 +153,34 @@ func newPulsarReaderFromParsed(conf *service.ParsedConfig, log *service.Logger)
 	if p.rootCasFile, err = conf.FieldString("tls", "root_cas_file"); err != nil {
 		return
 	}
+	if p.nackDelay, err = conf.FieldDuration("nack_redelivery_delay"); err != nil {
+		return
+	}
+	if conf.Contains("dead_letter_policy") {
+		var maxDeliveries int
+		if maxDeliveries, err = conf.FieldInt("dead_letter_policy", "max_deliveries"); err != nil {
+			return
+		}
+		if maxDeliveries <= 0 {
+			err = errors.New("field dead_letter_policy.max_deliveries must be greater than zero")
+			return
+		}
+		p.dlqPolicy = &pulsar.DLQPolicy{MaxDeliveries: uint32(maxDeliveries)}
+		if p.dlqPolicy.DeadLetterTopic, err = conf.FieldString("dead_letter_policy", "dead_letter_topic"); err != nil {
+			return
+		}
+	}
 
 	if p.url == "" {
 		err = errors.New("field url must not be empty")
 		return
 	}
-	if len(p.topics) == 0 {
-		err = errors.New("field topics must not be empty")
+	if len(p.topics) == 0 && p.topicsRegex == "" {
+		err = errors.New("either field topics or topics_pattern must be set")
+		return
+	}
+	if len(p.topics) > 0 && p.topicsRegex != "" {
+		err = errors.New("fields topics and topics_pattern cannot be used together")
 		return
 	}
 	if p.subName == "" {
@@ -189,9 +254,12 @@ func (p *pulsarReader) Connect(ctx context.Context) error {
 	}
 
 	if consumer, err = client.Subscribe(pulsar.ConsumerOptions{
-		Topics:           p.topics,
-		SubscriptionName: p.subName,
-		Type:             subType,
+		Topics:              p.topics,
+		TopicsPattern:       p.topicsRegex,
+		SubscriptionName:    p.subName,
+		Type:                subType,
+		NackRedeliveryDelay: p.nackDelay,
+		DLQ:                 p.dlqPolicy,
 		KeySharedPolicy: &pulsar.KeySharedPolicy{
 			AllowOutOfOrderDelivery: true,
 		},
@@ -0,0 +1,77 @@
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPulsarInputConfig(t *testing.T) {
+	conf, err := pulsarInputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topics_pattern: persistent://public/default/events-.*
+subscription_name: foo
+nack_redelivery_delay: 10s
+dead_letter_policy:
+  max_deliveries: 3
+  dead_letter_topic: events-dlq
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newPulsarReaderFromParsed(conf, nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, r.topics)
+	assert.Equal(t, "persistent://public/default/events-.*", r.topicsRegex)
+	assert.Equal(t, time.Second*10, r.nackDelay)
+	require.NotNil(t, r.dlqPolicy)
+	assert.Equal(t, uint32(3), r.dlqPolicy.MaxDeliveries)
+	assert.Equal(t, "events-dlq", r.dlqPolicy.DeadLetterTopic)
+
+	conf, err = pulsarInputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topics: [ foo ]
+subscription_name: foo
+`, nil)
+	require.NoError(t, err)
+
+	r, err = newPulsarReaderFromParsed(conf, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"foo"}, r.topics)
+	assert.Equal(t, time.Minute, r.nackDelay)
+	assert.Nil(t, r.dlqPolicy)
+
+	for _, c := range []string{
+		`
+url: pulsar://localhost:6650
+subscription_name: foo
+`,
+		`
+url: pulsar://localhost:6650
+topics: [ foo ]
+topics_pattern: foo.*
+subscription_name: foo
+`,
+		`
+url: pulsar://localhost:6650
+topics_pattern: foo(
+subscription_name: foo
+`,
+		`
+url: pulsar://localhost:6650
+topics: [ foo ]
+subscription_name: foo
+dead_letter_policy:
+  max_deliveries: 0
+`,
+	} {
+		conf, err = pulsarInputConfig().ParseYAML(c, nil)
+		require.NoError(t, err)
+
+		_, err = newPulsarReaderFromParsed(conf, nil)
+		assert.Error(t, err, c)
+	}
+}
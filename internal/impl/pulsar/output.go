@@ -2,51 +2,101 @@ package pulsar
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/linkedin/goavro/v2"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+func pulsarOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Version("3.43.0").
+		Categories("Services").
+		Summary("Write messages to an Apache Pulsar server.").
+		Description(`
+Messages of a batch are sent asynchronously and the producer is flushed at the end of each batch, which allows the producer to group them into batches of its own. Messages that are delayed with the ` + "`deliver_at` or `deliver_after`" + ` fields are sent individually, and are only delayed for subscriptions of the ` + "`shared`" + ` type.
+
+### Schemas
+
+A schema can be associated with the topic by setting ` + "`schema.type`" + ` to either ` + "`avro` or `json`" + ` and providing the definition of the schema in the Avro format, which is registered with the broker when the producer is created. In both cases messages are expected to be JSON documents that conform to the schema, and when the type is ` + "`avro`" + ` they are converted to the Avro binary format before being sent.`).
+		Field(service.NewStringField("url").
+			Description("A URL to connect to.").
+			Example("pulsar://localhost:6650").
+			Example("pulsar://pulsar.us-west.example.com:6650").
+			Example("pulsar+ssl://pulsar.us-west.example.com:6651")).
+		Field(service.NewStringField("topic").
+			Description("The topic to publish to.")).
+		Field(service.NewObjectField("tls", service.NewStringField("root_cas_file").Default("")).
+			Description("Specify the path to a custom CA certificate to trust broker TLS service.")).
+		Field(service.NewInterpolatedStringField("key").
+			Description("The key to publish messages with.").
+			Default("")).
+		Field(service.NewInterpolatedStringField("ordering_key").
+			Description("The ordering key to publish messages with.").
+			Default("")).
+		Field(service.NewInterpolatedStringField("deliver_at").
+			Description("An optional time at which messages should be delivered to consumers, as either a unix timestamp in seconds or an RFC 3339 timestamp. Cannot be used together with `deliver_after`.").
+			Example(`${! meta("scheduled_for") }`).
+			Example(`${! (timestamp_unix() + 3600) }`).
+			Version("4.5.0").
+			Optional().
+			Advanced()).
+		Field(service.NewInterpolatedStringField("deliver_after").
+			Description("An optional duration after which messages should be delivered to consumers. Cannot be used together with `deliver_at`.").
+			Example("10m").
+			Example(`${! meta("delay") }`).
+			Version("4.5.0").
+			Optional().
+			Advanced()).
+		Field(service.NewStringEnumField("compression", "none", "lz4", "zlib", "zstd").
+			Description("The compression algorithm to use for messages.").
+			Default("none").
+			Version("4.5.0").
+			Advanced()).
+		Field(service.NewObjectField("schema",
+			service.NewStringEnumField("type", "none", "avro", "json").
+				Description("The type of the schema.").
+				Default("none"),
+			service.NewStringField("definition").
+				Description("The definition of the schema in the Avro format.").
+				Example(`{"type":"record","name":"event","fields":[{"name":"id","type":"string"}]}`).
+				Default(""),
+		).
+			Description("An optional schema to associate with the topic.").
+			Version("4.5.0").
+			Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(64)).
+		Field(service.NewBatchPolicyField("batching").Version("4.5.0")).
+		Field(authField())
+}
+
 func init() {
-	err := service.RegisterOutput(
-		"pulsar",
-		service.NewConfigSpec().
-			Version("3.43.0").
-			Categories("Services").
-			Summary("Write messages to an Apache Pulsar server.").
-			Field(service.NewStringField("url").
-				Description("A URL to connect to.").
-				Example("pulsar://localhost:6650").
-				Example("pulsar://pulsar.us-west.example.com:6650").
-				Example("pulsar+ssl://pulsar.us-west.example.com:6651")).
-			Field(service.NewStringField("topic").
-				Description("The topic to publish to.")).
-			Field(service.NewObjectField("tls", service.NewStringField("root_cas_file")).
-				Description("Specify the path to a custom CA certificate to trust broker TLS service.")).
-			Field(service.NewInterpolatedStringField("key").
-				Description("The key to publish messages with.").
-				Default("")).
-			Field(service.NewInterpolatedStringField("ordering_key").
-				Description("The ordering key to publish messages with.").
-				Default("")).
-			Field(service.NewIntField("max_in_flight").
-				Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
-				Default(64)).
-			Field(authField()),
-		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
-			w, err := newPulsarWriterFromParsed(conf, mgr.Logger())
-			if err != nil {
-				return nil, 0, err
+	err := service.RegisterBatchOutput(
+		"pulsar", pulsarOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (
+			output service.BatchOutput,
+			batchPolicy service.BatchPolicy,
+			maxInFlight int,
+			err error,
+		) {
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
 			}
-			n, err := conf.FieldInt("max_in_flight")
-			if err != nil {
-				return nil, 0, err
+			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
+				return
 			}
-			return w, n, err
+			output, err = newPulsarWriterFromParsed(conf, mgr.Logger())
+			return
 		})
 	if err != nil {
 		panic(err)
@@ -62,12 +112,17 @@ type pulsarWriter struct {
 
 	log *service.Logger
 
-	authConf    authConfig
-	url         string
-	topic       string
-	rootCasFile string
-	key         *service.InterpolatedString
-	orderingKey *service.InterpolatedString
+	authConf     authConfig
+	url          string
+	topic        string
+	rootCasFile  string
+	key          *service.InterpolatedString
+	orderingKey  *service.InterpolatedString
+	deliverAt    *service.InterpolatedString
+	deliverAfter *service.InterpolatedString
+	compression  pulsar.CompressionType
+	schema       pulsar.Schema
+	avroCodec    *goavro.Codec
 }
 
 func newPulsarWriterFromParsed(conf *service.ParsedConfig, log *service.Logger) (p *pulsarWriter, err error) {
@@ -94,9 +149,82 @@ func newPulsarWriterFromParsed(conf *service.ParsedConfig, log *service.Logger)
 	if p.orderingKey, err = conf.FieldInterpolatedString("ordering_key"); err != nil {
 		return
 	}
+	if conf.Contains("deliver_at") {
+		if p.deliverAt, err = conf.FieldInterpolatedString("deliver_at"); err != nil {
+			return
+		}
+	}
+	if conf.Contains("deliver_after") {
+		if p.deliverAfter, err = conf.FieldInterpolatedString("deliver_after"); err != nil {
+			return
+		}
+	}
+	if p.deliverAt != nil && p.deliverAfter != nil {
+		err = errors.New("fields deliver_at and deliver_after cannot be used together")
+		return
+	}
+
+	var compression string
+	if compression, err = conf.FieldString("compression"); err != nil {
+		return
+	}
+	if p.compression, err = parseCompressionType(compression); err != nil {
+		return
+	}
+
+	var schemaType, schemaDef string
+	if schemaType, err = conf.FieldString("schema", "type"); err != nil {
+		return
+	}
+	if schemaDef, err = conf.FieldString("schema", "definition"); err != nil {
+		return
+	}
+	if p.schema, p.avroCodec, err = parseSchema(schemaType, schemaDef); err != nil {
+		err = fmt.Errorf("field schema is invalid: %w", err)
+	}
 	return
 }
 
+func parseCompressionType(compression string) (pulsar.CompressionType, error) {
+	switch compression {
+	case "none":
+		return pulsar.NoCompression, nil
+	case "lz4":
+		return pulsar.LZ4, nil
+	case "zlib":
+		return pulsar.ZLib, nil
+	case "zstd":
+		return pulsar.ZSTD, nil
+	}
+	return pulsar.NoCompression, fmt.Errorf("could not parse compression type: %s", compression)
+}
+
+// parseSchema returns the schema to create a producer with, and when the schema
+// is Avro a codec for converting JSON documents into the Avro binary format.
+func parseSchema(schemaType, definition string) (pulsar.Schema, *goavro.Codec, error) {
+	if schemaType == "none" || schemaType == "" {
+		return nil, nil, nil
+	}
+	if definition == "" {
+		return nil, nil, errors.New("a definition must be provided")
+	}
+
+	// The schema constructors of the Pulsar client exit the process when given
+	// an invalid definition, so we check it ourselves first.
+	codec, err := goavro.NewCodecForStandardJSON(definition)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse definition: %w", err)
+	}
+
+	switch schemaType {
+	case "avro":
+		return pulsar.NewAvroSchema(definition, nil), codec, nil
+	case "json":
+		return pulsar.NewJSONSchema(definition, nil), nil, nil
+	}
+	return nil, nil, fmt.Errorf("could not parse schema type: %s", schemaType)
+}
+
 //------------------------------------------------------------------------------
 
 func (p *pulsarWriter) Connect(ctx context.Context) error {
@@ -131,7 +259,9 @@ func (p *pulsarWriter) Connect(ctx context.Context) error {
 	}
 
 	if producer, err = client.CreateProducer(pulsar.ProducerOptions{
-		Topic: p.topic,
+		Topic:           p.topic,
+		CompressionType: p.compression,
+		Schema:          p.schema,
 	}); err != nil {
 		client.Close()
 		return err
@@ -163,7 +293,62 @@ func (p *pulsarWriter) disconnect(ctx context.Context) error {
 
 //------------------------------------------------------------------------------
 
-func (p *pulsarWriter) Write(ctx context.Context, msg *service.Message) error {
+func (p *pulsarWriter) producerMessage(b service.MessageBatch, i int) (*pulsar.ProducerMessage, error) {
+	payload, err := b[i].AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &pulsar.ProducerMessage{}
+	switch {
+	case p.avroCodec != nil:
+		if m.Value, _, err = p.avroCodec.NativeFromTextual(payload); err != nil {
+			return nil, fmt.Errorf("failed to convert message to avro: %w", err)
+		}
+	case p.schema != nil:
+		m.Value = json.RawMessage(payload)
+	default:
+		m.Payload = payload
+	}
+
+	if key := b.InterpolatedBytes(i, p.key); len(key) > 0 {
+		m.Key = string(key)
+	}
+	if orderingKey := b.InterpolatedBytes(i, p.orderingKey); len(orderingKey) > 0 {
+		m.OrderingKey = string(orderingKey)
+	}
+	if p.deliverAt != nil {
+		if m.DeliverAt, err = parseDeliverAt(b.InterpolatedString(i, p.deliverAt)); err != nil {
+			return nil, err
+		}
+	}
+	if p.deliverAfter != nil {
+		if after := b.InterpolatedString(i, p.deliverAfter); after != "" {
+			if m.DeliverAfter, err = time.ParseDuration(after); err != nil {
+				return nil, fmt.Errorf("failed to parse deliver_after duration: %w", err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// parseDeliverAt parses either a unix timestamp in seconds or an RFC 3339
+// timestamp, where an empty string results in a zero time.
+func parseDeliverAt(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse deliver_at timestamp: %w", err)
+	}
+	return t, nil
+}
+
+func (p *pulsarWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
 	var r pulsar.Producer
 	p.m.RLock()
 	if p.producer != nil {
@@ -175,23 +360,36 @@ func (p *pulsarWriter) Write(ctx context.Context, msg *service.Message) error {
 		return component.ErrNotConnected
 	}
 
-	b, err := msg.AsBytes()
-	if err != nil {
-		return err
+	msgs := make([]*pulsar.ProducerMessage, len(b))
+	for i := range b {
+		m, err := p.producerMessage(b, i)
+		if err != nil {
+			return err
+		}
+		msgs[i] = m
 	}
 
-	m := &pulsar.ProducerMessage{
-		Payload: b,
-	}
-	if key := p.key.Bytes(msg); len(key) > 0 {
-		m.Key = string(key)
-	}
-	if orderingKey := p.orderingKey.Bytes(msg); len(orderingKey) > 0 {
-		m.OrderingKey = string(orderingKey)
+	var wg sync.WaitGroup
+	wg.Add(len(msgs))
+
+	errs := make([]error, len(msgs))
+	for i, m := range msgs {
+		i := i
+		r.SendAsync(ctx, m, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+			errs[i] = err
+			wg.Done()
+		})
 	}
 
-	_, err = r.Send(context.Background(), m)
-	return err
+	flushErr := r.Flush()
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return flushErr
 }
 
 func (p *pulsarWriter) Close(ctx context.Context) error {
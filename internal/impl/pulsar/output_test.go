@@ -0,0 +1,150 @@
+package pulsar
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestPulsarOutputProducerMessage(t *testing.T) {
+	conf, err := pulsarOutputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topic: foo
+key: ${! meta("key").or("") }
+deliver_at: ${! meta("at") }
+compression: zstd
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newPulsarWriterFromParsed(conf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, pulsar.ZSTD, w.compression)
+	assert.Nil(t, w.schema)
+
+	msgA := service.NewMessage([]byte("hello"))
+	msgA.MetaSet("key", "a")
+	msgA.MetaSet("at", "1654041600")
+
+	msgB := service.NewMessage([]byte("world"))
+	msgB.MetaSet("at", "2022-06-01T00:00:00Z")
+
+	batch := service.MessageBatch{msgA, msgB}
+
+	m, err := w.producerMessage(batch, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(m.Payload))
+	assert.Equal(t, "a", m.Key)
+	assert.Equal(t, int64(1654041600), m.DeliverAt.Unix())
+
+	m, err = w.producerMessage(batch, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(m.Payload))
+	assert.Equal(t, "", m.Key)
+	assert.Equal(t, int64(1654041600), m.DeliverAt.Unix())
+
+	msgB.MetaSet("at", "tomorrow")
+	_, err = w.producerMessage(batch, 1)
+	require.Error(t, err)
+}
+
+func TestPulsarOutputDeliverAfter(t *testing.T) {
+	conf, err := pulsarOutputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topic: foo
+deliver_after: ${! meta("delay") }
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newPulsarWriterFromParsed(conf, nil)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte("hello"))
+	msg.MetaSet("delay", "90s")
+
+	m, err := w.producerMessage(service.MessageBatch{msg}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*90, m.DeliverAfter)
+	assert.True(t, m.DeliverAt.IsZero())
+
+	conf, err = pulsarOutputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topic: foo
+deliver_at: ${! meta("at") }
+deliver_after: 10s
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newPulsarWriterFromParsed(conf, nil)
+	require.Error(t, err)
+}
+
+func TestPulsarOutputSchema(t *testing.T) {
+	schema := `{"type":"record","name":"event","fields":[{"name":"id","type":"string"},{"name":"count","type":["null","int"]}]}`
+
+	conf, err := pulsarOutputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topic: foo
+schema:
+  type: avro
+  definition: '`+schema+`'
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newPulsarWriterFromParsed(conf, nil)
+	require.NoError(t, err)
+	require.NotNil(t, w.schema)
+
+	m, err := w.producerMessage(service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"foo","count":10}`)),
+	}, 0)
+	require.NoError(t, err)
+	assert.Nil(t, m.Payload)
+
+	b, err := w.schema.Encode(m.Value)
+	require.NoError(t, err)
+
+	var v interface{}
+	require.NoError(t, w.schema.Decode(b, &v))
+	assert.Equal(t, "foo", v.(map[string]interface{})["id"])
+
+	_, err = w.producerMessage(service.MessageBatch{
+		service.NewMessage([]byte(`{"count":10}`)),
+	}, 0)
+	require.Error(t, err)
+
+	conf, err = pulsarOutputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topic: foo
+schema:
+  type: json
+  definition: '`+schema+`'
+`, nil)
+	require.NoError(t, err)
+
+	w, err = newPulsarWriterFromParsed(conf, nil)
+	require.NoError(t, err)
+
+	m, err = w.producerMessage(service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"foo"}`)),
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, json.RawMessage(`{"id":"foo"}`), m.Value)
+
+	conf, err = pulsarOutputConfig().ParseYAML(`
+url: pulsar://localhost:6650
+topic: foo
+schema:
+  type: avro
+  definition: 'not a schema'
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newPulsarWriterFromParsed(conf, nil)
+	require.Error(t, err)
+}
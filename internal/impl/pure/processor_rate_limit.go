@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
@@ -31,8 +32,16 @@ Throttles the throughput of a pipeline according to a specified
 ` + "[`rate_limit`](/docs/components/rate_limits/about)" + ` resource. Rate limits are
 shared across components and therefore apply globally to all processing
 pipelines.`,
+		Description: `
+### Keyed Limits
+
+When a ` + "`key`" + ` is specified each message is limited against a separate limit for the resolved key, allowing a single rate limit resource to throttle each tenant of a pipeline independently. Rate limit resources that do not implement keyed access, which currently includes every type other than ` + "`local`" + `, ignore the key and apply their shared limit to all messages.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString("resource", "The target [`rate_limit` resource](/docs/components/rate_limits/about).").HasDefault(""),
+			docs.FieldString(
+				"key", "An optional key to limit messages by, where each unique key is limited independently. When empty all messages share the same limit.",
+				`${! meta("tenant") }`,
+			).IsInterpolated().AtVersion("4.5.0").HasDefault(""),
 		),
 	})
 	if err != nil {
@@ -42,6 +51,7 @@ pipelines.`,
 
 type rateLimitProc struct {
 	rlName string
+	key    *field.Expression
 	mgr    bundle.NewManagement
 
 	closeChan chan struct{}
@@ -52,8 +62,16 @@ func newRateLimitProc(conf processor.RateLimitConfig, mgr bundle.NewManagement)
 	if !mgr.ProbeRateLimit(conf.Resource) {
 		return nil, fmt.Errorf("rate limit resource '%v' was not found", conf.Resource)
 	}
+	var key *field.Expression
+	if conf.Key != "" {
+		var err error
+		if key, err = mgr.BloblEnvironment().NewField(conf.Key); err != nil {
+			return nil, fmt.Errorf("failed to parse key expression: %v", err)
+		}
+	}
 	r := &rateLimitProc{
 		rlName:    conf.Resource,
+		key:       key,
 		mgr:       mgr,
 		closeChan: make(chan struct{}),
 	}
@@ -61,10 +79,20 @@ func newRateLimitProc(conf processor.RateLimitConfig, mgr bundle.NewManagement)
 }
 
 func (r *rateLimitProc) Process(ctx context.Context, msg *message.Part) ([]*message.Part, error) {
+	var key string
+	if r.key != nil {
+		batch := message.QuickBatch(nil)
+		batch.Append(msg)
+		key = r.key.String(0, batch)
+	}
 	for {
 		var waitFor time.Duration
 		var err error
 		if rerr := r.mgr.AccessRateLimit(ctx, r.rlName, func(rl ratelimit.V1) {
+			if kr, ok := rl.(ratelimit.Keyed); ok && r.key != nil {
+				waitFor, err = kr.AccessKey(ctx, key)
+				return
+			}
 			waitFor, err = rl.Access(ctx)
 		}); rerr != nil {
 			err = rerr
@@ -8,7 +8,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/manager"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
 
@@ -150,3 +155,51 @@ func TestRateLimitBlocked(t *testing.T) {
 		t.Error("Timed out")
 	}
 }
+
+func TestRateLimitKeyed(t *testing.T) {
+	mgrConf := manager.NewResourceConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+rate_limit_resources:
+  - label: foo
+    local:
+      count: 1
+      interval: 1h
+`), &mgrConf))
+
+	mgr, err := manager.New(mgrConf)
+	require.NoError(t, err)
+
+	conf := processor.NewConfig()
+	conf.Type = "rate_limit"
+	conf.RateLimit.Resource = "foo"
+	conf.RateLimit.Key = `${! json("key") }`
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	// Each message has a different key and therefore shouldn't be blocked by
+	// the limit of one per hour.
+	input := message.QuickBatch([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"2","value":"foo 2"}`),
+		[]byte(`{"key":"3","value":"foo 3"}`),
+	})
+
+	closedChan := make(chan struct{})
+	go func() {
+		defer close(closedChan)
+
+		output, res := proc.ProcessMessage(input)
+		assert.Nil(t, res)
+		if assert.Len(t, output, 1) {
+			assert.Equal(t, message.GetAllBytes(input), message.GetAllBytes(output[0]))
+		}
+	}()
+
+	select {
+	case <-closedChan:
+	case <-time.After(time.Second):
+		t.Error("Timed out")
+	}
+
+	proc.CloseAsync()
+}
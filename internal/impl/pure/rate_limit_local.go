@@ -3,22 +3,51 @@ package pure
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+const (
+	lrlAlgorithmFixedWindow = "fixed_window"
+	lrlAlgorithmTokenBucket = "token_bucket"
+)
+
 func localRatelimitConfig() *service.ConfigSpec {
 	spec := service.NewConfigSpec().
 		Stable().
 		Summary(`The local rate limit is a simple X every Y type rate limit that can be shared across any number of components within the pipeline but does not support distributed rate limits across multiple running instances of Benthos.`).
+		Description(`
+### Algorithms
+
+The ` + "`fixed_window`" + ` algorithm permits ` + "`count`" + ` requests and then blocks until the ` + "`interval`" + ` has passed, at which point the full count is permitted again. This can result in bursts of traffic at the boundaries of each interval.
+
+The ` + "`token_bucket`" + ` algorithm refills a bucket of tokens continuously at a rate of ` + "`count`" + ` tokens per ` + "`interval`" + `, where each request consumes a token. The bucket holds up to ` + "`burst`" + ` tokens, which is the largest number of requests that may be permitted at once after a period of inactivity.
+
+### Keyed Access
+
+When accessed with a key, such as by the ` + "[`rate_limit` processor](/docs/components/processors/rate_limit)" + ` with a ` + "`key`" + ` configured, a separate limit is tracked for each unique key. This allows a single rate limit resource to limit each tenant of a pipeline independently. Keys that have not been accessed for long enough that their limit would be fully replenished are periodically removed.`).
 		Field(service.NewIntField("count").
 			Description("The maximum number of requests to allow for a given period of time.").
 			Default(1000)).
 		Field(service.NewDurationField("interval").
 			Description("The time window to limit requests by.").
-			Default("1s"))
+			Default("1s")).
+		Field(service.NewStringAnnotatedEnumField("algorithm", map[string]string{
+			lrlAlgorithmFixedWindow: "Permit `count` requests within each consecutive `interval`.",
+			lrlAlgorithmTokenBucket: "Refill a bucket of tokens at a steady rate of `count` per `interval`.",
+		}).
+			Description("The algorithm used to enforce the limit.").
+			Version("4.5.0").
+			Default(lrlAlgorithmFixedWindow).
+			Advanced()).
+		Field(service.NewIntField("burst").
+			Description("The maximum number of tokens that the bucket can hold when the `token_bucket` algorithm is used. When set to zero the value of `count` is used.").
+			Version("4.5.0").
+			Default(0).
+			Advanced())
 
 	return spec
 }
@@ -44,49 +73,160 @@ func newLocalRatelimitFromConfig(conf *service.ParsedConfig) (*localRatelimit, e
 	if err != nil {
 		return nil, err
 	}
-	return newLocalRatelimit(count, interval)
+	algorithm, err := conf.FieldString("algorithm")
+	if err != nil {
+		return nil, err
+	}
+	burst, err := conf.FieldInt("burst")
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case lrlAlgorithmFixedWindow:
+		return newLocalRatelimit(count, interval)
+	case lrlAlgorithmTokenBucket:
+		return newLocalTokenBucketRatelimit(count, burst, interval)
+	}
+	return nil, fmt.Errorf("algorithm '%v' was not recognised", algorithm)
 }
 
 //------------------------------------------------------------------------------
 
-type localRatelimit struct {
-	mut         sync.Mutex
-	bucket      int
+// localBucket is the state of a single limit, either the shared limit or that
+// of an individual key.
+type localBucket struct {
+	tokens      float64
 	lastRefresh time.Time
+}
+
+type localRatelimit struct {
+	mut       sync.Mutex
+	shared    localBucket
+	keyed     map[string]*localBucket
+	lastSweep time.Time
 
-	size   int
-	period time.Duration
+	tokenBucket bool
+	size        int
+	capacity    int
+	period      time.Duration
 }
 
 func newLocalRatelimit(count int, interval time.Duration) (*localRatelimit, error) {
 	if count <= 0 {
 		return nil, errors.New("count must be larger than zero")
 	}
+	return newLocalRatelimitState(false, count, count, interval), nil
+}
+
+func newLocalTokenBucketRatelimit(count, burst int, interval time.Duration) (*localRatelimit, error) {
+	if count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	if burst < 0 {
+		return nil, errors.New("burst must not be negative")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be larger than zero")
+	}
+	if burst == 0 {
+		burst = count
+	}
+	return newLocalRatelimitState(true, count, burst, interval), nil
+}
+
+func newLocalRatelimitState(tokenBucket bool, count, capacity int, interval time.Duration) *localRatelimit {
+	now := time.Now()
 	return &localRatelimit{
-		bucket:      count,
-		lastRefresh: time.Now(),
+		shared: localBucket{
+			tokens:      float64(capacity),
+			lastRefresh: now,
+		},
+		keyed:       map[string]*localBucket{},
+		lastSweep:   now,
+		tokenBucket: tokenBucket,
 		size:        count,
+		capacity:    capacity,
 		period:      interval,
-	}, nil
+	}
+}
+
+// refillPeriod returns the length of time it takes an exhausted bucket to be
+// fully replenished.
+func (r *localRatelimit) refillPeriod() time.Duration {
+	if !r.tokenBucket {
+		return r.period
+	}
+	return time.Duration(float64(r.period) * float64(r.capacity) / float64(r.size))
+}
+
+func (r *localRatelimit) access(b *localBucket, now time.Time) time.Duration {
+	if r.tokenBucket {
+		if elapsed := now.Sub(b.lastRefresh); elapsed > 0 {
+			b.tokens += float64(r.size) * float64(elapsed) / float64(r.period)
+			if b.tokens > float64(r.capacity) {
+				b.tokens = float64(r.capacity)
+			}
+			b.lastRefresh = now
+		}
+		if b.tokens >= 1 {
+			b.tokens--
+			return 0
+		}
+		wait := time.Duration((1 - b.tokens) * float64(r.period) / float64(r.size))
+		if wait <= 0 {
+			wait = 1
+		}
+		return wait
+	}
+
+	b.tokens--
+	if b.tokens < 0 {
+		b.tokens = 0
+		remaining := r.period - now.Sub(b.lastRefresh)
+
+		if remaining > 0 {
+			return remaining
+		}
+		b.tokens = float64(r.size - 1)
+		b.lastRefresh = now
+	}
+	return 0
 }
 
 func (r *localRatelimit) Access(ctx context.Context) (time.Duration, error) {
 	r.mut.Lock()
-	r.bucket--
+	defer r.mut.Unlock()
+	return r.access(&r.shared, time.Now()), nil
+}
+
+// AccessKey accesses a limit that is tracked separately for each key.
+func (r *localRatelimit) AccessKey(ctx context.Context, key string) (time.Duration, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
 
-	if r.bucket < 0 {
-		r.bucket = 0
-		remaining := r.period - time.Since(r.lastRefresh)
+	now := time.Now()
+	refill := r.refillPeriod()
 
-		if remaining > 0 {
-			r.mut.Unlock()
-			return remaining, nil
+	// Any key that has been idle for an entire refill period is equivalent to
+	// a new key and can therefore be dropped.
+	if now.Sub(r.lastSweep) >= refill {
+		for k, b := range r.keyed {
+			if now.Sub(b.lastRefresh) >= refill {
+				delete(r.keyed, k)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	b, exists := r.keyed[key]
+	if !exists {
+		b = &localBucket{
+			tokens:      float64(r.capacity),
+			lastRefresh: now,
 		}
-		r.bucket = r.size - 1
-		r.lastRefresh = time.Now()
+		r.keyed[key] = b
 	}
-	r.mut.Unlock()
-	return 0, nil
+	return r.access(b, now), nil
 }
 
 func (r *localRatelimit) Close(ctx context.Context) error {
@@ -89,6 +89,101 @@ interval: 10ms
 	}
 }
 
+func TestLocalRateLimitTokenBucket(t *testing.T) {
+	conf, err := localRatelimitConfig().ParseYAML(`
+count: 10
+interval: 1s
+algorithm: token_bucket
+burst: 5
+`, nil)
+	require.NoError(t, err)
+
+	rl, err := newLocalRatelimitFromConfig(conf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		period, _ := rl.Access(ctx)
+		assert.Equal(t, time.Duration(0), period, "access %v", i)
+	}
+
+	// Tokens are refilled at a rate of one every 100ms.
+	period, _ := rl.Access(ctx)
+	assert.Greater(t, int64(period), int64(0))
+	assert.LessOrEqual(t, int64(period), int64(time.Millisecond*100))
+
+	<-time.After(time.Millisecond * 150)
+
+	period, _ = rl.Access(ctx)
+	assert.Equal(t, time.Duration(0), period)
+
+	period, _ = rl.Access(ctx)
+	assert.Greater(t, int64(period), int64(0))
+}
+
+func TestLocalRateLimitTokenBucketConfErrors(t *testing.T) {
+	conf, err := localRatelimitConfig().ParseYAML(`
+algorithm: token_bucket
+burst: -1
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newLocalRatelimitFromConfig(conf)
+	require.Error(t, err)
+
+	conf, err = localRatelimitConfig().ParseYAML(`
+algorithm: token_bucket
+interval: 0s
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newLocalRatelimitFromConfig(conf)
+	require.Error(t, err)
+}
+
+func TestLocalRateLimitKeyed(t *testing.T) {
+	for _, algorithm := range []string{lrlAlgorithmFixedWindow, lrlAlgorithmTokenBucket} {
+		algorithm := algorithm
+		t.Run(algorithm, func(t *testing.T) {
+			conf, err := localRatelimitConfig().ParseYAML(`
+count: 3
+interval: 10ms
+algorithm: `+algorithm+`
+`, nil)
+			require.NoError(t, err)
+
+			rl, err := newLocalRatelimitFromConfig(conf)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+
+			for _, key := range []string{"foo", "bar"} {
+				for i := 0; i < 3; i++ {
+					period, _ := rl.AccessKey(ctx, key)
+					assert.Equal(t, time.Duration(0), period, "key %v access %v", key, i)
+				}
+				period, _ := rl.AccessKey(ctx, key)
+				assert.Greater(t, int64(period), int64(0), key)
+			}
+
+			// The shared limit is unaffected by keyed access.
+			period, _ := rl.Access(ctx)
+			assert.Equal(t, time.Duration(0), period)
+
+			<-time.After(time.Millisecond * 25)
+
+			// Idle keys are removed on the next keyed access.
+			period, _ = rl.AccessKey(ctx, "baz")
+			assert.Equal(t, time.Duration(0), period)
+
+			rl.mut.Lock()
+			assert.Len(t, rl.keyed, 1)
+			rl.mut.Unlock()
+		})
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func BenchmarkRateLimit(b *testing.B) {
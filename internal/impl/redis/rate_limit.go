@@ -0,0 +1,230 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rrlAlgorithmTokenBucket   = "token_bucket"
+	rrlAlgorithmSlidingWindow = "sliding_window"
+)
+
+func redisRatelimitConfig() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Version("4.5.0").
+		Summary(`A rate limit that coordinates access across any number of Benthos instances by storing its state within a Redis server.`).
+		Description(`
+Each instance of Benthos that shares the same ` + "`key`" + ` on a Redis server shares the same limit of ` + "`count`" + ` requests per ` + "`interval`" + `. The state of the limit is read and modified atomically with Lua scripts, and the time used for calculations is taken from the Redis server in order to avoid drift between the clocks of Benthos instances.
+
+### Algorithms
+
+The ` + "`token_bucket`" + ` algorithm refills a bucket of size ` + "`count`" + ` continuously over the ` + "`interval`" + `, which smooths traffic evenly rather than allowing bursts at the boundaries of each interval.
+
+The ` + "`sliding_window`" + ` algorithm records each request within a sorted set, and permits a request only when fewer than ` + "`count`" + ` requests have been made within the last ` + "`interval`" + `. This is more precise than ` + "`token_bucket`" + ` but consumes memory within Redis proportional to ` + "`count`" + `.`)
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewStringField("key").
+			Description("The key to store the rate limit state under. All instances of this rate limit using the same key share the same limit.").
+			Example("benthos_rate_limit")).
+		Field(service.NewIntField("count").
+			Description("The maximum number of requests to allow for a given period of time.").
+			Default(1000)).
+		Field(service.NewDurationField("interval").
+			Description("The time window to limit requests by.").
+			Default("1s")).
+		Field(service.NewStringAnnotatedEnumField("algorithm", map[string]string{
+			rrlAlgorithmTokenBucket:   "Refill a bucket of tokens at a steady rate.",
+			rrlAlgorithmSlidingWindow: "Count requests within a sliding window of time.",
+		}).
+			Description("The algorithm used to enforce the limit.").
+			Default(rrlAlgorithmTokenBucket).
+			Advanced())
+}
+
+func init() {
+	err := service.RegisterRateLimit(
+		"redis", redisRatelimitConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.RateLimit, error) {
+			return newRedisRatelimitFromConfig(conf)
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newRedisRatelimitFromConfig(conf *service.ParsedConfig) (*redisRatelimit, error) {
+	client, err := getClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := conf.FieldString("key")
+	if err != nil {
+		return nil, err
+	}
+	count, err := conf.FieldInt("count")
+	if err != nil {
+		return nil, err
+	}
+	interval, err := conf.FieldDuration("interval")
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := conf.FieldString("algorithm")
+	if err != nil {
+		return nil, err
+	}
+	return newRedisRatelimit(client, key, count, interval, algorithm)
+}
+
+//------------------------------------------------------------------------------
+
+// Both scripts return the number of milliseconds to wait before the next
+// access attempt, where zero means that access is granted.
+
+const rrlTokenBucketScript = `
+redis.replicate_commands()
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call("HMGET", key, "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+
+local elapsed = math.max(0, now - ts)
+tokens = math.min(capacity, tokens + (elapsed * capacity / interval))
+
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+else
+  wait = math.max(1, math.ceil((1 - tokens) * interval / capacity))
+end
+
+redis.call("HMSET", key, "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", key, interval * 2)
+return wait
+`
+
+const rrlSlidingWindowScript = `
+redis.replicate_commands()
+local key = KEYS[1]
+local count = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local member = ARGV[3]
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call("ZREMRANGEBYSCORE", key, "-inf", now - interval)
+if redis.call("ZCARD", key) < count then
+  redis.call("ZADD", key, now, member)
+  redis.call("PEXPIRE", key, interval)
+  return 0
+end
+
+local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+return math.max(1, tonumber(oldest[2]) + interval - now)
+`
+
+type redisRatelimit struct {
+	// Used for generating unique sliding window members, kept first for
+	// alignment of atomic operations. The instance ID is random so that the
+	// members of separate instances sharing a key never collide.
+	seq        uint64
+	instanceID string
+
+	client        redis.UniversalClient
+	key           string
+	count         int
+	intervalMS    int64
+	slidingWindow bool
+	script        string
+	scriptHash    string
+}
+
+func newRedisRatelimit(client redis.UniversalClient, key string, count int, interval time.Duration, algorithm string) (*redisRatelimit, error) {
+	if key == "" {
+		return nil, errors.New("a key must be specified")
+	}
+	if count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	intervalMS := interval.Milliseconds()
+	if intervalMS <= 0 {
+		return nil, errors.New("interval must be at least one millisecond")
+	}
+
+	instanceID, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &redisRatelimit{
+		client:     client,
+		key:        key,
+		count:      count,
+		intervalMS: intervalMS,
+		instanceID: instanceID.String(),
+	}
+
+	switch algorithm {
+	case rrlAlgorithmTokenBucket:
+		r.script = rrlTokenBucketScript
+	case rrlAlgorithmSlidingWindow:
+		r.script = rrlSlidingWindowScript
+		r.slidingWindow = true
+	default:
+		return nil, fmt.Errorf("algorithm '%v' was not recognised", algorithm)
+	}
+	r.scriptHash = redis.NewScript(r.script).Hash()
+	return r, nil
+}
+
+func (r *redisRatelimit) Access(ctx context.Context) (time.Duration, error) {
+	args := []interface{}{r.key, r.count, r.intervalMS}
+	if r.slidingWindow {
+		args = append(args, fmt.Sprintf("%v-%x", r.instanceID, atomic.AddUint64(&r.seq, 1)))
+	}
+
+	// Optimistically run the cached script and only send the full script
+	// when the server doesn't have it yet, which is what redis.Script.Run
+	// does but with the context of the caller.
+	res := r.client.DoContext(ctx, append([]interface{}{"evalsha", r.scriptHash, 1}, args...)...)
+	if err := res.Err(); err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT ") {
+		res = r.client.DoContext(ctx, append([]interface{}{"eval", r.script, 1}, args...)...)
+	}
+
+	waitMS, err := res.Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(waitMS) * time.Millisecond, nil
+}
+
+func (r *redisRatelimit) Close(ctx context.Context) error {
+	return r.client.Close()
+}
@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/integration"
+)
+
+func TestIntegrationRedisRateLimit(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+
+	resource, err := pool.Run("redis", "latest", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+
+	url := fmt.Sprintf("tcp://localhost:%v/1", resource.GetPort("6379/tcp"))
+	confStr := func(key, algorithm string) string {
+		return fmt.Sprintf(`
+url: %v
+key: %v
+count: 5
+interval: 1s
+algorithm: %v
+`, url, key, algorithm)
+	}
+
+	require.NoError(t, pool.Retry(func() error {
+		pConf, cErr := redisRatelimitConfig().ParseYAML(confStr("benthos_test_redis_connect", rrlAlgorithmTokenBucket), nil)
+		if cErr != nil {
+			return cErr
+		}
+
+		r, cErr := newRedisRatelimitFromConfig(pConf)
+		if cErr != nil {
+			return cErr
+		}
+		defer r.Close(context.Background())
+
+		_, cErr = r.Access(context.Background())
+		return cErr
+	}))
+
+	newRateLimit := func(t *testing.T, key, algorithm string) *redisRatelimit {
+		t.Helper()
+
+		pConf, err := redisRatelimitConfig().ParseYAML(confStr(key, algorithm), nil)
+		require.NoError(t, err)
+
+		r, err := newRedisRatelimitFromConfig(pConf)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = r.Close(context.Background())
+		})
+		return r
+	}
+
+	for _, algorithm := range []string{rrlAlgorithmTokenBucket, rrlAlgorithmSlidingWindow} {
+		algorithm := algorithm
+		t.Run(algorithm, func(t *testing.T) {
+			ctx := context.Background()
+			key := "benthos_test_" + algorithm
+
+			// Two instances sharing a key should share a single limit.
+			rlOne, rlTwo := newRateLimit(t, key, algorithm), newRateLimit(t, key, algorithm)
+
+			for i := 0; i < 5; i++ {
+				rl := rlOne
+				if i%2 == 1 {
+					rl = rlTwo
+				}
+				period, err := rl.Access(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, time.Duration(0), period, "access %v", i)
+			}
+
+			period, err := rlOne.Access(ctx)
+			require.NoError(t, err)
+			assert.Greater(t, int64(period), int64(0))
+			assert.LessOrEqual(t, int64(period), int64(time.Second))
+
+			period, err = rlTwo.Access(ctx)
+			require.NoError(t, err)
+			assert.Greater(t, int64(period), int64(0))
+
+			<-time.After(time.Second)
+
+			period, err = rlTwo.Access(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, time.Duration(0), period)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMiniredisRateLimit(t *testing.T, s *miniredis.Miniredis, count int, interval time.Duration, algorithm string) *redisRatelimit {
+	t.Helper()
+
+	r, err := newRedisRatelimit(redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	}), "foo", count, interval, algorithm)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = r.Close(context.Background())
+	})
+	return r
+}
+
+func assertAccess(t *testing.T, r *redisRatelimit, expected time.Duration, msgAndArgs ...interface{}) {
+	t.Helper()
+
+	period, err := r.Access(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, period, msgAndArgs...)
+}
+
+func TestRedisRateLimitTokenBucket(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	now := time.Unix(1000, 0)
+	s.SetTime(now)
+
+	rl := newMiniredisRateLimit(t, s, 5, time.Second, rrlAlgorithmTokenBucket)
+
+	// A full bucket allows a burst of count accesses.
+	for i := 0; i < 5; i++ {
+		assertAccess(t, rl, 0, "access %v", i)
+	}
+
+	// An empty bucket waits for a single token to refill.
+	assertAccess(t, rl, time.Millisecond*200)
+
+	// Tokens refill continuously over the interval.
+	now = now.Add(time.Millisecond * 400)
+	s.SetTime(now)
+	assertAccess(t, rl, 0)
+	assertAccess(t, rl, 0)
+	assertAccess(t, rl, time.Millisecond*200)
+
+	now = now.Add(time.Millisecond * 100)
+	s.SetTime(now)
+	assertAccess(t, rl, time.Millisecond*100)
+
+	// The bucket never refills beyond its capacity.
+	now = now.Add(time.Second * 10)
+	s.SetTime(now)
+	for i := 0; i < 5; i++ {
+		assertAccess(t, rl, 0, "access %v", i)
+	}
+	assertAccess(t, rl, time.Millisecond*200)
+
+	// The state expires once it would have fully refilled anyway.
+	assert.True(t, s.Exists("foo"))
+	s.FastForward(time.Second * 2)
+	assert.False(t, s.Exists("foo"))
+}
+
+func TestRedisRateLimitSlidingWindow(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	now := time.Unix(1000, 0)
+	s.SetTime(now)
+
+	rl := newMiniredisRateLimit(t, s, 5, time.Second, rrlAlgorithmSlidingWindow)
+
+	for i := 0; i < 3; i++ {
+		assertAccess(t, rl, 0, "access %v", i)
+	}
+
+	now = now.Add(time.Millisecond * 500)
+	s.SetTime(now)
+	assertAccess(t, rl, 0)
+	assertAccess(t, rl, 0)
+
+	// The window is full until the oldest access leaves it.
+	assertAccess(t, rl, time.Millisecond*500)
+
+	now = now.Add(time.Millisecond * 200)
+	s.SetTime(now)
+	assertAccess(t, rl, time.Millisecond*300)
+
+	// Only the accesses older than the interval leave the window.
+	now = now.Add(time.Millisecond * 300)
+	s.SetTime(now)
+	for i := 0; i < 3; i++ {
+		assertAccess(t, rl, 0, "access %v", i)
+	}
+	assertAccess(t, rl, time.Millisecond*500)
+
+	// The state expires once all accesses have left the window.
+	assert.True(t, s.Exists("foo"))
+	s.FastForward(time.Second)
+	assert.False(t, s.Exists("foo"))
+}
+
+func TestRedisRateLimitShared(t *testing.T) {
+	for algorithm, wait := range map[string]time.Duration{
+		rrlAlgorithmTokenBucket:   time.Millisecond * 250,
+		rrlAlgorithmSlidingWindow: time.Second,
+	} {
+		algorithm, wait := algorithm, wait
+		t.Run(algorithm, func(t *testing.T) {
+			s := miniredis.RunT(t)
+			s.SetTime(time.Unix(1000, 0))
+
+			rlOne := newMiniredisRateLimit(t, s, 4, time.Second, algorithm)
+			rlTwo := newMiniredisRateLimit(t, s, 4, time.Second, algorithm)
+			assert.NotEqual(t, rlOne.instanceID, rlTwo.instanceID)
+
+			for i := 0; i < 4; i++ {
+				rl := rlOne
+				if i%2 == 1 {
+					rl = rlTwo
+				}
+				assertAccess(t, rl, 0, "access %v", i)
+			}
+			assertAccess(t, rlOne, wait)
+			assertAccess(t, rlTwo, wait)
+		})
+	}
+}
+
+func TestRedisRateLimitContext(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	rl := newMiniredisRateLimit(t, s, 5, time.Second, rrlAlgorithmTokenBucket)
+
+	ctx, done := context.WithCancel(context.Background())
+	done()
+
+	_, err := rl.Access(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRedisRateLimitConfigErrors(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+
+	_, err := newRedisRatelimit(client, "", 5, time.Second, rrlAlgorithmTokenBucket)
+	assert.EqualError(t, err, "a key must be specified")
+
+	_, err = newRedisRatelimit(client, "foo", 0, time.Second, rrlAlgorithmTokenBucket)
+	assert.EqualError(t, err, "count must be larger than zero")
+
+	_, err = newRedisRatelimit(client, "foo", 5, time.Microsecond, rrlAlgorithmTokenBucket)
+	assert.EqualError(t, err, "interval must be at least one millisecond")
+
+	_, err = newRedisRatelimit(client, "foo", 5, time.Second, "meow")
+	assert.EqualError(t, err, "algorithm 'meow' was not recognised")
+}
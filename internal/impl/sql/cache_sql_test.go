@@ -0,0 +1,264 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "modernc.org/sqlite"
+)
+
+func testSQLiteCache(t *testing.T, extraConf string) (*sqlCache, *sql.DB) {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%v/cache.db?_pragma=busy_timeout(5000)", t.TempDir())
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	_, err = db.Exec(`create table footable (
+  foo_key varchar(50) not null,
+  foo_value blob not null,
+  foo_expires integer,
+  primary key (foo_key)
+);`)
+	require.NoError(t, err)
+
+	conf, err := sqlCacheConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+table: footable
+key_column: foo_key
+value_column: foo_value
+%v
+`, dsn, extraConf), service.NewEnvironment())
+	require.NoError(t, err)
+
+	c, err := newSQLCacheFromConfig(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close(context.Background())
+	})
+	return c, db
+}
+
+func TestSQLCacheBasic(t *testing.T) {
+	c, _ := testSQLiteCache(t, "")
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("first"), nil))
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(v))
+
+	require.NoError(t, c.Set(ctx, "foo", []byte("second"), nil))
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(v))
+
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("third"), nil))
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(v))
+
+	require.NoError(t, c.Add(ctx, "bar", []byte("fourth"), nil))
+	v, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "fourth", string(v))
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	require.NoError(t, c.SetMulti(ctx,
+		service.CacheItem{Key: "foo", Value: []byte("fifth")},
+		service.CacheItem{Key: "bar", Value: []byte("sixth")},
+		service.CacheItem{Key: "foo", Value: []byte("seventh")},
+	))
+	v, err = c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "seventh", string(v))
+	v, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "sixth", string(v))
+}
+
+func TestSQLCacheTTL(t *testing.T) {
+	c, db := testSQLiteCache(t, `
+expires_column: foo_expires
+cleanup_interval: 0s
+`)
+	ctx := context.Background()
+
+	now := time.Unix(1000, 0)
+	c.nowFn = func() time.Time {
+		return now
+	}
+
+	ttl := time.Minute
+	require.NoError(t, c.Set(ctx, "foo", []byte("first"), &ttl))
+	require.NoError(t, c.Add(ctx, "bar", []byte("second"), &ttl))
+	require.NoError(t, c.Set(ctx, "baz", []byte("third"), nil))
+
+	var expires int64
+	require.NoError(t, db.QueryRow(`select foo_expires from footable where foo_key = 'foo'`).Scan(&expires))
+	assert.Equal(t, int64(1060000), expires)
+
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(v))
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "bar", []byte("fourth"), nil))
+
+	now = now.Add(time.Minute * 2)
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, service.ErrKeyNotFound, err)
+
+	v, err = c.Get(ctx, "baz")
+	require.NoError(t, err)
+	assert.Equal(t, "third", string(v))
+
+	// Adding an item replaces an expired row.
+	require.NoError(t, c.Add(ctx, "bar", []byte("fifth"), nil))
+	v, err = c.Get(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "fifth", string(v))
+
+	require.NoError(t, c.deleteExpired(ctx))
+
+	var keys []string
+	rows, err := db.Query(`select foo_key from footable order by foo_key`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var k string
+		require.NoError(t, rows.Scan(&k))
+		keys = append(keys, k)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"bar", "baz"}, keys)
+}
+
+func TestSQLCacheDefaultTTL(t *testing.T) {
+	c, db := testSQLiteCache(t, `
+expires_column: foo_expires
+default_ttl: 1h
+`)
+	ctx := context.Background()
+
+	c.nowFn = func() time.Time {
+		return time.Unix(1000, 0)
+	}
+
+	ttl := time.Second
+	require.NoError(t, c.SetMulti(ctx,
+		service.CacheItem{Key: "foo", Value: []byte("first")},
+		service.CacheItem{Key: "bar", Value: []byte("second"), TTL: &ttl},
+	))
+
+	var expires int64
+	require.NoError(t, db.QueryRow(`select foo_expires from footable where foo_key = 'foo'`).Scan(&expires))
+	assert.Equal(t, int64(4600000), expires)
+
+	require.NoError(t, db.QueryRow(`select foo_expires from footable where foo_key = 'bar'`).Scan(&expires))
+	assert.Equal(t, int64(1001000), expires)
+}
+
+func TestSQLCacheAddSnowflake(t *testing.T) {
+	c := &sqlCache{
+		driver:        "snowflake",
+		table:         "footable",
+		keyColumn:     "foo_key",
+		valueColumn:   "foo_value",
+		expiresColumn: "foo_expires",
+		nowFn: func() time.Time {
+			return time.Unix(1000, 0)
+		},
+	}
+
+	var err error
+	c.db, err = sql.Open("benthos_test_recording", "")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.db.Close()
+	})
+
+	t.Cleanup(func() {
+		testRecordingDriver.setRowsAffected(1)
+	})
+
+	ctx := context.Background()
+	ttl := time.Second
+
+	testRecordingDriver.reset()
+	require.NoError(t, c.Add(ctx, "foo", []byte("first"), &ttl))
+
+	// A row with the same key that has not expired is left untouched by the
+	// merge.
+	testRecordingDriver.setRowsAffected(0)
+	assert.Equal(t, service.ErrKeyAlreadyExists, c.Add(ctx, "foo", []byte("second"), nil))
+
+	merge := "MERGE INTO footable AS target USING (SELECT ? AS foo_key, ? AS foo_value, ? AS foo_expires) AS source ON (target.foo_key = source.foo_key) WHEN NOT MATCHED THEN INSERT (foo_key, foo_value, foo_expires) VALUES (source.foo_key, source.foo_value, source.foo_expires)"
+	assert.Equal(t, []string{
+		"DELETE FROM footable WHERE foo_key = ? AND foo_expires <= ? [foo 1000000]",
+		merge + " [foo [102 105 114 115 116] 1001000]",
+		"DELETE FROM footable WHERE foo_key = ? AND foo_expires <= ? [foo 1000000]",
+		merge + " [foo [115 101 99 111 110 100] <nil>]",
+	}, testRecordingDriver.reset())
+}
+
+func TestSQLCacheSetMerge(t *testing.T) {
+	for driver, merge := range map[string]string{
+		"mssql":     "MERGE INTO footable WITH (HOLDLOCK) AS target USING (SELECT ? AS foo_key, ? AS foo_value, ? AS foo_expires) AS source ON (target.foo_key = source.foo_key) WHEN MATCHED THEN UPDATE SET foo_value = source.foo_value, foo_expires = source.foo_expires WHEN NOT MATCHED THEN INSERT (foo_key, foo_value, foo_expires) VALUES (source.foo_key, source.foo_value, source.foo_expires);",
+		"oracle":    "MERGE INTO footable target USING (SELECT :1 AS foo_key, :2 AS foo_value, :3 AS foo_expires FROM dual) source ON (target.foo_key = source.foo_key) WHEN MATCHED THEN UPDATE SET foo_value = source.foo_value, foo_expires = source.foo_expires WHEN NOT MATCHED THEN INSERT (foo_key, foo_value, foo_expires) VALUES (source.foo_key, source.foo_value, source.foo_expires)",
+		"snowflake": "MERGE INTO footable AS target USING (SELECT ? AS foo_key, ? AS foo_value, ? AS foo_expires) AS source ON (target.foo_key = source.foo_key) WHEN MATCHED THEN UPDATE SET foo_value = source.foo_value, foo_expires = source.foo_expires WHEN NOT MATCHED THEN INSERT (foo_key, foo_value, foo_expires) VALUES (source.foo_key, source.foo_value, source.foo_expires)",
+	} {
+		driver, merge := driver, merge
+		t.Run(driver, func(t *testing.T) {
+			c := &sqlCache{
+				driver:        driver,
+				table:         "footable",
+				keyColumn:     "foo_key",
+				valueColumn:   "foo_value",
+				expiresColumn: "foo_expires",
+				nowFn: func() time.Time {
+					return time.Unix(1000, 0)
+				},
+			}
+
+			var err error
+			c.db, err = sql.Open("benthos_test_recording", "")
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = c.db.Close()
+			})
+
+			ttl := time.Second
+
+			testRecordingDriver.reset()
+			require.NoError(t, c.SetMulti(context.Background(),
+				service.CacheItem{Key: "foo", Value: []byte("first"), TTL: &ttl},
+				service.CacheItem{Key: "bar", Value: []byte("second")},
+			))
+
+			assert.Equal(t, []string{
+				"BEGIN",
+				merge + " [foo [102 105 114 115 116] 1001000]",
+				merge + " [bar [115 101 99 111 110 100] <nil>]",
+				"COMMIT",
+			}, testRecordingDriver.reset())
+		})
+	}
+}
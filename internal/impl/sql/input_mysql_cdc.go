@@ -0,0 +1,699 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func mysqlCDCInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		// Stable(). TODO
+		Categories("Services").
+		Summary("Consumes the row changes of a MySQL database by reading its binary log as a replica, creating a message for each row inserted, updated or deleted.").
+		Description(`
+The server must be configured with `+"`binlog_format=ROW`"+`, which is the default since MySQL 5.7.7, and the user must have the `+"`REPLICATION SLAVE`"+` and `+"`REPLICATION CLIENT`"+` privileges. Column names are read from the binary log when the server is configured with `+"`binlog_row_metadata=FULL`"+` (MySQL 8.0.1 and later), otherwise they are queried from `+"`information_schema`"+`, in which case changes written before a schema change may be mapped to the wrong columns.
+
+Each message is a JSON object describing a single row change of the form:
+
+`+"```json"+`
+{
+  "operation": "update",
+  "schema": "inventory",
+  "table": "orders",
+  "before": { "id": 1, "status": "pending" },
+  "after": { "id": 1, "status": "shipped" }
+}
+`+"```"+`
+
+Where the `+"`operation`"+` is one of `+"`insert`, `update` or `delete`"+`, and the `+"`before` and `after`"+` fields are omitted for inserts and deletes respectively. Changes that precede the starting position of this input, such as the existing rows of tables, are not emitted.
+
+### Checkpoints
+
+The binary log position is stored within a [cache resource](/docs/components/caches/about) under the key `+"`checkpoint_key`"+` once all changes of a transaction and of all prior transactions have been acknowledged, and when this input starts it resumes from the stored position. When no position is stored the input starts from the current position of the server. When `+"`use_gtid`"+` is enabled the position is stored as a GTID set, otherwise it is stored as a binary log file name and offset in the form `+"`mysql-bin.000003:1337`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- operation
+- schema
+- table
+- binlog_file
+- binlog_position
+- gtid_set
+`+"```"+`
+
+Where the binary log position and GTID set are those of the commit of the transaction that the change belongs to, and `+"`gtid_set`"+` is only added when `+"`use_gtid`"+` is enabled.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`).
+		Field(service.NewStringField("dsn").
+			Description("A Data Source Name to identify the target database, in the same format as the `mysql` driver of the [`sql_select`](/docs/components/inputs/sql_select#dsn) input: `[username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]`. The database name is used as the schema of tables listed without one.").
+			Example("foouser:foopassword@tcp(localhost:3306)/foodb")).
+		Field(service.NewStringListField("tables").
+			Description("An optional list of tables to emit the changes of, where tables without a schema prefix belong to the database of the DSN. When empty the changes of all tables are emitted.").
+			Default([]string{}).
+			Example([]string{"foo", "inventory.bar"})).
+		Field(service.NewStringField("checkpoint_cache").
+			Description("A [cache resource](/docs/components/caches/about) to store the binary log position in.")).
+		Field(service.NewStringField("checkpoint_key").
+			Description("The key to store the binary log position under within the checkpoint cache.").
+			Default("mysql_binlog_position").
+			Advanced()).
+		Field(service.NewBoolField("use_gtid").
+			Description("Whether to track the position of the binary log with global transaction identifiers, which requires GTIDs to be enabled on the server.").
+			Default(false)).
+		Field(service.NewStringEnumField("flavor", gomysql.MySQLFlavor, gomysql.MariaDBFlavor).
+			Description("The flavor of the server.").
+			Default(gomysql.MySQLFlavor).
+			Advanced()).
+		Field(service.NewIntField("server_id").
+			Description("The server ID to register as a replica with, which must be unique amongst the replicas of the server. When set to zero a random ID is used.").
+			Default(0).
+			Advanced()).
+		Field(service.NewIntField("checkpoint_limit").
+			Description("The maximum number of messages that can be pending acknowledgement before applying back pressure. Increasing this limit enables parallel processing and batching at the output level, but increases the number of changes that are consumed again after a restart.").
+			Default(1024).
+			Advanced()).
+		Version("4.5.0").
+		Example("Capture Changes",
+			`
+Here we consume the changes of a table, storing the binary log position in a Redis cache, and write them to a Kafka topic keyed by the primary key of the row:`,
+			`
+input:
+  mysql_cdc:
+    dsn: foouser:foopassword@tcp(localhost:3306)/foodb
+    tables: [ orders ]
+    checkpoint_cache: binlog_positions
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders_changes
+    key: '${! json("after.id").or(json("before.id")) }'
+
+cache_resources:
+  - label: binlog_positions
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"mysql_cdc", mysqlCDCInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newMySQLCDCInputFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// binlogPosition is a position within the binary log of a server, where gtid
+// is only set when GTIDs are used.
+type binlogPosition struct {
+	file string
+	pos  uint32
+	gtid string
+}
+
+func parseBinlogPosition(s string, useGTID bool) (p binlogPosition, err error) {
+	if useGTID {
+		p.gtid = s
+		return
+	}
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return p, fmt.Errorf("expected binlog position in the form <file>:<offset>, got '%v'", s)
+	}
+	pos, err := strconv.ParseUint(s[i+1:], 10, 32)
+	if err != nil {
+		return p, fmt.Errorf("failed to parse binlog offset of '%v': %w", s, err)
+	}
+	p.file, p.pos = s[:i], uint32(pos)
+	return
+}
+
+func (p binlogPosition) String() string {
+	if p.gtid != "" {
+		return p.gtid
+	}
+	return fmt.Sprintf("%v:%v", p.file, p.pos)
+}
+
+type pendingMySQLChange struct {
+	change   cdcChange
+	position binlogPosition
+	tracker  *cdcTransactionTracker
+}
+
+type mysqlCDCInput struct {
+	dsn       string
+	dsnConf   *mysql.Config
+	tables    map[string]struct{}
+	cacheName string
+	cacheKey  string
+	useGTID   bool
+	flavor    string
+	serverID  uint32
+	mgr       *service.Resources
+	cp        *checkpoint.Capped
+
+	connMut  sync.Mutex
+	db       *sql.DB
+	syncer   *replication.BinlogSyncer
+	streamer *replication.BinlogStreamer
+
+	// The position of the last transaction queued, which is where the input
+	// resumes from when reconnecting.
+	position *binlogPosition
+	file     string
+	gtid     string
+	columns  map[string][]string
+	txn      []cdcChange
+	inTxn    bool
+	pending  []pendingMySQLChange
+
+	logger *service.Logger
+}
+
+func newMySQLCDCInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*mysqlCDCInput, error) {
+	m := &mysqlCDCInput{
+		tables:  map[string]struct{}{},
+		columns: map[string][]string{},
+		mgr:     mgr,
+		logger:  mgr.Logger(),
+	}
+
+	var err error
+	if m.dsn, err = conf.FieldString("dsn"); err != nil {
+		return nil, err
+	}
+	if m.dsnConf, err = mysql.ParseDSN(m.dsn); err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
+	}
+
+	tables, err := conf.FieldStringList("tables")
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		if !strings.Contains(t, ".") {
+			t = m.dsnConf.DBName + "." + t
+		}
+		m.tables[t] = struct{}{}
+	}
+
+	if m.cacheName, err = conf.FieldString("checkpoint_cache"); err != nil {
+		return nil, err
+	}
+	if m.cacheKey, err = conf.FieldString("checkpoint_key"); err != nil {
+		return nil, err
+	}
+	if m.useGTID, err = conf.FieldBool("use_gtid"); err != nil {
+		return nil, err
+	}
+	if m.flavor, err = conf.FieldString("flavor"); err != nil {
+		return nil, err
+	}
+
+	serverID, err := conf.FieldInt("server_id")
+	if err != nil {
+		return nil, err
+	}
+	if serverID < 0 {
+		return nil, fmt.Errorf("server_id must not be negative, got %v", serverID)
+	}
+	if m.serverID = uint32(serverID); m.serverID == 0 {
+		m.serverID = 1000 + uint32(rand.New(rand.NewSource(time.Now().UnixNano())).Int31n(1<<30))
+	}
+
+	checkpointLimit, err := conf.FieldInt("checkpoint_limit")
+	if err != nil {
+		return nil, err
+	}
+	m.cp = checkpoint.NewCapped(int64(checkpointLimit))
+
+	if !mgr.HasCache(m.cacheName) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", m.cacheName)
+	}
+	return m, nil
+}
+
+func (m *mysqlCDCInput) readCheckpoint(ctx context.Context) (*binlogPosition, error) {
+	var data []byte
+	var err error
+	if cerr := m.mgr.AccessCache(ctx, m.cacheName, func(c service.Cache) {
+		data, err = c.Get(ctx, m.cacheKey)
+	}); cerr != nil {
+		return nil, fmt.Errorf("unable to access cache '%v': %w", m.cacheName, cerr)
+	}
+	if errors.Is(err, service.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pos, err := parseBinlogPosition(string(data), m.useGTID)
+	if err != nil {
+		return nil, err
+	}
+	return &pos, nil
+}
+
+func (m *mysqlCDCInput) writeCheckpoint(ctx context.Context, pos binlogPosition) error {
+	var err error
+	if cerr := m.mgr.AccessCache(ctx, m.cacheName, func(c service.Cache) {
+		err = c.Set(ctx, m.cacheKey, []byte(pos.String()), nil)
+	}); cerr != nil {
+		return fmt.Errorf("unable to access cache '%v': %w", m.cacheName, cerr)
+	}
+	return err
+}
+
+// currentPosition returns the current position of the binary log of the
+// server.
+func (m *mysqlCDCInput) currentPosition(ctx context.Context, db *sql.DB) (pos binlogPosition, err error) {
+	if m.useGTID && m.flavor == gomysql.MariaDBFlavor {
+		err = db.QueryRowContext(ctx, "SELECT @@gtid_current_pos").Scan(&pos.gtid)
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		// The statement was renamed in MySQL 8.4.
+		if rows, err = db.QueryContext(ctx, "SHOW BINARY LOG STATUS"); err != nil {
+			return pos, fmt.Errorf("failed to query binlog status: %w", err)
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = errors.New("binary logging is not enabled on the server")
+		}
+		return
+	}
+
+	status, err := sqlRowToMap(rows)
+	if err != nil {
+		return
+	}
+	pos.file, _ = status["File"].(string)
+	position, err := strconv.ParseUint(fmt.Sprintf("%v", status["Position"]), 10, 32)
+	if err != nil {
+		return pos, fmt.Errorf("failed to parse binlog position: %w", err)
+	}
+	pos.pos = uint32(position)
+	if m.useGTID {
+		pos.gtid, _ = status["Executed_Gtid_Set"].(string)
+	}
+	return
+}
+
+func (m *mysqlCDCInput) Connect(ctx context.Context) (err error) {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.syncer != nil {
+		return nil
+	}
+
+	host, portStr, err := net.SplitHostPort(m.dsnConf.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse dsn address: %w", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("failed to parse dsn port: %w", err)
+	}
+
+	var db *sql.DB
+	if db, err = sql.Open("mysql", m.dsn); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = db.Close()
+		}
+	}()
+	if err = db.PingContext(ctx); err != nil {
+		return
+	}
+
+	pos := m.position
+	if pos == nil {
+		if pos, err = m.readCheckpoint(ctx); err != nil {
+			return
+		}
+	}
+	if pos == nil {
+		var current binlogPosition
+		if current, err = m.currentPosition(ctx, db); err != nil {
+			return
+		}
+		pos = &current
+	}
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID: m.serverID,
+		Flavor:   m.flavor,
+		Host:     host,
+		Port:     uint16(port),
+		User:     m.dsnConf.User,
+		Password: m.dsnConf.Passwd,
+	})
+
+	var streamer *replication.BinlogStreamer
+	if m.useGTID {
+		var gset gomysql.GTIDSet
+		if gset, err = gomysql.ParseGTIDSet(m.flavor, pos.gtid); err != nil {
+			syncer.Close()
+			return fmt.Errorf("failed to parse GTID set: %w", err)
+		}
+		streamer, err = syncer.StartSyncGTID(gset)
+	} else {
+		streamer, err = syncer.StartSync(gomysql.Position{Name: pos.file, Pos: pos.pos})
+	}
+	if err != nil {
+		syncer.Close()
+		return fmt.Errorf("failed to start binlog sync from '%v': %w", pos, err)
+	}
+
+	m.db = db
+	m.syncer = syncer
+	m.streamer = streamer
+	m.file = pos.file
+	m.gtid = pos.gtid
+	m.txn = nil
+	m.inTxn = false
+
+	m.logger.Infof("Consuming binlog from position '%v'", pos)
+	return nil
+}
+
+func (m *mysqlCDCInput) disconnect() {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.syncer != nil {
+		m.syncer.Close()
+		m.syncer = nil
+		m.streamer = nil
+	}
+	if m.db != nil {
+		_ = m.db.Close()
+		m.db = nil
+	}
+}
+
+func (m *mysqlCDCInput) tableMatches(schema, table string) bool {
+	if len(m.tables) == 0 {
+		return true
+	}
+	_, exists := m.tables[schema+"."+table]
+	return exists
+}
+
+// columnNames returns the names of the columns of the table of a rows event,
+// which are only included within the event when the server is configured with
+// binlog_row_metadata=FULL.
+func (m *mysqlCDCInput) columnNames(ctx context.Context, table *replication.TableMapEvent) ([]string, error) {
+	if len(table.ColumnName) > 0 {
+		names := make([]string, len(table.ColumnName))
+		for i, n := range table.ColumnName {
+			names[i] = string(n)
+		}
+		return names, nil
+	}
+
+	key := string(table.Schema) + "." + string(table.Table)
+	if names, exists := m.columns[key]; exists {
+		return names, nil
+	}
+
+	m.connMut.Lock()
+	db := m.db
+	m.connMut.Unlock()
+	if db == nil {
+		return nil, service.ErrNotConnected
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		string(table.Schema), string(table.Table),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns of table %v: %w", key, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	m.columns[key] = names
+	return names, nil
+}
+
+func mysqlRowToMap(columns []string, row []interface{}) map[string]interface{} {
+	obj := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		name := fmt.Sprintf("col_%v", i)
+		if i < len(columns) {
+			name = columns[i]
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		obj[name] = v
+	}
+	return obj
+}
+
+func (m *mysqlCDCInput) addRows(ctx context.Context, eventType replication.EventType, e *replication.RowsEvent) error {
+	schema, table := string(e.Table.Schema), string(e.Table.Table)
+	if !m.tableMatches(schema, table) {
+		return nil
+	}
+
+	columns, err := m.columnNames(ctx, e.Table)
+	if err != nil {
+		return err
+	}
+
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		for _, row := range e.Rows {
+			m.txn = append(m.txn, cdcChange{
+				operation: "insert",
+				schema:    schema,
+				table:     table,
+				after:     mysqlRowToMap(columns, row),
+			})
+		}
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		// The rows of update events alternate between the before and after
+		// image of each row.
+		for i := 0; i+1 < len(e.Rows); i += 2 {
+			m.txn = append(m.txn, cdcChange{
+				operation: "update",
+				schema:    schema,
+				table:     table,
+				before:    mysqlRowToMap(columns, e.Rows[i]),
+				after:     mysqlRowToMap(columns, e.Rows[i+1]),
+			})
+		}
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		for _, row := range e.Rows {
+			m.txn = append(m.txn, cdcChange{
+				operation: "delete",
+				schema:    schema,
+				table:     table,
+				before:    mysqlRowToMap(columns, row),
+			})
+		}
+	}
+	return nil
+}
+
+func (m *mysqlCDCInput) resolve(ctx context.Context, tracker *cdcTransactionTracker) error {
+	if highest, ok := tracker.resolve().(binlogPosition); ok {
+		return m.writeCheckpoint(ctx, highest)
+	}
+	return nil
+}
+
+// commit queues the changes of the current transaction, where pos is the
+// position of the end of its commit event.
+func (m *mysqlCDCInput) commit(ctx context.Context, pos uint32, gset gomysql.GTIDSet) error {
+	if gset != nil {
+		m.gtid = gset.String()
+	}
+	position := binlogPosition{file: m.file, pos: pos}
+	if m.useGTID {
+		position.gtid = m.gtid
+	}
+
+	changes := m.txn
+	resolveFn, err := m.cp.Track(ctx, position, int64(len(changes)))
+	if err != nil {
+		return err
+	}
+	m.txn = nil
+	m.inTxn = false
+	tracker := &cdcTransactionTracker{
+		pending: int64(len(changes)),
+		resolve: resolveFn,
+	}
+	m.position = &position
+
+	if len(changes) == 0 {
+		// Transactions without changes of interest are still checkpointed in
+		// order to avoid reading them again after a restart.
+		if err := m.resolve(ctx, tracker); err != nil {
+			m.logger.Errorf("Failed to checkpoint transaction without changes: %v", err)
+		}
+		return nil
+	}
+	for _, c := range changes {
+		m.pending = append(m.pending, pendingMySQLChange{
+			change:   c,
+			position: position,
+			tracker:  tracker,
+		})
+	}
+	return nil
+}
+
+func (m *mysqlCDCInput) handleEvent(ctx context.Context, ev *replication.BinlogEvent) error {
+	switch e := ev.Event.(type) {
+	case *replication.RotateEvent:
+		m.file = string(e.NextLogName)
+	case *replication.RowsEvent:
+		return m.addRows(ctx, ev.Header.EventType, e)
+	case *replication.XIDEvent:
+		return m.commit(ctx, ev.Header.LogPos, e.GSet)
+	case *replication.QueryEvent:
+		switch strings.ToUpper(strings.TrimSpace(string(e.Query))) {
+		case "BEGIN":
+			m.txn = nil
+			m.inTxn = true
+		case "COMMIT":
+			return m.commit(ctx, ev.Header.LogPos, e.GSet)
+		case "ROLLBACK":
+			// A rollback is only logged when the transaction changed
+			// non-transactional tables, and those changes persist.
+			return m.commit(ctx, ev.Header.LogPos, e.GSet)
+		default:
+			if m.inTxn {
+				// Statements within a transaction, such as savepoints, do
+				// not end it.
+				return nil
+			}
+			// Statements such as DDL are committed on their own and may
+			// change the columns of tables.
+			m.columns = map[string][]string{}
+			return m.commit(ctx, ev.Header.LogPos, e.GSet)
+		}
+	}
+	return nil
+}
+
+func (m *mysqlCDCInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	for len(m.pending) == 0 {
+		m.connMut.Lock()
+		streamer := m.streamer
+		m.connMut.Unlock()
+
+		if streamer == nil {
+			return nil, nil, service.ErrNotConnected
+		}
+
+		ev, err := streamer.GetEvent(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			m.logger.Errorf("Failed to read binlog event: %v", err)
+			m.disconnect()
+			return nil, nil, service.ErrNotConnected
+		}
+		if err := m.handleEvent(ctx, ev); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	next := m.pending[0]
+	m.pending = m.pending[1:]
+
+	event := map[string]interface{}{
+		"operation": next.change.operation,
+		"schema":    next.change.schema,
+		"table":     next.change.table,
+	}
+	if next.change.before != nil {
+		event["before"] = next.change.before
+	}
+	if next.change.after != nil {
+		event["after"] = next.change.after
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructured(event)
+	msg.MetaSet("operation", next.change.operation)
+	msg.MetaSet("schema", next.change.schema)
+	msg.MetaSet("table", next.change.table)
+	msg.MetaSet("binlog_file", next.position.file)
+	msg.MetaSet("binlog_position", strconv.FormatUint(uint64(next.position.pos), 10))
+	if next.position.gtid != "" {
+		msg.MetaSet("gtid_set", next.position.gtid)
+	}
+
+	return msg, func(ctx context.Context, err error) error {
+		// Nacks are handled by AutoRetryNacks, and therefore the transaction
+		// is only resolved once all of its messages are delivered.
+		if atomic.AddInt64(&next.tracker.pending, -1) == 0 {
+			return m.resolve(ctx, next.tracker)
+		}
+		return nil
+	}, nil
+}
+
+func (m *mysqlCDCInput) Close(ctx context.Context) error {
+	m.disconnect()
+	return nil
+}
@@ -0,0 +1,145 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestIntegrationMySQLCDC(t *testing.T) {
+	if m := flag.Lookup("test.run").Value.String(); m == "" || regexp.MustCompile(strings.Split(m, "/")[0]).FindString(t.Name()) == "" {
+		t.Skip("Skipping as execution was not requested explicitly using go test -run ^TestIntegration$")
+	}
+	if runtime.GOOS == "darwin" {
+		t.Skip("skipping test on macos")
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = 30 * time.Second
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "mysql",
+		ExposedPorts: []string{"3306/tcp"},
+		Env: []string{
+			"MYSQL_ROOT_PASSWORD=testpass",
+			"MYSQL_DATABASE=testdb",
+		},
+		Cmd: []string{"--binlog-format=ROW", "--server-id=1"},
+	})
+	require.NoError(t, err)
+
+	var db *sql.DB
+	t.Cleanup(func() {
+		if err = pool.Purge(resource); err != nil {
+			t.Logf("Failed to clean up docker resource: %v", err)
+		}
+		if db != nil {
+			db.Close()
+		}
+	})
+
+	dsn := fmt.Sprintf("root:testpass@tcp(localhost:%v)/testdb", resource.GetPort("3306/tcp"))
+	require.NoError(t, pool.Retry(func() error {
+		if db, err = sql.Open("mysql", dsn); err != nil {
+			return err
+		}
+		if err = db.Ping(); err != nil {
+			db.Close()
+			db = nil
+			return err
+		}
+		return nil
+	}))
+
+	_, err = db.Exec(`create table footable (
+  id integer not null,
+  name varchar(50) not null,
+  primary key (id)
+);`)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`insert into footable (id, name) values (100, 'before the input started');`)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	parsed, err := mysqlCDCInputConfig().ParseYAML(fmt.Sprintf(`
+dsn: %v
+tables: [ footable ]
+checkpoint_cache: foocache
+`, dsn), service.NewEnvironment())
+	require.NoError(t, err)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	input, err := newMySQLCDCInputFromConfig(parsed, mgr)
+	require.NoError(t, err)
+	require.NoError(t, input.Connect(ctx))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec(`insert into footable (id, name) values (1, 'foo'), (2, 'bar');`)
+	require.NoError(t, err)
+	_, err = tx.Exec(`update footable set name = 'baz' where id = 2;`)
+	require.NoError(t, err)
+	_, err = tx.Exec(`delete from footable where id = 1;`)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	var bodies []string
+	var acks []service.AckFunc
+	for len(bodies) < 4 {
+		msg, ackFn, err := input.Read(ctx)
+		require.NoError(t, err)
+
+		bodyBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		bodies = append(bodies, string(bodyBytes))
+		acks = append(acks, ackFn)
+	}
+
+	assert.Equal(t, []string{
+		`{"after":{"id":1,"name":"foo"},"operation":"insert","schema":"testdb","table":"footable"}`,
+		`{"after":{"id":2,"name":"bar"},"operation":"insert","schema":"testdb","table":"footable"}`,
+		`{"after":{"id":2,"name":"baz"},"before":{"id":2,"name":"bar"},"operation":"update","schema":"testdb","table":"footable"}`,
+		`{"before":{"id":1,"name":"foo"},"operation":"delete","schema":"testdb","table":"footable"}`,
+	}, bodies)
+
+	for _, ackFn := range acks {
+		require.NoError(t, ackFn(ctx, nil))
+	}
+	require.NoError(t, input.Close(ctx))
+
+	// A new input resumes from the checkpointed position and therefore only
+	// receives new changes.
+	input, err = newMySQLCDCInputFromConfig(parsed, mgr)
+	require.NoError(t, err)
+	require.NoError(t, input.Connect(ctx))
+	defer input.Close(ctx)
+
+	_, err = db.Exec(`insert into footable (id, name) values (3, 'qux');`)
+	require.NoError(t, err)
+
+	msg, ackFn, err := input.Read(ctx)
+	require.NoError(t, err)
+	bodyBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"after":{"id":3,"name":"qux"},"operation":"insert","schema":"testdb","table":"footable"}`, string(bodyBytes))
+	require.NoError(t, ackFn(ctx, nil))
+}
@@ -0,0 +1,203 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestBinlogPosition(t *testing.T) {
+	pos, err := parseBinlogPosition("mysql-bin.000003:1337", false)
+	require.NoError(t, err)
+	assert.Equal(t, binlogPosition{file: "mysql-bin.000003", pos: 1337}, pos)
+	assert.Equal(t, "mysql-bin.000003:1337", pos.String())
+
+	_, err = parseBinlogPosition("mysql-bin.000003", false)
+	require.Error(t, err)
+
+	_, err = parseBinlogPosition("mysql-bin.000003:nope", false)
+	require.Error(t, err)
+
+	gtid := "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5"
+	pos, err = parseBinlogPosition(gtid, true)
+	require.NoError(t, err)
+	assert.Equal(t, gtid, pos.String())
+}
+
+func TestMySQLCDCInputEvents(t *testing.T) {
+	conf, err := mysqlCDCInputConfig().ParseYAML(`
+dsn: foouser:foopass@tcp(localhost:3306)/testdb
+tables: [ footable ]
+checkpoint_cache: foocache
+`, service.NewEnvironment())
+	require.NoError(t, err)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	input, err := newMySQLCDCInputFromConfig(conf, mgr)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	fooTable := &replication.TableMapEvent{
+		Schema:     []byte("testdb"),
+		Table:      []byte("footable"),
+		ColumnName: [][]byte{[]byte("id"), []byte("name")},
+	}
+	barTable := &replication.TableMapEvent{
+		Schema:     []byte("testdb"),
+		Table:      []byte("bartable"),
+		ColumnName: [][]byte{[]byte("id")},
+	}
+
+	for _, ev := range []*replication.BinlogEvent{
+		{
+			Header: &replication.EventHeader{EventType: replication.ROTATE_EVENT},
+			Event:  &replication.RotateEvent{Position: 4, NextLogName: []byte("mysql-bin.000001")},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 100},
+			Event:  &replication.QueryEvent{Query: []byte("BEGIN")},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2, LogPos: 150},
+			Event: &replication.RowsEvent{Table: fooTable, Rows: [][]interface{}{
+				{int32(1), []byte("foo")},
+				{int32(2), []byte("bar")},
+			}},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 160},
+			Event:  &replication.QueryEvent{Query: []byte("SAVEPOINT sp1")},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 170},
+			Event:  &replication.QueryEvent{Query: []byte("ROLLBACK TO SAVEPOINT sp1")},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2, LogPos: 175},
+			Event: &replication.RowsEvent{Table: barTable, Rows: [][]interface{}{
+				{int32(1)},
+			}},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.UPDATE_ROWS_EVENTv2, LogPos: 200},
+			Event: &replication.RowsEvent{Table: fooTable, Rows: [][]interface{}{
+				{int32(2), []byte("bar")},
+				{int32(2), []byte("baz")},
+			}},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.XID_EVENT, LogPos: 250},
+			Event:  &replication.XIDEvent{XID: 1},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 300},
+			Event:  &replication.QueryEvent{Query: []byte("BEGIN")},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.DELETE_ROWS_EVENTv2, LogPos: 350},
+			Event: &replication.RowsEvent{Table: fooTable, Rows: [][]interface{}{
+				{int32(1), []byte("foo")},
+			}},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.XID_EVENT, LogPos: 400},
+			Event:  &replication.XIDEvent{XID: 2},
+		},
+	} {
+		require.NoError(t, input.handleEvent(ctx, ev))
+	}
+
+	readCheckpoint := func() string {
+		pos, err := input.readCheckpoint(ctx)
+		require.NoError(t, err)
+		if pos == nil {
+			return ""
+		}
+		return pos.String()
+	}
+
+	type result struct {
+		body     string
+		position string
+	}
+
+	var results []result
+	var acks []service.AckFunc
+	for i := 0; i < 4; i++ {
+		msg, ackFn, err := input.Read(ctx)
+		require.NoError(t, err)
+
+		bodyBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		position, _ := msg.MetaGet("binlog_position")
+
+		results = append(results, result{body: string(bodyBytes), position: position})
+		acks = append(acks, ackFn)
+	}
+
+	assert.Equal(t, []result{
+		{body: `{"after":{"id":1,"name":"foo"},"operation":"insert","schema":"testdb","table":"footable"}`, position: "250"},
+		{body: `{"after":{"id":2,"name":"bar"},"operation":"insert","schema":"testdb","table":"footable"}`, position: "250"},
+		{body: `{"after":{"id":2,"name":"baz"},"before":{"id":2,"name":"bar"},"operation":"update","schema":"testdb","table":"footable"}`, position: "250"},
+		{body: `{"before":{"id":1,"name":"foo"},"operation":"delete","schema":"testdb","table":"footable"}`, position: "400"},
+	}, results)
+
+	// The second transaction is not checkpointed until all changes of the
+	// first have been acknowledged.
+	require.NoError(t, acks[3](ctx, nil))
+	assert.Equal(t, "", readCheckpoint())
+
+	require.NoError(t, acks[0](ctx, nil))
+	require.NoError(t, acks[1](ctx, nil))
+	assert.Equal(t, "", readCheckpoint())
+
+	require.NoError(t, acks[2](ctx, nil))
+	assert.Equal(t, "mysql-bin.000001:400", readCheckpoint())
+
+	// Transactions without changes of interest are checkpointed immediately.
+	for _, ev := range []*replication.BinlogEvent{
+		{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 450},
+			Event:  &replication.QueryEvent{Query: []byte("BEGIN")},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2, LogPos: 500},
+			Event: &replication.RowsEvent{Table: barTable, Rows: [][]interface{}{
+				{int32(2)},
+			}},
+		},
+		{
+			Header: &replication.EventHeader{EventType: replication.XID_EVENT, LogPos: 550},
+			Event:  &replication.XIDEvent{XID: 3},
+		},
+	} {
+		require.NoError(t, input.handleEvent(ctx, ev))
+	}
+	assert.Equal(t, "mysql-bin.000001:550", readCheckpoint())
+
+	// Statements outside of a transaction are committed on their own.
+	require.NoError(t, input.handleEvent(ctx, &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 600},
+		Event:  &replication.QueryEvent{Query: []byte("ALTER TABLE footable ADD COLUMN age INT")},
+	}))
+	assert.Equal(t, "mysql-bin.000001:600", readCheckpoint())
+
+	require.NoError(t, input.Close(ctx))
+}
+
+func TestMySQLCDCInputMissingCache(t *testing.T) {
+	conf, err := mysqlCDCInputConfig().ParseYAML(`
+dsn: foouser:foopass@tcp(localhost:3306)/testdb
+checkpoint_cache: foocache
+`, service.NewEnvironment())
+	require.NoError(t, err)
+
+	_, err = newMySQLCDCInputFromConfig(conf, service.MockResources())
+	require.EqualError(t, err, "cache resource 'foocache' was not found")
+}
@@ -0,0 +1,493 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type pgoutputMsgBuilder struct {
+	buf bytes.Buffer
+}
+
+func newPgoutputMsg(kind byte) *pgoutputMsgBuilder {
+	b := &pgoutputMsgBuilder{}
+	b.buf.WriteByte(kind)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) byte1(v byte) *pgoutputMsgBuilder {
+	b.buf.WriteByte(v)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) uint16(v uint16) *pgoutputMsgBuilder {
+	_ = binary.Write(&b.buf, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) uint32(v uint32) *pgoutputMsgBuilder {
+	_ = binary.Write(&b.buf, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) uint64(v uint64) *pgoutputMsgBuilder {
+	_ = binary.Write(&b.buf, binary.BigEndian, v)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) string(v string) *pgoutputMsgBuilder {
+	b.buf.WriteString(v)
+	b.buf.WriteByte(0)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) text(v string) *pgoutputMsgBuilder {
+	b.byte1('t').uint32(uint32(len(v)))
+	b.buf.WriteString(v)
+	return b
+}
+
+func (b *pgoutputMsgBuilder) bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func TestPgoutputDecoder(t *testing.T) {
+	relation := newPgoutputMsg('R').
+		uint32(16385).string("public").string("users").byte1('d').
+		uint16(4).
+		byte1(1).string("id").uint32(pgTypeInt4).uint32(0xFFFFFFFF).
+		byte1(0).string("name").uint32(25).uint32(0xFFFFFFFF).
+		byte1(0).string("active").uint32(pgTypeBool).uint32(0xFFFFFFFF).
+		byte1(0).string("attrs").uint32(pgTypeJSONB).uint32(0xFFFFFFFF).
+		bytes()
+
+	insert := newPgoutputMsg('I').uint32(16385).byte1('N').
+		uint16(4).text("1").text("foo").text("t").text(`{"a":[1,2]}`).
+		bytes()
+
+	update := newPgoutputMsg('U').uint32(16385).
+		byte1('O').uint16(4).text("1").text("foo").text("t").byte1('n').
+		byte1('N').uint16(4).text("1").text("bar").text("f").byte1('u').
+		bytes()
+
+	del := newPgoutputMsg('D').uint32(16385).
+		byte1('K').uint16(1).text("1").
+		bytes()
+
+	dec := newPgoutputDecoder()
+
+	for i, data := range [][]byte{
+		newPgoutputMsg('B').uint64(100).uint64(0).uint32(512).bytes(),
+		relation,
+		newPgoutputMsg('Y').uint32(16400).string("public").string("mood").bytes(),
+		insert,
+		update,
+		del,
+		newPgoutputMsg('T').uint32(1).byte1(0).uint32(16385).bytes(),
+	} {
+		txn, err := dec.decode(uint64(i+1), data)
+		require.NoError(t, err, i)
+		assert.Nil(t, txn, i)
+	}
+
+	txn, err := dec.decode(200, newPgoutputMsg('C').byte1(0).uint64(190).uint64(200).uint64(0).bytes())
+	require.NoError(t, err)
+	require.NotNil(t, txn)
+
+	assert.Equal(t, uint64(200), txn.lsn)
+	assert.Equal(t, []cdcChange{
+		{
+			operation: "insert",
+			schema:    "public",
+			table:     "users",
+			after: map[string]interface{}{
+				"id":     int64(1),
+				"name":   "foo",
+				"active": true,
+				"attrs":  map[string]interface{}{"a": []interface{}{1.0, 2.0}},
+			},
+		},
+		{
+			operation: "update",
+			schema:    "public",
+			table:     "users",
+			before: map[string]interface{}{
+				"id":     int64(1),
+				"name":   "foo",
+				"active": true,
+				"attrs":  nil,
+			},
+			after: map[string]interface{}{
+				"id":     int64(1),
+				"name":   "bar",
+				"active": false,
+			},
+		},
+		{
+			operation: "delete",
+			schema:    "public",
+			table:     "users",
+			before: map[string]interface{}{
+				"id": int64(1),
+			},
+		},
+	}, txn.changes)
+}
+
+func TestPgoutputDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		msgs [][]byte
+		err  string
+	}{
+		{
+			name: "unknown relation",
+			msgs: [][]byte{
+				newPgoutputMsg('B').uint64(100).uint64(0).uint32(512).bytes(),
+				newPgoutputMsg('I').uint32(5).byte1('N').uint16(0).bytes(),
+			},
+			err: "received change for unknown relation id: 5",
+		},
+		{
+			name: "change outside of transaction",
+			msgs: [][]byte{
+				newPgoutputMsg('I').uint32(5).byte1('N').uint16(0).bytes(),
+			},
+			err: "received change message outside of a transaction",
+		},
+		{
+			name: "truncated relation",
+			msgs: [][]byte{
+				newPgoutputMsg('R').uint32(5).string("public").bytes(),
+			},
+			err: errPgoutputShortMessage.Error(),
+		},
+		{
+			name: "commit without begin",
+			msgs: [][]byte{
+				newPgoutputMsg('C').byte1(0).uint64(190).uint64(200).uint64(0).bytes(),
+			},
+			err: "received commit message without a preceding begin message",
+		},
+		{
+			name: "unknown message type",
+			msgs: [][]byte{
+				newPgoutputMsg('Z').bytes(),
+			},
+			err: `unrecognised pgoutput message type: 'Z'`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			dec := newPgoutputDecoder()
+			var err error
+			for _, m := range test.msgs {
+				if _, err = dec.decode(1, m); err != nil {
+					break
+				}
+			}
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestWal2jsonDecoder(t *testing.T) {
+	dec := newWal2jsonDecoder()
+
+	for i, data := range []string{
+		`{"action":"B"}`,
+		`{"action":"I","schema":"public","table":"users","columns":[{"name":"id","value":1},{"name":"name","value":"foo"}]}`,
+		`{"action":"U","schema":"public","table":"users","columns":[{"name":"id","value":1},{"name":"name","value":"bar"}],"identity":[{"name":"id","value":1}]}`,
+		`{"action":"D","schema":"public","table":"users","identity":[{"name":"id","value":1}]}`,
+		`{"action":"T","schema":"public","table":"users"}`,
+	} {
+		txn, err := dec.decode(uint64(i+1), []byte(data))
+		require.NoError(t, err, i)
+		assert.Nil(t, txn, i)
+	}
+
+	txn, err := dec.decode(200, []byte(`{"action":"C"}`))
+	require.NoError(t, err)
+	require.NotNil(t, txn)
+
+	assert.Equal(t, uint64(200), txn.lsn)
+	assert.Equal(t, []cdcChange{
+		{
+			operation: "insert",
+			schema:    "public",
+			table:     "users",
+			after:     map[string]interface{}{"id": json.Number("1"), "name": "foo"},
+		},
+		{
+			operation: "update",
+			schema:    "public",
+			table:     "users",
+			before:    map[string]interface{}{"id": json.Number("1")},
+			after:     map[string]interface{}{"id": json.Number("1"), "name": "bar"},
+		},
+		{
+			operation: "delete",
+			schema:    "public",
+			table:     "users",
+			before:    map[string]interface{}{"id": json.Number("1")},
+		},
+	}, txn.changes)
+
+	_, err = newWal2jsonDecoder().decode(1, []byte(`{"action":"I","schema":"public","table":"users"}`))
+	require.EqualError(t, err, "received change action outside of a transaction")
+}
+
+func TestPostgresCDCLSN(t *testing.T) {
+	lsn, err := parseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", formatLSN(lsn))
+
+	_, err = parseLSN("nope")
+	require.Error(t, err)
+}
+
+func TestPostgresCDCInputConfig(t *testing.T) {
+	spec := postgresCDCInputConfig()
+	env := service.NewEnvironment()
+
+	conf, err := spec.ParseYAML(`
+dsn: postgres://foo
+slot_name: foo_slot
+`, env)
+	require.NoError(t, err)
+
+	_, err = newPostgresCDCInputFromConfig(conf, nil)
+	require.EqualError(t, err, "a publication must be specified when the plugin is pgoutput")
+
+	conf, err = spec.ParseYAML(`
+dsn: postgres://foo
+slot_name: foo_slot
+plugin: wal2json
+tables: [ foo, inventory.bar ]
+`, env)
+	require.NoError(t, err)
+
+	i, err := newPostgresCDCInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	assert.True(t, i.tableMatches("public", "foo"))
+	assert.True(t, i.tableMatches("inventory", "bar"))
+	assert.False(t, i.tableMatches("inventory", "foo"))
+	require.NoError(t, i.Close(context.Background()))
+}
+
+type fakeCDCSlot struct {
+	mut      sync.Mutex
+	txns     []*cdcTransaction
+	advanced []uint64
+}
+
+func (f *fakeCDCSlot) peekTransactions(ctx context.Context, after uint64) ([]*cdcTransaction, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var txns []*cdcTransaction
+	for _, txn := range f.txns {
+		if txn.lsn > after {
+			txns = append(txns, txn)
+		}
+	}
+	return txns, nil
+}
+
+func (f *fakeCDCSlot) advance(ctx context.Context, lsn uint64) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if len(f.advanced) == 0 || lsn > f.advanced[len(f.advanced)-1] {
+		f.advanced = append(f.advanced, lsn)
+	}
+	return nil
+}
+
+func (f *fakeCDCSlot) advancedLSNs() []uint64 {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return append([]uint64{}, f.advanced...)
+}
+
+func fakeCDCTransaction(lsn uint64, table string, n int) *cdcTransaction {
+	txn := &cdcTransaction{lsn: lsn}
+	for i := 0; i < n; i++ {
+		txn.changes = append(txn.changes, cdcChange{
+			operation: "insert",
+			schema:    "public",
+			table:     table,
+			after:     map[string]interface{}{"id": i},
+		})
+	}
+	return txn
+}
+
+func newFakeSlotPostgresCDCInput(t *testing.T, checkpointLimit int, txns ...*cdcTransaction) (*postgresCDCInput, *fakeCDCSlot) {
+	t.Helper()
+
+	conf, err := postgresCDCInputConfig().ParseYAML(fmt.Sprintf(`
+dsn: postgres://foo
+slot_name: foo_slot
+plugin: wal2json
+tables: [ foo ]
+poll_interval: 10ms
+checkpoint_limit: %v
+`, checkpointLimit), service.NewEnvironment())
+	require.NoError(t, err)
+
+	i, err := newPostgresCDCInputFromConfig(conf, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+
+	slot := &fakeCDCSlot{txns: txns}
+	i.slot = slot
+	return i, slot
+}
+
+func readCDCMessages(t *testing.T, i *postgresCDCInput, n int, lsn string) []service.AckFunc {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	acks := make([]service.AckFunc, 0, n)
+	for j := 0; j < n; j++ {
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+
+		msgLSN, _ := msg.MetaGet("lsn")
+		require.Equal(t, lsn, msgLSN, "message %v", j)
+		acks = append(acks, ackFn)
+	}
+	return acks
+}
+
+func ackCDCMessages(t *testing.T, acks []service.AckFunc) {
+	t.Helper()
+	for _, ackFn := range acks {
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+}
+
+func TestPostgresCDCInputCheckpointLimit(t *testing.T) {
+	i, slot := newFakeSlotPostgresCDCInput(t, 1024,
+		fakeCDCTransaction(100, "foo", 600),
+		fakeCDCTransaction(200, "foo", 500),
+		fakeCDCTransaction(300, "bar", 2),
+		fakeCDCTransaction(400, "foo", 3),
+	)
+
+	firstAcks := readCDCMessages(t, i, 600, "0/64")
+	assert.Empty(t, slot.advancedLSNs())
+
+	// The next transaction exceeds the checkpoint limit, and therefore it is
+	// only read once enough of the previous transaction is acknowledged.
+	type readResult struct {
+		ackFn service.AckFunc
+		err   error
+	}
+	readChan := make(chan readResult, 1)
+	go func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		_, ackFn, err := i.Read(ctx)
+		readChan <- readResult{ackFn: ackFn, err: err}
+	}()
+
+	select {
+	case <-readChan:
+		t.Fatal("expected read to block until messages are acknowledged")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	ackCDCMessages(t, firstAcks)
+	assert.Equal(t, []uint64{100}, slot.advancedLSNs())
+
+	var secondAcks []service.AckFunc
+	select {
+	case res := <-readChan:
+		require.NoError(t, res.err)
+		secondAcks = append(secondAcks, res.ackFn)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for read")
+	}
+	secondAcks = append(secondAcks, readCDCMessages(t, i, 499, "0/C8")...)
+
+	// The transaction without changes of interest is skipped, but the slot is
+	// not advanced beyond it until the prior transaction is acknowledged.
+	ackCDCMessages(t, readCDCMessages(t, i, 3, "0/190"))
+	assert.Equal(t, []uint64{100}, slot.advancedLSNs())
+
+	ackCDCMessages(t, secondAcks)
+	assert.Equal(t, []uint64{100, 400}, slot.advancedLSNs())
+
+	// Transactions already queued are not read again when the slot is peeked.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	_, _, err := i.Read(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPostgresCDCInputTransactionAboveCheckpointLimit(t *testing.T) {
+	i, slot := newFakeSlotPostgresCDCInput(t, 10,
+		fakeCDCTransaction(100, "foo", 25),
+		fakeCDCTransaction(200, "foo", 5),
+	)
+
+	// A transaction larger than the checkpoint limit is read in full when
+	// nothing else is pending.
+	firstAcks := readCDCMessages(t, i, 25, "0/64")
+	ackCDCMessages(t, firstAcks[:24])
+	assert.Empty(t, slot.advancedLSNs())
+
+	ackCDCMessages(t, firstAcks[24:])
+	assert.Equal(t, []uint64{100}, slot.advancedLSNs())
+
+	ackCDCMessages(t, readCDCMessages(t, i, 5, "0/C8"))
+	assert.Equal(t, []uint64{100, 200}, slot.advancedLSNs())
+}
+
+func TestPostgresCDCPeekLimit(t *testing.T) {
+	p := &postgresCDCInput{
+		batchSize: 10,
+		readRows: []cdcReadRows{
+			{lsn: 100, rows: 5},
+			{lsn: 200, rows: 7},
+			{lsn: 300, rows: 3},
+		},
+	}
+
+	// The rows of transactions that have been read are decoded again by each
+	// peek until the slot is advanced past them.
+	assert.Equal(t, 25, p.peekLimit(300))
+
+	p.advancedLSN = 100
+	assert.Equal(t, 20, p.peekLimit(300))
+	assert.Equal(t, []cdcReadRows{{lsn: 200, rows: 7}, {lsn: 300, rows: 3}}, p.readRows)
+
+	// Transactions beyond the last one read are no longer tracked, as they
+	// are read again after a reconnect.
+	assert.Equal(t, 17, p.peekLimit(200))
+	assert.Equal(t, []cdcReadRows{{lsn: 200, rows: 7}}, p.readRows)
+
+	p.advancedLSN = 200
+	assert.Equal(t, 10, p.peekLimit(200))
+	assert.Empty(t, p.readRows)
+}
@@ -29,7 +29,7 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 			Example([]string{"*"}).
 			Example([]string{"foo", "bar", "baz"})).
 		Field(service.NewStringField("where").
-			Description("An optional where clause to add. Placeholder arguments are populated with the `args_mapping` field. Placeholders should always be question marks, and will automatically be converted to dollar syntax when the postgres or clickhouse drivers are used.").
+			Description("An optional where clause to add. Placeholder arguments are populated with the `args_mapping` field. Placeholders should always be question marks, and will automatically be converted to dollar syntax when the postgres or clickhouse drivers are used, and to colon syntax when the oracle driver is used.").
 			Example("type = ? and created_at > ?").
 			Example("user_id = ?").
 			Optional()).
@@ -146,9 +146,7 @@ func newSQLSelectInputFromConfig(conf *service.ParsedConfig, logger *service.Log
 	}
 
 	s.builder = squirrel.Select(columns...).From(tableStr)
-	if s.driver == "postgres" || s.driver == "clickhouse" {
-		s.builder = s.builder.PlaceholderFormat(squirrel.Dollar)
-	}
+	s.builder = s.builder.PlaceholderFormat(placeholderFormat(s.driver))
 
 	if conf.Contains("prefix") {
 		prefixStr, err := conf.FieldString("prefix")
@@ -0,0 +1,227 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func onConflictField() *service.ConfigField {
+	return service.NewObjectField("on_conflict",
+		service.NewStringListField("key_columns").
+			Description("The columns that identify a row. With the `postgres` and `sqlite` drivers these columns must match a unique index or the primary key of the table, whereas the `mysql` driver ignores this field and instead detects conflicts with any unique index of the table.").
+			Example([]string{"id"}),
+		service.NewStringListField("update_columns").
+			Description("The columns to update when a row already exists. When empty rows that already exist are left unchanged.").
+			Example([]string{"name", "updated_at"}).
+			Default([]string{}),
+	).
+		Description("Optionally update rows that already exist rather than inserting duplicates, which makes writes idempotent. Conflicts are resolved with `ON CONFLICT` for the `postgres` and `sqlite` drivers, `ON DUPLICATE KEY UPDATE` for the `mysql` driver and `MERGE` for the `mssql` driver. All other drivers are not supported. When a batch contains multiple rows with the same key only the last one is written.").
+		Optional().
+		Version("4.5.0")
+}
+
+// mssqlMaxParams is the maximum number of parameters of a merge statement,
+// which is below the limit of 2100 parameters per request of SQL Server.
+const mssqlMaxParams = 2000
+
+// insertConflict describes how rows that already exist are resolved when
+// inserted. The table and all columns are validated and quoted, as they are
+// written into the statements directly.
+type insertConflict struct {
+	driver        string
+	table         string
+	columns       []string
+	keyColumns    []string
+	updateColumns []string
+	keyIndexes    []int
+}
+
+// insertConflictConfigured returns whether on_conflict has been configured,
+// as the parsed config contains the field with empty column lists otherwise.
+func insertConflictConfigured(conf *service.ParsedConfig) bool {
+	if !conf.Contains("on_conflict") {
+		return false
+	}
+	keyColumns, _ := conf.FieldStringList("on_conflict", "key_columns")
+	updateColumns, _ := conf.FieldStringList("on_conflict", "update_columns")
+	return len(keyColumns) > 0 || len(updateColumns) > 0
+}
+
+func insertConflictFromParsed(conf *service.ParsedConfig, driver, table string, columns []string) (*insertConflict, error) {
+	switch driver {
+	case "postgres", "sqlite", "mysql", "mssql":
+	default:
+		return nil, fmt.Errorf("on_conflict is not supported by the %v driver", driver)
+	}
+
+	c := &insertConflict{driver: driver}
+
+	keyColumns, err := conf.FieldStringList("on_conflict", "key_columns")
+	if err != nil {
+		return nil, err
+	}
+	if len(keyColumns) == 0 {
+		return nil, errors.New("on_conflict requires at least one key column")
+	}
+	updateColumns, err := conf.FieldStringList("on_conflict", "update_columns")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range append(append([]string{}, keyColumns...), updateColumns...) {
+		if columnIndex(columns, k) == -1 {
+			return nil, fmt.Errorf("on_conflict column %v is not within the columns being inserted", k)
+		}
+	}
+	for _, k := range keyColumns {
+		c.keyIndexes = append(c.keyIndexes, columnIndex(columns, k))
+	}
+
+	if c.table, err = quoteTableName(driver, table); err != nil {
+		return nil, err
+	}
+	if c.columns, err = quoteIdentifiers(driver, columns); err != nil {
+		return nil, err
+	}
+	if c.keyColumns, err = quoteIdentifiers(driver, keyColumns); err != nil {
+		return nil, err
+	}
+	if c.updateColumns, err = quoteIdentifiers(driver, updateColumns); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func quoteIdentifiers(driver string, names []string) ([]string, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		var err error
+		if quoted[i], err = quoteIdentifier(driver, name); err != nil {
+			return nil, fmt.Errorf("column name '%v' is invalid: %w", name, err)
+		}
+	}
+	return quoted, nil
+}
+
+func columnIndex(columns []string, name string) int {
+	for i, c := range columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// suffix returns the clause to append to an insert statement, or an empty
+// string for drivers that instead require a merge statement.
+func (c *insertConflict) suffix() string {
+	var updates []string
+	switch c.driver {
+	case "postgres", "sqlite":
+		if len(c.updateColumns) == 0 {
+			return fmt.Sprintf("ON CONFLICT (%v) DO NOTHING", strings.Join(c.keyColumns, ", "))
+		}
+		for _, col := range c.updateColumns {
+			updates = append(updates, fmt.Sprintf("%v = EXCLUDED.%v", col, col))
+		}
+		return fmt.Sprintf("ON CONFLICT (%v) DO UPDATE SET %v", strings.Join(c.keyColumns, ", "), strings.Join(updates, ", "))
+	case "mysql":
+		if len(c.updateColumns) == 0 {
+			return fmt.Sprintf("ON DUPLICATE KEY UPDATE %v = %v", c.keyColumns[0], c.keyColumns[0])
+		}
+		for _, col := range c.updateColumns {
+			updates = append(updates, fmt.Sprintf("%v = VALUES(%v)", col, col))
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	}
+	return ""
+}
+
+// dedupe removes all but the last row of each key from a batch of rows, as
+// neither upserts nor merges are able to modify the same row twice within a
+// single statement.
+func (c *insertConflict) dedupe(rows [][]interface{}) [][]interface{} {
+	positions := make(map[string]int, len(rows))
+	deduped := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		keyValues := make([]interface{}, len(c.keyIndexes))
+		for i, index := range c.keyIndexes {
+			if index < len(row) {
+				keyValues[i] = row[index]
+			}
+		}
+		key := fmt.Sprintf("%#v", keyValues)
+		if i, exists := positions[key]; exists {
+			deduped[i] = row
+			continue
+		}
+		positions[key] = len(deduped)
+		deduped = append(deduped, row)
+	}
+	return deduped
+}
+
+// mergeStatement is a merge statement and its arguments.
+type mergeStatement struct {
+	query string
+	args  []interface{}
+}
+
+// mergeStatements returns the merge statements that upsert a batch of rows,
+// where the rows are split across multiple statements in order to remain
+// within the parameter limit of SQL Server.
+func (c *insertConflict) mergeStatements(rows [][]interface{}) []mergeStatement {
+	chunkSize := mssqlMaxParams / len(c.columns)
+	if chunkSize == 0 {
+		chunkSize = 1
+	}
+
+	var statements []mergeStatement
+	for len(rows) > 0 {
+		n := chunkSize
+		if n > len(rows) {
+			n = len(rows)
+		}
+		statements = append(statements, c.mergeStatement(rows[:n]))
+		rows = rows[n:]
+	}
+	return statements
+}
+
+func (c *insertConflict) mergeStatement(rows [][]interface{}) mergeStatement {
+	rowPlaceholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(c.columns)), ", ") + ")"
+
+	values := make([]string, 0, len(rows))
+	args := make([]interface{}, 0, len(rows)*len(c.columns))
+	for _, row := range rows {
+		values = append(values, rowPlaceholders)
+		args = append(args, row...)
+	}
+
+	conditions := make([]string, 0, len(c.keyColumns))
+	for _, k := range c.keyColumns {
+		conditions = append(conditions, fmt.Sprintf("target.%v = source.%v", k, k))
+	}
+
+	sourceColumns := make([]string, 0, len(c.columns))
+	for _, col := range c.columns {
+		sourceColumns = append(sourceColumns, "source."+col)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "MERGE INTO %v AS target USING (VALUES %v) AS source (%v) ON %v",
+		c.table, strings.Join(values, ", "), strings.Join(c.columns, ", "), strings.Join(conditions, " AND "))
+	if len(c.updateColumns) > 0 {
+		updates := make([]string, 0, len(c.updateColumns))
+		for _, col := range c.updateColumns {
+			updates = append(updates, fmt.Sprintf("target.%v = source.%v", col, col))
+		}
+		fmt.Fprintf(&b, " WHEN MATCHED THEN UPDATE SET %v", strings.Join(updates, ", "))
+	}
+	fmt.Fprintf(&b, " WHEN NOT MATCHED THEN INSERT (%v) VALUES (%v);",
+		strings.Join(c.columns, ", "), strings.Join(sourceColumns, ", "))
+	return mergeStatement{query: b.String(), args: args}
+}
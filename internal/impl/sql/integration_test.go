@@ -21,6 +21,7 @@ import (
 	"github.com/benthosdev/benthos/v4/public/service"
 
 	_ "github.com/benthosdev/benthos/v4/public/components/pure"
+	_ "modernc.org/sqlite"
 )
 
 type testFn func(t *testing.T, driver, dsn, table string)
@@ -541,6 +542,30 @@ func TestIntegration(t *testing.T) {
 	t.Run("mssql", msSQLIntegration)
 }
 
+func TestSQLite(t *testing.T) {
+	// The sqlite driver runs in process and therefore the test suite doesn't
+	// require a service or explicit opt-in.
+	dsn := fmt.Sprintf("file:%v/foo.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", t.TempDir())
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	createTable := func(name string) error {
+		_, err := db.Exec(fmt.Sprintf(`create table %v (
+  foo varchar(50) not null,
+  bar integer not null,
+  baz varchar(50) not null,
+  primary key (foo)
+);`, name))
+		return err
+	}
+
+	testSuite(t, "sqlite", dsn, createTable)
+}
+
 func clickhouseIntegration(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,272 @@
+package sql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Decodes the messages of the pgoutput logical replication protocol (version
+// 1) as described at:
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+
+var errPgoutputShortMessage = errors.New("pgoutput message is shorter than expected")
+
+// Type OIDs of the column types that are converted from their text
+// representation into structured values.
+const (
+	pgTypeBool   = 16
+	pgTypeInt8   = 20
+	pgTypeInt2   = 21
+	pgTypeInt4   = 23
+	pgTypeJSON   = 114
+	pgTypeFloat4 = 700
+	pgTypeFloat8 = 701
+	pgTypeJSONB  = 3802
+)
+
+type pgoutputColumn struct {
+	name    string
+	typeOID uint32
+}
+
+type pgoutputRelation struct {
+	schema  string
+	table   string
+	columns []pgoutputColumn
+}
+
+type pgoutputDecoder struct {
+	relations map[uint32]pgoutputRelation
+	txn       *cdcTransaction
+}
+
+func newPgoutputDecoder() cdcDecoder {
+	return &pgoutputDecoder{
+		relations: map[uint32]pgoutputRelation{},
+	}
+}
+
+func (d *pgoutputDecoder) decode(lsn uint64, data []byte) (*cdcTransaction, error) {
+	if len(data) == 0 {
+		return nil, errPgoutputShortMessage
+	}
+
+	r := &pgoutputReader{b: data[1:]}
+	switch data[0] {
+	case 'B':
+		d.txn = &cdcTransaction{}
+	case 'C':
+		txn := d.txn
+		if txn == nil {
+			return nil, errors.New("received commit message without a preceding begin message")
+		}
+		d.txn = nil
+		txn.lsn = lsn
+		return txn, nil
+	case 'R':
+		return nil, d.decodeRelation(r)
+	case 'I', 'U', 'D':
+		if d.txn == nil {
+			return nil, errors.New("received change message outside of a transaction")
+		}
+		change, err := d.decodeChange(data[0], r)
+		if err != nil {
+			return nil, err
+		}
+		d.txn.changes = append(d.txn.changes, change)
+	case 'O', 'Y', 'T', 'M':
+		// Origin, type, truncate and generic logical decoding messages are
+		// not emitted.
+	default:
+		return nil, fmt.Errorf("unrecognised pgoutput message type: %q", data[0])
+	}
+	return nil, nil
+}
+
+func (d *pgoutputDecoder) decodeRelation(r *pgoutputReader) error {
+	id := r.uint32()
+	rel := pgoutputRelation{
+		schema: r.string(),
+		table:  r.string(),
+	}
+	_ = r.byte1() // Replica identity setting
+
+	nCols := int(r.uint16())
+	for i := 0; i < nCols && r.err == nil; i++ {
+		_ = r.byte1() // Column flags
+		col := pgoutputColumn{name: r.string()}
+		col.typeOID = r.uint32()
+		_ = r.uint32() // Type modifier
+		rel.columns = append(rel.columns, col)
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	d.relations[id] = rel
+	return nil
+}
+
+func (d *pgoutputDecoder) decodeChange(kind byte, r *pgoutputReader) (change cdcChange, err error) {
+	relID := r.uint32()
+	if r.err != nil {
+		return change, r.err
+	}
+
+	rel, exists := d.relations[relID]
+	if !exists {
+		return change, fmt.Errorf("received change for unknown relation id: %v", relID)
+	}
+	change.schema, change.table = rel.schema, rel.table
+
+	switch kind {
+	case 'I':
+		change.operation = "insert"
+		if t := r.byte1(); t != 'N' && r.err == nil {
+			return change, fmt.Errorf("unexpected insert tuple type: %q", t)
+		}
+		change.after, err = d.decodeTuple(rel, r)
+	case 'U':
+		change.operation = "update"
+		t := r.byte1()
+		if t == 'K' || t == 'O' {
+			if change.before, err = d.decodeTuple(rel, r); err != nil {
+				return
+			}
+			t = r.byte1()
+		}
+		if t != 'N' && r.err == nil {
+			return change, fmt.Errorf("unexpected update tuple type: %q", t)
+		}
+		change.after, err = d.decodeTuple(rel, r)
+	case 'D':
+		change.operation = "delete"
+		if t := r.byte1(); t != 'K' && t != 'O' && r.err == nil {
+			return change, fmt.Errorf("unexpected delete tuple type: %q", t)
+		}
+		change.before, err = d.decodeTuple(rel, r)
+	}
+	if err == nil {
+		err = r.err
+	}
+	return
+}
+
+func (d *pgoutputDecoder) decodeTuple(rel pgoutputRelation, r *pgoutputReader) (map[string]interface{}, error) {
+	nCols := int(r.uint16())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if nCols > len(rel.columns) {
+		return nil, fmt.Errorf("tuple of relation %v.%v has %v columns, expected at most %v", rel.schema, rel.table, nCols, len(rel.columns))
+	}
+
+	tuple := make(map[string]interface{}, nCols)
+	for i := 0; i < nCols; i++ {
+		col := rel.columns[i]
+		switch kind := r.byte1(); kind {
+		case 'n':
+			tuple[col.name] = nil
+		case 'u':
+			// Unchanged TOASTed values are not sent and are therefore omitted.
+		case 't':
+			value := r.bytes(int(r.uint32()))
+			if r.err != nil {
+				return nil, r.err
+			}
+			tuple[col.name] = pgTextValue(col.typeOID, value)
+		default:
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, fmt.Errorf("unrecognised tuple column type: %q", kind)
+		}
+	}
+	return tuple, r.err
+}
+
+// pgTextValue converts the text representation of a column value into a
+// structured value where the type is a boolean, number or JSON document, and
+// otherwise returns the text representation as a string.
+func pgTextValue(typeOID uint32, value []byte) interface{} {
+	switch typeOID {
+	case pgTypeBool:
+		return string(value) == "t"
+	case pgTypeInt2, pgTypeInt4, pgTypeInt8:
+		if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			return i
+		}
+	case pgTypeFloat4, pgTypeFloat8:
+		if f, err := strconv.ParseFloat(string(value), 64); err == nil {
+			return f
+		}
+	case pgTypeJSON, pgTypeJSONB:
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err == nil {
+			return v
+		}
+	}
+	return string(value)
+}
+
+//------------------------------------------------------------------------------
+
+// pgoutputReader reads the fields of a pgoutput message, once a read exceeds
+// the length of the message all subsequent reads return zero values and err is
+// set.
+type pgoutputReader struct {
+	b   []byte
+	err error
+}
+
+func (r *pgoutputReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errPgoutputShortMessage
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *pgoutputReader) byte1() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.b {
+		if c == 0 {
+			s := string(r.b[:i])
+			r.b = r.b[i+1:]
+			return s
+		}
+	}
+	r.err = errPgoutputShortMessage
+	return ""
+}
@@ -89,6 +89,8 @@ type sqlInsertProcessor struct {
 	builder squirrel.InsertBuilder
 	dbMut   sync.RWMutex
 
+	driver      string
+	columns     []string
 	useTxStmt   bool
 	argsMapping *bloblang.Executor
 
@@ -108,11 +110,8 @@ func NewSQLInsertProcessorFromConfig(conf *service.ParsedConfig, logger *service
 	if err != nil {
 		return nil, err
 	}
-	if _, in := map[string]struct{}{
-		"clickhouse": {},
-	}[driverStr]; in {
-		s.useTxStmt = true
-	}
+	s.driver = driverStr
+	s.useTxStmt = usesPreparedInserts(driverStr)
 
 	dsnStr, err := conf.FieldString("dsn")
 	if err != nil {
@@ -124,8 +123,7 @@ func NewSQLInsertProcessorFromConfig(conf *service.ParsedConfig, logger *service
 		return nil, err
 	}
 
-	columns, err := conf.FieldStringList("columns")
-	if err != nil {
+	if s.columns, err = conf.FieldStringList("columns"); err != nil {
 		return nil, err
 	}
 
@@ -135,10 +133,8 @@ func NewSQLInsertProcessorFromConfig(conf *service.ParsedConfig, logger *service
 		}
 	}
 
-	s.builder = squirrel.Insert(tableStr).Columns(columns...)
-	if driverStr == "postgres" || driverStr == "clickhouse" {
-		s.builder = s.builder.PlaceholderFormat(squirrel.Dollar)
-	}
+	s.builder = squirrel.Insert(tableStr).Columns(s.columns...)
+	s.builder = s.builder.PlaceholderFormat(placeholderFormat(driverStr))
 
 	if conf.Contains("prefix") {
 		prefixStr, err := conf.FieldString("prefix")
@@ -192,8 +188,9 @@ func (s *sqlInsertProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 		if tx, err = s.db.Begin(); err != nil {
 			return nil, err
 		}
-		sqlStr, _, err := insertBuilder.Values().ToSql()
+		sqlStr, err := preparedInsertSQL(s.driver, insertBuilder, len(s.columns))
 		if err != nil {
+			_ = tx.Rollback()
 			return nil, err
 		}
 		if stmt, err = tx.Prepare(sqlStr); err != nil {
@@ -31,7 +31,7 @@ If the query fails to execute then the message will remain unchanged and the err
 			Example([]string{"*"}).
 			Example([]string{"foo", "bar", "baz"})).
 		Field(service.NewStringField("where").
-			Description("An optional where clause to add. Placeholder arguments are populated with the `args_mapping` field. Placeholders should always be question marks, and will automatically be converted to dollar syntax when the postgres or clickhouse drivers are used.").
+			Description("An optional where clause to add. Placeholder arguments are populated with the `args_mapping` field. Placeholders should always be question marks, and will automatically be converted to dollar syntax when the postgres or clickhouse drivers are used, and to colon syntax when the oracle driver is used.").
 			Example("meow = ? and woof = ?").
 			Example("user_id = ?").
 			Optional()).
@@ -145,9 +145,7 @@ func NewSQLSelectProcessorFromConfig(conf *service.ParsedConfig, logger *service
 	}
 
 	s.builder = squirrel.Select(columns...).From(tableStr)
-	if driverStr == "postgres" || driverStr == "clickhouse" {
-		s.builder = s.builder.PlaceholderFormat(squirrel.Dollar)
-	}
+	s.builder = s.builder.PlaceholderFormat(placeholderFormat(driverStr))
 
 	if conf.Contains("prefix") {
 		prefixStr, err := conf.FieldString("prefix")
@@ -0,0 +1,386 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type columnType int
+
+const (
+	columnTypeBool columnType = iota
+	columnTypeInt
+	columnTypeFloat
+	columnTypeString
+	columnTypeJSON
+)
+
+// inferColumnType returns the type of column suitable for storing a value
+// extracted from a structured message.
+func inferColumnType(v interface{}) columnType {
+	switch t := v.(type) {
+	case bool:
+		return columnTypeBool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return columnTypeInt
+	case float32, float64:
+		return columnTypeFloat
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return columnTypeInt
+		}
+		return columnTypeFloat
+	case map[string]interface{}, []interface{}:
+		return columnTypeJSON
+	}
+	return columnTypeString
+}
+
+// columnTypeName returns the name of a column type for a given driver.
+func columnTypeName(driver string, t columnType) string {
+	switch t {
+	case columnTypeBool:
+		switch driver {
+		case "mssql":
+			return "BIT"
+		case "oracle":
+			return "NUMBER(1)"
+		case "clickhouse":
+			return "Nullable(UInt8)"
+		}
+		return "BOOLEAN"
+	case columnTypeInt:
+		switch driver {
+		case "oracle":
+			return "NUMBER(19)"
+		case "clickhouse":
+			return "Nullable(Int64)"
+		}
+		return "BIGINT"
+	case columnTypeFloat:
+		switch driver {
+		case "postgres":
+			return "DOUBLE PRECISION"
+		case "mssql", "snowflake":
+			return "FLOAT"
+		case "sqlite":
+			return "REAL"
+		case "oracle":
+			return "BINARY_DOUBLE"
+		case "clickhouse":
+			return "Nullable(Float64)"
+		}
+		return "DOUBLE"
+	case columnTypeJSON:
+		switch driver {
+		case "postgres":
+			return "JSONB"
+		case "mysql":
+			return "JSON"
+		}
+	}
+	switch driver {
+	case "mssql":
+		return "NVARCHAR(MAX)"
+	case "oracle":
+		return "VARCHAR2(4000)"
+	case "clickhouse":
+		return "Nullable(String)"
+	case "snowflake", "trino":
+		return "VARCHAR"
+	}
+	return "TEXT"
+}
+
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// quoteIdentifier validates and quotes an identifier for a driver. Identifiers
+// must only contain letters, digits and underscores, and must not start with a
+// digit, which allows them to be quoted without escaping.
+//
+// Quoting an identifier normally makes it case sensitive, and therefore the
+// identifier is first folded to the case that the database folds unquoted
+// identifiers to. This way quoting only permits identifiers that are reserved
+// words, and otherwise behaves the same as an unquoted identifier.
+func quoteIdentifier(driver, name string) (string, error) {
+	if !identifierRegexp.MatchString(name) {
+		return "", fmt.Errorf("identifier '%v' is invalid, identifiers must only contain letters, digits and underscores, and must not start with a digit", name)
+	}
+	switch driver {
+	case "postgres", "trino":
+		name = strings.ToLower(name)
+	case "oracle", "snowflake":
+		name = strings.ToUpper(name)
+	}
+	switch driver {
+	case "mysql", "clickhouse":
+		return "`" + name + "`", nil
+	case "mssql":
+		return "[" + name + "]", nil
+	}
+	return `"` + name + `"`, nil
+}
+
+// quoteTableName validates and quotes a table name that is optionally
+// qualified with a schema, e.g. inventory.orders.
+func quoteTableName(driver, name string) (string, error) {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		quoted, err := quoteIdentifier(driver, part)
+		if err != nil {
+			return "", fmt.Errorf("table name '%v' is invalid: %w", name, err)
+		}
+		parts[i] = quoted
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// columnValue converts a value extracted from a structured message into an
+// argument accepted by drivers.
+func columnValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
+// tableSchema tracks the columns of a table that is created and extended
+// according to the structure of the messages written to it. The columns are
+// cached and therefore the table is only inspected when it is first written
+// to, or when adding a column fails.
+//
+// Column names are taken from the fields of messages, and are therefore always
+// validated and quoted before they are used within statements.
+type tableSchema struct {
+	driver string
+	table  string
+
+	mut     sync.Mutex
+	checked bool
+	columns map[string]struct{}
+}
+
+// newTableSchema creates a table schema for a table name that has already been
+// quoted.
+func newTableSchema(driver, quotedTable string) *tableSchema {
+	return &tableSchema{
+		driver:  driver,
+		table:   quotedTable,
+		columns: map[string]struct{}{},
+	}
+}
+
+// Column names are compared case insensitively as most databases fold the case
+// of unquoted identifiers.
+func (t *tableSchema) hasColumn(name string) bool {
+	_, exists := t.columns[strings.ToLower(name)]
+	return exists
+}
+
+func (t *tableSchema) refresh(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %v WHERE 1=0", t.table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		t.columns[strings.ToLower(name)] = struct{}{}
+	}
+	return nil
+}
+
+func (t *tableSchema) create(ctx context.Context, db *sql.DB, names []string, samples map[string]interface{}) error {
+	if len(names) == 0 {
+		return fmt.Errorf("unable to create table %v without any columns", t.table)
+	}
+
+	definitions := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted, err := quoteIdentifier(t.driver, name)
+		if err != nil {
+			return err
+		}
+		definitions = append(definitions, quoted+" "+columnTypeName(t.driver, inferColumnType(samples[name])))
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %v (%v)", t.table, strings.Join(definitions, ", "))
+	if t.driver == "clickhouse" {
+		query += " ENGINE = MergeTree() ORDER BY tuple()"
+	}
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		t.columns[strings.ToLower(name)] = struct{}{}
+	}
+	return nil
+}
+
+func (t *tableSchema) addColumn(ctx context.Context, db *sql.DB, name string, sample interface{}) error {
+	quoted, err := quoteIdentifier(t.driver, name)
+	if err != nil {
+		return err
+	}
+
+	addStr := "ADD COLUMN"
+	if t.driver == "mssql" || t.driver == "oracle" {
+		addStr = "ADD"
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %v %v %v %v", t.table, addStr, quoted, columnTypeName(t.driver, inferColumnType(sample)))
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		// The column might have been added by another writer since the table
+		// was last inspected.
+		if rErr := t.refresh(ctx, db); rErr == nil && t.hasColumn(name) {
+			return nil
+		}
+		return err
+	}
+	t.columns[strings.ToLower(name)] = struct{}{}
+	return nil
+}
+
+// ensureColumns creates the table if it does not yet exist, and adds any of
+// the provided columns that it does not yet have, where the type of each
+// column is inferred from a sample value.
+func (t *tableSchema) ensureColumns(ctx context.Context, db *sql.DB, samples map[string]interface{}) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !t.checked {
+		if err := t.refresh(ctx, db); err != nil {
+			// We assume the table doesn't exist and attempt to create it, if
+			// the table exists and inspecting it failed for another reason
+			// then this also fails.
+			if cErr := t.create(ctx, db, names, samples); cErr != nil {
+				return fmt.Errorf("failed to inspect table: %v, and failed to create it: %w", err, cErr)
+			}
+		}
+		t.checked = true
+	}
+
+	for _, name := range names {
+		if t.hasColumn(name) {
+			continue
+		}
+		if err := t.addColumn(ctx, db, name, samples[name]); err != nil {
+			return fmt.Errorf("failed to add column %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// batchRows extracts the rows of a batch of messages, which must each be an
+// object, and ensures that the table has a column for each field. Returns the
+// quoted columns to insert and the arguments of each message in the same
+// order. Fields with names that are not valid identifiers are rejected.
+func (t *tableSchema) batchRows(ctx context.Context, db *sql.DB, batch service.MessageBatch) ([]string, [][]interface{}, error) {
+	objs := make([]map[string]interface{}, len(batch))
+	samples := map[string]interface{}{}
+	var fields []string
+
+	for i, msg := range batch {
+		v, err := msg.AsStructured()
+		if err != nil {
+			return nil, nil, err
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("expected message to be an object, got %T", v)
+		}
+		objs[i] = obj
+
+		for k, v := range obj {
+			sample, seen := samples[k]
+			if !seen {
+				fields = append(fields, k)
+			}
+			if sample == nil && v != nil {
+				samples[k] = v
+			} else if !seen {
+				samples[k] = nil
+			}
+		}
+	}
+	sort.Strings(fields)
+
+	quotedFields := make(map[string]string, len(fields))
+	for _, k := range fields {
+		quoted, err := quoteIdentifier(t.driver, k)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field '%v' cannot be inserted as a column: %w", k, err)
+		}
+		quotedFields[k] = quoted
+	}
+
+	// Fields that are only ever null don't provide a type and therefore we
+	// wait for a value before adding a column for them.
+	typed := map[string]interface{}{}
+	for k, v := range samples {
+		if v != nil {
+			typed[k] = v
+		}
+	}
+	if err := t.ensureColumns(ctx, db, typed); err != nil {
+		return nil, nil, err
+	}
+
+	t.mut.Lock()
+	var inserted []string
+	for _, k := range fields {
+		if t.hasColumn(k) {
+			inserted = append(inserted, k)
+		}
+	}
+	t.mut.Unlock()
+
+	columns := make([]string, len(inserted))
+	for i, k := range inserted {
+		columns[i] = quotedFields[k]
+	}
+
+	rows := make([][]interface{}, len(objs))
+	for i, obj := range objs {
+		args := make([]interface{}, len(inserted))
+		for j, k := range inserted {
+			var err error
+			if args[j], err = columnValue(obj[k]); err != nil {
+				return nil, nil, err
+			}
+		}
+		rows[i] = args
+	}
+	return columns, rows, nil
+}
@@ -25,6 +25,7 @@ import (
 	_ "github.com/benthosdev/benthos/v4/internal/impl/nanomsg"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/nats"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/nsq"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/otlp"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/prometheus"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/pusher"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/redis"
@@ -45,4 +46,8 @@ import (
 	_ "github.com/denisenkom/go-mssqldb"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "github.com/sijms/go-ora/v2"
+	_ "github.com/snowflakedb/gosnowflake"
+	_ "github.com/trinodb/trino-go-client/trino"
+	_ "modernc.org/sqlite"
 )